@@ -0,0 +1,215 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/fmpwizard/go-quilljs-delta/collab"
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+	"github.com/fmpwizard/go-quilljs-delta/render"
+)
+
+// errNotDocument is returned by the commands that need a document, an
+// insert only delta
+var errNotDocument = errors.New("not a document, it has retain or delete ops")
+
+// errNoNewline is a document that doesn't end with a newline, which quill
+// can't load
+var errNoNewline = errors.New("the document doesn't end with a newline")
+
+func (c *cli) compose(args []string) error {
+	files, err := parse(c.flags("compose"), args)
+	if err != nil {
+		return err
+	}
+	if len(files) < 2 {
+		return &usageError{"compose needs at least two deltas"}
+	}
+	deltas, err := c.readAll(files)
+	if err != nil {
+		return err
+	}
+	ret := deltas[0]
+	for i, d := range deltas[1:] {
+		if isDocument(ret) && collab.BaseLength(d) > ret.Length() {
+			return fmt.Errorf("%s: change of length %d goes past the end of a document of length %d",
+				files[i+1], collab.BaseLength(d), ret.Length())
+		}
+		ret = ret.Compose(*d)
+	}
+	return c.write(ret)
+}
+
+func (c *cli) transform(args []string) error {
+	fs := c.flags("transform")
+	priority := fs.Bool("priority", false, "the first delta happened first, it wins ties between inserts at the same index")
+	files, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 2 {
+		return &usageError{"transform needs two deltas"}
+	}
+	deltas, err := c.readAll(files)
+	if err != nil {
+		return err
+	}
+	return c.write(deltas[0].Transform(*deltas[1], *priority))
+}
+
+func (c *cli) invert(args []string) error {
+	fs := c.flags("invert")
+	baseFile := fs.String("base", "", "the document the change applies to")
+	files, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if *baseFile == "" {
+		return &usageError{"invert needs the --base document"}
+	}
+	base, err := c.read(*baseFile)
+	if err != nil {
+		return err
+	}
+	change, err := c.readOne(files)
+	if err != nil {
+		return err
+	}
+	if !isDocument(base) {
+		return fmt.Errorf("base: %v", errNotDocument)
+	}
+	if collab.BaseLength(change) > base.Length() {
+		return fmt.Errorf("change of length %d goes past the end of the base of length %d",
+			collab.BaseLength(change), base.Length())
+	}
+	return c.write(change.Invert(base))
+}
+
+func (c *cli) diff(args []string) error {
+	files, err := parse(c.flags("diff"), args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 2 {
+		return &usageError{"diff needs two documents"}
+	}
+	deltas, err := c.readAll(files)
+	if err != nil {
+		return err
+	}
+	ret, err := deltas[0].Diff(deltas[1])
+	if err != nil {
+		return err
+	}
+	return c.write(ret)
+}
+
+func (c *cli) slice(args []string) error {
+	fs := c.flags("slice")
+	start := fs.Int("start", 0, "index of the first character")
+	end := fs.Int("end", -1, "index after the last character, the end of the delta when negative")
+	files, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if *start < 0 || (*end >= 0 && *end < *start) {
+		return &usageError{"--start must be positive and not after --end"}
+	}
+	d, err := c.readOne(files)
+	if err != nil {
+		return err
+	}
+	if *end < 0 {
+		*end = d.Length()
+	}
+	return c.write(d.Slice(*start, *end))
+}
+
+func (c *cli) length(args []string) error {
+	files, err := parse(c.flags("length"), args)
+	if err != nil {
+		return err
+	}
+	d, err := c.readOne(files)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(c.stdout, d.Length())
+	return err
+}
+
+func (c *cli) validate(args []string) error {
+	fs := c.flags("validate")
+	document := fs.Bool("document", false, "the delta must be a document ending with a newline")
+	files, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	d, err := c.readOne(files)
+	if err != nil {
+		return err
+	}
+	var problems []string
+	if *document && !isDocument(d) {
+		problems = append(problems, errNotDocument.Error())
+	} else if *document && !endsWithNewline(d) {
+		problems = append(problems, errNoNewline.Error())
+	}
+	for _, v := range d.Validate(delta.DefaultSchema()) {
+		problems = append(problems, v.String())
+	}
+	for _, p := range problems {
+		fmt.Fprintln(c.stdout, p)
+	}
+	if len(problems) > 0 {
+		name := "stdin"
+		if len(files) > 0 && files[0] != "-" {
+			name = files[0]
+		}
+		return &inputError{name, fmt.Errorf("%d problems found", len(problems))}
+	}
+	return nil
+}
+
+func (c *cli) normalize(args []string) error {
+	fs := c.flags("normalize")
+	schema := fs.Bool("schema", false, "also drop the formats and embeds the default schema doesn't know")
+	files, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	d, err := c.readOne(files)
+	if err != nil {
+		return err
+	}
+	ret := delta.New(nil)
+	for _, op := range d.Ops {
+		ret.Push(op)
+	}
+	ret.Chop()
+	if *schema {
+		ret = ret.Conform(delta.DefaultSchema())
+	}
+	return c.write(ret)
+}
+
+func (c *cli) render(args []string) error {
+	fs := c.flags("render")
+	format := fs.String("format", "html", "html, md or text")
+	files, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	renderer, ok := render.Formats[*format]
+	if !ok {
+		return &usageError{fmt.Sprintf("unknown format %q", *format)}
+	}
+	d, err := c.readOne(files)
+	if err != nil {
+		return err
+	}
+	if !isDocument(d) {
+		return errNotDocument
+	}
+	return renderer(c.stdout, d)
+}
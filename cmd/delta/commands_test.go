@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommands(t *testing.T) {
+	dir := tempFiles(t, map[string]string{
+		"doc.json":    `{"ops":[{"insert":"Hello\n"}]}`,
+		"bold.json":   `{"ops":[{"insert":"Hello world\n","attributes":{"bold":true}}]}`,
+		"world.json":  `{"ops":[{"retain":5},{"insert":" world"}]}`,
+		"there.json":  `{"ops":[{"retain":5},{"insert":" there"}]}`,
+		"delete.json": `{"ops":[{"retain":1},{"delete":4}]}`,
+		"long.json":   `{"ops":[{"retain":10},{"delete":4}]}`,
+	})
+	defer os.RemoveAll(dir)
+	file := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+
+	tests := []struct {
+		stdin  string
+		args   []string
+		code   int
+		stdout string
+	}{
+		{"", []string{"compose", file("doc.json"), file("world.json")}, exitOK, `{"ops":[{"insert":"Hello world\n"}]}` + "\n"},
+		{"", []string{"compose", file("world.json"), file("delete.json")}, exitOK, `{"ops":[{"retain":1},{"insert":" world"},{"delete":4}]}` + "\n"},
+		{"", []string{"compose", file("doc.json"), file("long.json")}, exitFailed, ""},
+		{"", []string{"compose", file("doc.json")}, exitUsage, ""},
+		{"", []string{"transform", file("world.json"), file("there.json")}, exitOK, `{"ops":[{"retain":5},{"insert":" there"}]}` + "\n"},
+		{"", []string{"transform", file("world.json"), file("there.json"), "--priority"}, exitOK, `{"ops":[{"retain":11},{"insert":" there"}]}` + "\n"},
+		{"", []string{"transform", "--priority=false", file("there.json"), file("world.json")}, exitOK, `{"ops":[{"retain":5},{"insert":" world"}]}` + "\n"},
+		{"", []string{"invert", "--base", file("doc.json"), file("delete.json")}, exitOK, `{"ops":[{"retain":1},{"insert":"ello"}]}` + "\n"},
+		{`[{"retain":5,"attributes":{"bold":true}}]`, []string{"invert", "--base", file("doc.json")}, exitOK, `{"ops":[{"retain":5,"attributes":{"bold":null}}]}` + "\n"},
+		{"", []string{"invert", "--base", file("doc.json"), file("long.json")}, exitFailed, ""},
+		{"", []string{"invert", "--base", file("world.json"), file("delete.json")}, exitFailed, ""},
+		{"", []string{"invert", file("delete.json")}, exitUsage, ""},
+		{"", []string{"diff", file("doc.json"), file("doc.json")}, exitOK, `{"ops":[]}` + "\n"},
+		{"", []string{"diff", file("doc.json"), file("bold.json")}, exitOK,
+			`{"ops":[{"retain":5,"attributes":{"bold":true}},{"insert":" world","attributes":{"bold":true}},{"retain":1,"attributes":{"bold":true}}]}` + "\n"},
+		{"", []string{"diff", file("doc.json"), file("world.json")}, exitFailed, ""},
+		{"", []string{"slice", "--start", "1", "--end", "3", file("doc.json")}, exitOK, `{"ops":[{"insert":"el"}]}` + "\n"},
+		{"", []string{"slice", "--start", "4", file("doc.json")}, exitOK, `{"ops":[{"insert":"o\n"}]}` + "\n"},
+		{"", []string{"slice", "--start", "4", "--end", "2", file("doc.json")}, exitUsage, ""},
+		{"", []string{"length", file("world.json")}, exitOK, "11\n"},
+		{`[{"insert":"a"},{"insert":"b","attributes":{"bold":true}},{"insert":"c","attributes":{"bold":true}},{"retain":3}]`,
+			[]string{"normalize"}, exitOK, `{"ops":[{"insert":"a"},{"insert":"bc","attributes":{"bold":true}}]}` + "\n"},
+		{`[{"insert":"a","attributes":{"blink":true}},{"insert":"\n"}]`, []string{"normalize", "--schema"}, exitOK, `{"ops":[{"insert":"a\n"}]}` + "\n"},
+		{"", []string{"validate", "--document", file("doc.json")}, exitOK, ""},
+		{`[{"insert":"a","attributes":{"header":1}}]`, []string{"validate", "--document"}, exitInvalid,
+			"the document doesn't end with a newline\nindex 0 (op 0): header: block format on text\n"},
+		{"", []string{"validate", "--document", file("world.json")}, exitInvalid, "not a document, it has retain or delete ops\n"},
+		{"", []string{"validate", file("world.json")}, exitOK, ""},
+		{"", []string{"render", file("bold.json")}, exitOK, "<p><strong>Hello world</strong></p>\n"},
+		{"", []string{"render", "--format", "md", file("bold.json")}, exitOK, "**Hello world**\n"},
+		{"", []string{"render", "--format", "text", file("bold.json")}, exitOK, "Hello world\n"},
+		{"", []string{"render", "--format", "pdf", file("bold.json")}, exitUsage, ""},
+		{"", []string{"render", file("world.json")}, exitFailed, ""},
+	}
+	for _, test := range tests {
+		code, stdout, stderr := run(t, test.stdin, test.args...)
+		if code != test.code || stdout != test.stdout {
+			t.Errorf("%s: expected status %d and %q, got %d and %q (%s)", strings.Join(test.args, " "),
+				test.code, test.stdout, code, stdout, stderr)
+		}
+	}
+}
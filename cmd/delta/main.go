@@ -0,0 +1,309 @@
+// Command delta works with Quill deltas stored as JSON, to compose,
+// transform or render them without a browser. Deltas are read from files,
+// or from the standard input when the file is "-" or missing, either as
+// {"ops": [...]} or as the bare list of ops. Results are written to the
+// standard output.
+//
+// Usage:
+//
+//	delta compose FILE FILE...
+//	delta transform [--priority] FILE FILE
+//	delta invert --base FILE [FILE]
+//	delta diff FILE FILE
+//	delta slice [--start N] [--end N] [FILE]
+//	delta length [FILE]
+//	delta validate [--document] [FILE]
+//	delta normalize [--schema] [FILE]
+//	delta render [--format html|md|text] [FILE]
+//	delta replay [--initial FILE] [--snapshot FILE] [--until N] [--submitted] [--print] [LOG]
+//
+// replay replays an op log, with one revision as JSON per line, like
+//
+//	{"revision": 1, "base": 0, "author": "ann", "delta": {"ops": [...]}}
+//
+// as collab.Server stores them, each change already transformed against the
+// revisions before it, and reports the first revision that doesn't apply:
+// one out of order, one with a base after the revision before it, one longer
+// than the document or one leaving a document that doesn't end with a
+// newline. With --submitted the changes are the ones clients sent, and each
+// is transformed against the revisions since its base the way collab.Server
+// does. The result is then checked against the snapshot.
+//
+// The exit status is 0 on success, 1 when the operation fails, like
+// inverting a change against a document it doesn't apply to, 2 for wrong
+// arguments and 3 when an input isn't a valid delta.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+)
+
+// Exit statuses
+const (
+	exitOK      = 0
+	exitFailed  = 1
+	exitUsage   = 2
+	exitInvalid = 3
+)
+
+// usageError is a problem with the arguments of a command
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+// inputError is an input that can't be read or isn't a valid delta
+type inputError struct {
+	name string
+	err  error
+}
+
+func (e *inputError) Error() string {
+	if e.name == "" {
+		return e.err.Error()
+	}
+	return e.name + ": " + e.err.Error()
+}
+
+// command is a subcommand, run gets the arguments after its name
+type command struct {
+	args string
+	help string
+	run  func(c *cli, args []string) error
+}
+
+var commands = map[string]command{
+	"compose":   {"FILE FILE...", "compose the deltas, in order", (*cli).compose},
+	"transform": {"[--priority] FILE FILE", "transform the second delta against the first", (*cli).transform},
+	"invert":    {"--base FILE [FILE]", "invert a change against the document it applies to", (*cli).invert},
+	"diff":      {"FILE FILE", "the change from the first document to the second", (*cli).diff},
+	"slice":     {"[--start N] [--end N] [FILE]", "the ops between two indexes", (*cli).slice},
+	"length":    {"[FILE]", "print the length of the delta", (*cli).length},
+	"validate":  {"[--document] [FILE]", "check the ops and formats of the delta", (*cli).validate},
+	"normalize": {"[--schema] [FILE]", "merge ops and drop empty ones", (*cli).normalize},
+	"render":    {"[--format html|md|text] [FILE]", "render a document", (*cli).render},
+	"replay":    {"[--initial FILE] [--snapshot FILE] [--until N] [--submitted] [--print] [LOG]", "replay an op log and check the result", (*cli).replay},
+}
+
+// cli holds the streams of a run
+type cli struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+	// stdinRead is set once a delta has been read from stdin
+	stdinRead bool
+}
+
+func main() {
+	c := &cli{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}
+	os.Exit(c.main(os.Args[1:]))
+}
+
+// main runs the command in args and returns the exit status
+func (c *cli) main(args []string) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		c.usage()
+		if len(args) == 0 {
+			return exitUsage
+		}
+		return exitOK
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(c.stderr, "delta: unknown command %q\n", args[0])
+		c.usage()
+		return exitUsage
+	}
+	err := cmd.run(c, args[1:])
+	switch err.(type) {
+	case nil:
+		return exitOK
+	case *usageError:
+		fmt.Fprintf(c.stderr, "delta %s: %v\nusage: delta %s %s\n", args[0], err, args[0], cmd.args)
+		return exitUsage
+	case *inputError:
+		fmt.Fprintf(c.stderr, "delta %s: %v\n", args[0], err)
+		return exitInvalid
+	}
+	if err == flag.ErrHelp {
+		return exitOK
+	}
+	fmt.Fprintf(c.stderr, "delta %s: %v\n", args[0], err)
+	return exitFailed
+}
+
+func (c *cli) usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(c.stderr, "usage: delta COMMAND [ARGS]\n\ncommands:")
+	for _, name := range names {
+		fmt.Fprintf(c.stderr, "  %-10s %s\n", name, commands[name].help)
+	}
+	fmt.Fprintln(c.stderr, "\nFILE is a JSON delta, - or no FILE reads it from the standard input")
+}
+
+// flags returns a flag set for a command
+func (c *cli) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("delta "+name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	return fs
+}
+
+// parse parses args with fs, letting flags come after the files too, and
+// returns the files. A wrong flag is a usage error
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var files []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if err == flag.ErrHelp {
+				return nil, err
+			}
+			return nil, &usageError{err.Error()}
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return files, nil
+		}
+		files = append(files, args[0])
+		args = args[1:]
+	}
+}
+
+// read returns the delta in the file name, or in stdin when name is "" or
+// "-". The ops are checked to be well formed: one of insert, retain or
+// delete, with a positive length
+func (c *cli) read(name string) (*delta.Delta, error) {
+	var data []byte
+	var err error
+	if name == "" || name == "-" {
+		if c.stdinRead {
+			return nil, &usageError{"the standard input can only be read once"}
+		}
+		c.stdinRead = true
+		name = "stdin"
+		data, err = ioutil.ReadAll(c.stdin)
+	} else {
+		data, err = ioutil.ReadFile(name)
+	}
+	if err != nil {
+		// the error already names the file
+		return nil, &inputError{err: err}
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		data = append(append([]byte(`{"ops":`), data...), '}')
+	}
+	d, err := delta.FromJSON(data)
+	if err != nil {
+		return nil, &inputError{name, err}
+	}
+	for i, op := range d.Ops {
+		if err := checkOp(op); err != nil {
+			return nil, &inputError{name, fmt.Errorf("op %d: %v", i, err)}
+		}
+	}
+	return d, nil
+}
+
+// checkOp returns what is wrong with op, if anything
+func checkOp(op delta.Op) error {
+	kinds := 0
+	if op.Insert != nil || op.InsertEmbed != nil {
+		kinds++
+		if op.Insert != nil && len(op.Insert) == 0 {
+			return errors.New("empty insert")
+		}
+	}
+	if op.Retain != nil {
+		kinds++
+		if *op.Retain <= 0 {
+			return errors.New("retain must be positive")
+		}
+	}
+	if op.Delete != nil {
+		kinds++
+		if *op.Delete <= 0 {
+			return errors.New("delete must be positive")
+		}
+		if op.Attributes != nil {
+			return errors.New("delete with attributes")
+		}
+	}
+	if kinds != 1 {
+		return errors.New("must be one of insert, retain or delete")
+	}
+	return nil
+}
+
+// readAll reads the deltas in files, in order
+func (c *cli) readAll(files []string) ([]*delta.Delta, error) {
+	deltas := make([]*delta.Delta, len(files))
+	for i, name := range files {
+		d, err := c.read(name)
+		if err != nil {
+			return nil, err
+		}
+		deltas[i] = d
+	}
+	return deltas, nil
+}
+
+// readOne reads the delta of a command that takes at most one file
+func (c *cli) readOne(files []string) (*delta.Delta, error) {
+	switch len(files) {
+	case 0:
+		return c.read("")
+	case 1:
+		return c.read(files[0])
+	}
+	return nil, &usageError{"too many files"}
+}
+
+// write writes d as JSON, on one line
+func (c *cli) write(d *delta.Delta) error {
+	if d.Ops == nil {
+		d = delta.New([]delta.Op{})
+	}
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	_, err = c.stdout.Write(append(data, '\n'))
+	return err
+}
+
+// isDocument reports whether d only has inserts
+func isDocument(d *delta.Delta) bool {
+	for _, op := range d.Ops {
+		if op.Insert == nil && op.InsertEmbed == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// endsWithNewline reports whether the last op of d inserts text ending with
+// a newline
+func endsWithNewline(d *delta.Delta) bool {
+	if len(d.Ops) == 0 {
+		return false
+	}
+	last := d.Ops[len(d.Ops)-1]
+	return strings.HasSuffix(string(last.Insert), "\n")
+}
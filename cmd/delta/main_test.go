@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// run runs the command in args with stdin, and returns its exit status and
+// what it wrote to stdout and stderr
+func run(t *testing.T, stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	c := &cli{stdin: strings.NewReader(stdin), stdout: &stdout, stderr: &stderr}
+	return c.main(args), stdout.String(), stderr.String()
+}
+
+// tempFiles writes files, by name, to a new directory and returns it
+func tempFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "delta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestUsage(t *testing.T) {
+	if code, _, stderr := run(t, ""); code != exitUsage || !strings.Contains(stderr, "compose") {
+		t.Errorf("expected the usage and status %d, got %d: %s", exitUsage, code, stderr)
+	}
+	if code, _, _ := run(t, "", "help"); code != exitOK {
+		t.Errorf("expected status %d for help, got %d", exitOK, code)
+	}
+	if code, _, stderr := run(t, "", "frobnicate"); code != exitUsage || !strings.Contains(stderr, `unknown command "frobnicate"`) {
+		t.Errorf("expected an unknown command, got %d: %s", code, stderr)
+	}
+	if code, _, stderr := run(t, "", "length", "--bogus"); code != exitUsage || !strings.Contains(stderr, "usage: delta length") {
+		t.Errorf("expected a usage error, got %d: %s", code, stderr)
+	}
+}
+
+func TestRead(t *testing.T) {
+	dir := tempFiles(t, map[string]string{"ops.json": `[{"insert":"ab\n"}]`})
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		stdin  string
+		args   []string
+		code   int
+		output string
+	}{
+		{`{"ops":[{"insert":"abc"}]}`, []string{"length"}, exitOK, "3\n"},
+		{`{"ops":[{"insert":"abc"}]}`, []string{"length", "-"}, exitOK, "3\n"},
+		{"", []string{"length", filepath.Join(dir, "ops.json")}, exitOK, "3\n"},
+		{"", []string{"length", filepath.Join(dir, "missing.json")}, exitInvalid, "no such file"},
+		{`{"ops":`, []string{"length"}, exitInvalid, "stdin: unexpected end of JSON input"},
+		{`[{"retain":0}]`, []string{"length"}, exitInvalid, "op 0: retain must be positive"},
+		{`[{"delete":1,"attributes":{"bold":true}}]`, []string{"length"}, exitInvalid, "op 0: delete with attributes"},
+		{`[{"attributes":{"bold":true}}]`, []string{"length"}, exitInvalid, "op 0: must be one of insert, retain or delete"},
+		{`[{"insert":"a"}]`, []string{"compose", "-", "-"}, exitUsage, "the standard input can only be read once"},
+		{"", []string{"length", "a", "b"}, exitUsage, "too many files"},
+	}
+	for _, test := range tests {
+		code, stdout, stderr := run(t, test.stdin, test.args...)
+		if code != test.code || !strings.Contains(stdout+stderr, test.output) {
+			t.Errorf("%v: expected status %d and %q, got %d: %s%s", test.args, test.code, test.output, code, stdout, stderr)
+		}
+	}
+}
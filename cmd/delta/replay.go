@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/fmpwizard/go-quilljs-delta/collab"
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+)
+
+// maxLogLine is the longest line of an op log, a revision that pastes a
+// whole document can be long
+const maxLogLine = 64 << 20
+
+// replay replays an op log, one collab.Revision as JSON per line, through a
+// collab.Server, and stops at the first revision the server would reject or
+// that leaves a document not ending with a newline. The deltas are the
+// changes the server stored after transforming them, unless --submitted says
+// they are the changes as clients sent them, against their base revision
+func (c *cli) replay(args []string) error {
+	fs := c.flags("replay")
+	initial := fs.String("initial", "", "the document at revision 0, an empty one when not given")
+	snapshot := fs.String("snapshot", "", "the stored document to check the result against")
+	until := fs.Int("until", 0, "stop after this revision, the one of the snapshot, instead of at the end of the log")
+	submitted := fs.Bool("submitted", false, "the deltas are the changes clients sent, transform them against the revisions since their base")
+	printDoc := fs.Bool("print", false, "write the replayed document as JSON")
+	files, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) > 1 {
+		return &usageError{"too many files"}
+	}
+	var doc *delta.Delta
+	if *initial != "" {
+		if doc, err = c.read(*initial); err != nil {
+			return err
+		}
+		if !isDocument(doc) {
+			return &inputError{*initial, errNotDocument}
+		}
+	}
+	var snap *delta.Delta
+	if *snapshot != "" {
+		if snap, err = c.read(*snapshot); err != nil {
+			return err
+		}
+		if !isDocument(snap) {
+			return &inputError{*snapshot, errNotDocument}
+		}
+	}
+
+	name := "stdin"
+	var r io.Reader
+	if len(files) == 0 || files[0] == "-" {
+		if c.stdinRead {
+			return &usageError{"the standard input can only be read once"}
+		}
+		c.stdinRead = true
+		r = c.stdin
+	} else {
+		name = files[0]
+		f, err := os.Open(name)
+		if err != nil {
+			return &inputError{err: err}
+		}
+		defer f.Close()
+		r = f
+	}
+
+	server := collab.NewServer(doc)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxLogLine)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var rev collab.Revision
+		if err := json.Unmarshal(scanner.Bytes(), &rev); err != nil {
+			return &inputError{name, fmt.Errorf("line %d: %v", line, err)}
+		}
+		if rev.Change == nil {
+			return &inputError{name, fmt.Errorf("line %d: no delta", line)}
+		}
+		for i, op := range rev.Change.Ops {
+			if err := checkOp(op); err != nil {
+				return &inputError{name, fmt.Errorf("line %d: op %d: %v", line, i, err)}
+			}
+		}
+		if *until > 0 && rev.Number > *until {
+			break
+		}
+		if err := apply(server, rev, *submitted); err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return &inputError{name, err}
+	}
+
+	result, number := server.Document()
+	if *until > 0 && number < *until {
+		return fmt.Errorf("the log ends at revision %d, before revision %d", number, *until)
+	}
+	if snap != nil {
+		change, err := result.Diff(snap)
+		if err != nil {
+			return err
+		}
+		if len(change.Ops) > 0 {
+			fmt.Fprintln(c.stdout, "change from the replayed document to the snapshot:")
+			if err := c.write(change); err != nil {
+				return err
+			}
+			return fmt.Errorf("revision %d doesn't match the snapshot", number)
+		}
+	}
+	if *printDoc {
+		return c.write(result)
+	}
+	fmt.Fprintf(c.stdout, "replayed %d revisions, document length %d\n", number, result.Length())
+	if snap != nil {
+		fmt.Fprintln(c.stdout, "the document matches the snapshot")
+	}
+	return nil
+}
+
+// apply submits rev to server, and returns what went wrong with it: a
+// revision out of order, a base the server doesn't have, a change longer
+// than the document once transformed, or a document that doesn't end with
+// a newline after it. A submitted change is transformed against the
+// revisions since its base, a stored one applies to the latest revision
+func apply(server *collab.Server, rev collab.Revision, submitted bool) error {
+	number := server.Revision() + 1
+	if rev.Number != number {
+		return fmt.Errorf("%s: expected revision %d", describe(rev), number)
+	}
+	base := rev.Base
+	if !submitted {
+		if rev.Base > server.Revision() {
+			return fmt.Errorf("%s: unknown base revision %d", describe(rev), rev.Base)
+		}
+		base = server.Revision()
+	}
+	_, err := server.Submit(rev.Author, base, rev.Change)
+	switch err {
+	case nil:
+	case collab.ErrUnknownRevision:
+		return fmt.Errorf("%s: unknown base revision %d", describe(rev), rev.Base)
+	case collab.ErrInvalidChange:
+		// the same transforms Submit did, to report the length it checked
+		change := rev.Change
+		revs, _ := server.Since(base)
+		for _, r := range revs {
+			change = r.Change.Transform(*change, true)
+		}
+		doc, _ := server.Document()
+		return fmt.Errorf("%s: lengths out of sync, change of length %d for a document of length %d",
+			describe(rev), collab.BaseLength(change), doc.Length())
+	default:
+		return fmt.Errorf("%s: %v", describe(rev), err)
+	}
+	if doc, _ := server.Document(); !endsWithNewline(doc) {
+		return fmt.Errorf("%s: %v", describe(rev), errNoNewline)
+	}
+	return nil
+}
+
+// describe names a revision in messages
+func describe(rev collab.Revision) string {
+	s := fmt.Sprintf("revision %d (base %d", rev.Number, rev.Base)
+	if rev.Author != "" {
+		s += fmt.Sprintf(", author %q", rev.Author)
+	}
+	return s + ")"
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fmpwizard/go-quilljs-delta/collab"
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+)
+
+// opLog is a log of three revisions as the server stores them, the last two
+// concurrent
+const opLog = `{"revision":1,"base":0,"author":"ann","delta":{"ops":[{"insert":"Hello\n"}]}}
+{"revision":2,"base":1,"author":"ann","delta":{"ops":[{"retain":5},{"insert":" world"}]}}
+
+{"revision":3,"base":1,"author":"bob","delta":{"ops":[{"retain":11},{"insert":"!"}]}}
+`
+
+// submittedLog is opLog with the changes as the clients sent them
+const submittedLog = `{"revision":1,"base":0,"author":"ann","delta":{"ops":[{"insert":"Hello\n"}]}}
+{"revision":2,"base":1,"author":"ann","delta":{"ops":[{"retain":5},{"insert":" world"}]}}
+
+{"revision":3,"base":1,"author":"bob","delta":{"ops":[{"retain":5},{"insert":"!"}]}}
+`
+
+func TestReplay(t *testing.T) {
+	dir := tempFiles(t, map[string]string{
+		"log.jsonl":     opLog,
+		"snapshot.json": `{"ops":[{"insert":"Hello world!\n"}]}`,
+		"rev2.json":     `{"ops":[{"insert":"Hello world\n"}]}`,
+		"initial.json":  `{"ops":[{"insert":"Hi\n"}]}`,
+		"change.json":   `[{"retain":1}]`,
+	})
+	defer os.RemoveAll(dir)
+	file := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+	// revision replaces the line of revision n in log
+	revision := func(log string, n int, line string) string {
+		lines := strings.Split(log, "\n")
+		if n == 3 {
+			n = 4
+		}
+		lines[n-1] = line
+		return strings.Join(lines, "\n")
+	}
+
+	tests := []struct {
+		stdin  string
+		args   []string
+		code   int
+		output string
+	}{
+		{"", []string{"replay", "--snapshot", file("snapshot.json"), file("log.jsonl")}, exitOK,
+			"replayed 3 revisions, document length 13\nthe document matches the snapshot\n"},
+		{opLog, []string{"replay", "--print"}, exitOK, `{"ops":[{"insert":"Hello world!\n"}]}` + "\n"},
+		{submittedLog, []string{"replay", "--submitted", "--print"}, exitOK, `{"ops":[{"insert":"Hello world!\n"}]}` + "\n"},
+		{submittedLog, []string{"replay", "--print"}, exitOK, `{"ops":[{"insert":"Hello! world\n"}]}` + "\n"},
+		{opLog, []string{"replay", "--until", "2", "--snapshot", file("rev2.json")}, exitOK,
+			"replayed 2 revisions, document length 12\nthe document matches the snapshot\n"},
+		{opLog, []string{"replay", "--until", "4"}, exitFailed, "the log ends at revision 3, before revision 4"},
+		{opLog, []string{"replay", "--snapshot", file("rev2.json")}, exitFailed,
+			"change from the replayed document to the snapshot:\n" + `{"ops":[{"retain":11},{"delete":1}]}` + "\ndelta replay: revision 3 doesn't match the snapshot"},
+		{`{"revision":1,"base":0,"delta":{"ops":[{"retain":2},{"insert":"o"}]}}`, []string{"replay", "--initial", file("initial.json"), "--print"}, exitOK,
+			`{"ops":[{"insert":"Hio\n"}]}` + "\n"},
+		{revision(opLog, 2, `{"revision":2,"base":1,"author":"ann","delta":{"ops":[{"retain":10},{"delete":3}]}}`), []string{"replay"}, exitFailed,
+			`line 2: revision 2 (base 1, author "ann"): lengths out of sync, change of length 13 for a document of length 6`},
+		{revision(opLog, 3, `{"revision":3,"base":1,"author":"bob","delta":{"ops":[{"retain":12},{"delete":1}]}}`), []string{"replay"}, exitFailed,
+			`line 4: revision 3 (base 1, author "bob"): lengths out of sync, change of length 13 for a document of length 12`},
+		{revision(submittedLog, 3, `{"revision":3,"base":1,"author":"bob","delta":{"ops":[{"retain":6},{"delete":1}]}}`), []string{"replay", "--submitted"}, exitFailed,
+			`line 4: revision 3 (base 1, author "bob"): lengths out of sync, change of length 13 for a document of length 12`},
+		{revision(opLog, 3, `{"revision":3,"base":1,"author":"bob","delta":{"ops":[{"retain":12},{"insert":"?"}]}}`), []string{"replay"}, exitFailed,
+			`line 4: revision 3 (base 1, author "bob"): the document doesn't end with a newline`},
+		{revision(submittedLog, 3, `{"revision":3,"base":1,"author":"bob","delta":{"ops":[{"retain":6},{"insert":"?"}]}}`), []string{"replay", "--submitted"}, exitFailed,
+			`line 4: revision 3 (base 1, author "bob"): the document doesn't end with a newline`},
+		{revision(opLog, 2, `{"revision":2,"base":1,"delta":{"ops":[{"retain":5},{"delete":1}]}}`), []string{"replay"}, exitFailed,
+			"line 2: revision 2 (base 1): the document doesn't end with a newline"},
+		{revision(opLog, 3, `{"revision":4,"base":1,"author":"bob","delta":{"ops":[{"insert":"!"}]}}`), []string{"replay"}, exitFailed,
+			`line 4: revision 4 (base 1, author "bob"): expected revision 3`},
+		{revision(opLog, 3, `{"revision":3,"base":3,"author":"bob","delta":{"ops":[{"insert":"!"}]}}`), []string{"replay"}, exitFailed,
+			`line 4: revision 3 (base 3, author "bob"): unknown base revision 3`},
+		{revision(submittedLog, 3, `{"revision":3,"base":3,"author":"bob","delta":{"ops":[{"insert":"!"}]}}`), []string{"replay", "--submitted"}, exitFailed,
+			`line 4: revision 3 (base 3, author "bob"): unknown base revision 3`},
+		{revision(opLog, 2, `{"revision":2,`), []string{"replay"}, exitInvalid, "stdin: line 2: unexpected end of JSON input"},
+		{revision(opLog, 2, `{"revision":2,"base":1}`), []string{"replay"}, exitInvalid, "stdin: line 2: no delta"},
+		{revision(opLog, 2, `{"revision":2,"base":1,"delta":{"ops":[{"retain":-1}]}}`), []string{"replay"}, exitInvalid, "stdin: line 2: op 0: retain must be positive"},
+		{"", []string{"replay", file("missing.jsonl")}, exitInvalid, "no such file"},
+		{"", []string{"replay", "--initial", file("change.json"), file("log.jsonl")}, exitInvalid, "change.json: not a document"},
+		{"", []string{"replay", file("log.jsonl"), file("log.jsonl")}, exitUsage, "too many files"},
+	}
+	for _, test := range tests {
+		code, stdout, stderr := run(t, test.stdin, test.args...)
+		if code != test.code || !strings.Contains(stdout+stderr, test.output) {
+			t.Errorf("%s: expected status %d and %q, got %d: %s%s", strings.Join(test.args, " "),
+				test.code, test.output, code, stdout, stderr)
+		}
+	}
+}
+
+func TestReplayServerLog(t *testing.T) {
+	server := collab.NewServer(nil)
+	changes := []struct {
+		base   int
+		author string
+		change *delta.Delta
+	}{
+		{0, "ann", delta.New(nil).Insert("Hello\n", nil)},
+		{1, "ann", delta.New(nil).Retain(5, nil).Insert(" world", nil)},
+		{1, "bob", delta.New(nil).Retain(5, nil).Insert("!", nil)},
+		{2, "bob", delta.New(nil).Retain(5, delta.Attributes{"bold": true})},
+	}
+	for _, c := range changes {
+		if _, err := server.Submit(c.author, c.base, c.change); err != nil {
+			t.Fatal(err)
+		}
+	}
+	revs, _ := server.Since(0)
+	var log strings.Builder
+	for _, rev := range revs {
+		data, _ := json.Marshal(rev)
+		log.Write(append(data, '\n'))
+	}
+	doc, _ := server.Document()
+	expected, _ := json.Marshal(doc)
+
+	code, stdout, stderr := run(t, log.String(), "replay", "--print")
+	if code != exitOK || stdout != string(expected)+"\n" {
+		t.Errorf("expected %s, got %d: %s%s", expected, code, stdout, stderr)
+	}
+	// transforming them again gets them wrong
+	if code, _, _ := run(t, log.String(), "replay", "--submitted", "--print"); code != exitFailed {
+		t.Errorf("expected status %d replaying transformed changes, got %d", exitFailed, code)
+	}
+}
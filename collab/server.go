@@ -0,0 +1,197 @@
+// Package collab implements a collaboration server for Quill documents. The
+// Server keeps the current document and the list of changes applied to it,
+// and transforms concurrent changes the same way quill's server side
+// examples do. Transports (see SSEHandler) only move deltas around, all of
+// them share the same Server.
+package collab
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+)
+
+var (
+	// ErrUnknownRevision is returned when a client refers to a revision the
+	// server doesn't have yet
+	ErrUnknownRevision = errors.New("collab: unknown revision")
+	// ErrInvalidChange is returned when a change retains or deletes past the
+	// end of the document
+	ErrInvalidChange = errors.New("collab: change does not apply to the document")
+)
+
+// Revision is a change applied to the document. Number is the revision of the
+// document after applying Change, the first change creates revision 1
+type Revision struct {
+	Number int          `json:"revision"`
+	Base   int          `json:"base"`
+	Author string       `json:"author,omitempty"`
+	Change *delta.Delta `json:"delta"`
+}
+
+// Listener gets notified of every revision applied to the document
+type Listener interface {
+	Applied(rev Revision)
+}
+
+// ListenerFunc lets you use a plain function as a Listener
+type ListenerFunc func(rev Revision)
+
+// Applied calls f(rev)
+func (f ListenerFunc) Applied(rev Revision) {
+	f(rev)
+}
+
+// Server holds one document and its history
+type Server struct {
+	mu        sync.Mutex
+	doc       *delta.Delta
+	history   []Revision
+	listeners map[int]Listener
+	nextID    int
+	// pending are the revisions waiting to be delivered, delivering is set
+	// while a Submit call delivers them
+	pending    []notification
+	delivering bool
+}
+
+// notification is a revision and the listeners it goes to
+type notification struct {
+	rev       Revision
+	listeners []Listener
+}
+
+// NewServer creates a Server starting from doc at revision 0. A nil doc
+// starts with an empty document
+func NewServer(doc *delta.Delta) *Server {
+	if doc == nil {
+		doc = delta.New(nil)
+	}
+	return &Server{
+		doc:       doc,
+		listeners: make(map[int]Listener),
+	}
+}
+
+// Document returns a copy of the current document and its revision
+func (s *Server) Document() (*delta.Delta, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return delta.New(append([]delta.Op(nil), s.doc.Ops...)), len(s.history)
+}
+
+// Revision returns the current revision number
+func (s *Server) Revision() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.history)
+}
+
+// Since returns all the revisions after rev, in order
+func (s *Server) Since(rev int) ([]Revision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if rev < 0 || rev > len(s.history) {
+		return nil, ErrUnknownRevision
+	}
+	return append([]Revision(nil), s.history[rev:]...), nil
+}
+
+// Submit applies change, written by author against revision base. If other
+// changes were applied since base, change is transformed against them first,
+// giving priority to the changes the server already has. The returned
+// Revision holds the transformed change, which is what other clients need
+// to apply.
+//
+// Listeners are called without holding any lock, so they can call Submit,
+// like a bot answering an edit. The revision it creates is delivered once
+// the current one has reached every listener, by the Submit call that is
+// already delivering, and the nested call returns right away
+func (s *Server) Submit(author string, base int, change *delta.Delta) (Revision, error) {
+	s.mu.Lock()
+	if base < 0 || base > len(s.history) {
+		s.mu.Unlock()
+		return Revision{}, ErrUnknownRevision
+	}
+	for _, rev := range s.history[base:] {
+		change = rev.Change.Transform(*change, true)
+	}
+	if BaseLength(change) > s.doc.Length() {
+		s.mu.Unlock()
+		return Revision{}, ErrInvalidChange
+	}
+	s.doc = s.doc.Compose(*change)
+	rev := Revision{
+		Number: len(s.history) + 1,
+		Base:   base,
+		Author: author,
+		Change: change,
+	}
+	s.history = append(s.history, rev)
+	s.pending = append(s.pending, notification{rev: rev, listeners: s.sortedListeners()})
+	if s.delivering {
+		// delivered in order by the call that is delivering the revisions
+		// before this one
+		s.mu.Unlock()
+		return rev, nil
+	}
+	s.delivering = true
+	for len(s.pending) > 0 {
+		n := s.pending[0]
+		s.pending = s.pending[1:]
+		s.mu.Unlock()
+		for _, l := range n.listeners {
+			l.Applied(n.rev)
+		}
+		s.mu.Lock()
+	}
+	s.delivering = false
+	s.mu.Unlock()
+	return rev, nil
+}
+
+// Subscribe registers l to receive every new revision, and returns a function
+// that removes the subscription. Revisions are delivered in order, one at a
+// time, so Applied should not block for long. See Submit for listeners that
+// submit changes
+func (s *Server) Subscribe(l Listener) func() {
+	s.mu.Lock()
+	id := s.nextID
+	s.nextID++
+	s.listeners[id] = l
+	s.mu.Unlock()
+	return func() {
+		s.mu.Lock()
+		delete(s.listeners, id)
+		s.mu.Unlock()
+	}
+}
+
+func (s *Server) sortedListeners() []Listener {
+	keys := make([]int, 0, len(s.listeners))
+	for k := range s.listeners {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	ret := make([]Listener, 0, len(keys))
+	for _, k := range keys {
+		ret = append(ret, s.listeners[k])
+	}
+	return ret
+}
+
+// BaseLength returns the length of the document a change applies to, that is
+// the sum of its retains and deletes
+func BaseLength(change *delta.Delta) int {
+	length := 0
+	for _, op := range change.Ops {
+		if op.Retain != nil {
+			length += *op.Retain
+		} else if op.Delete != nil {
+			length += *op.Delete
+		}
+	}
+	return length
+}
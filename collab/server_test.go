@@ -0,0 +1,119 @@
+package collab
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+)
+
+func docText(d *delta.Delta) string {
+	s := ""
+	for _, op := range d.Ops {
+		s += string(op.Insert)
+	}
+	return s
+}
+
+func TestSubmitSequential(t *testing.T) {
+	s := NewServer(delta.New(nil).Insert("Hello\n", nil))
+	rev, err := s.Submit("a", 0, delta.New(nil).Retain(5, nil).Insert(" World", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rev.Number != 1 {
+		t.Errorf("expected revision 1, got %d\n", rev.Number)
+	}
+	doc, n := s.Document()
+	if n != 1 || docText(doc) != "Hello World\n" {
+		t.Errorf("unexpected document %q at %d\n", docText(doc), n)
+	}
+}
+
+func TestSubmitConcurrent(t *testing.T) {
+	s := NewServer(delta.New(nil).Insert("Hello\n", nil))
+	// both clients are at revision 0
+	if _, err := s.Submit("a", 0, delta.New(nil).Insert(">> ", nil)); err != nil {
+		t.Fatal(err)
+	}
+	rev, err := s.Submit("b", 0, delta.New(nil).Retain(5, nil).Insert("!", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := BaseLength(rev.Change); l != 8 {
+		t.Errorf("expected transformed change to retain 8, got %d\n", l)
+	}
+	doc, _ := s.Document()
+	if docText(doc) != ">> Hello!\n" {
+		t.Errorf("unexpected document %q\n", docText(doc))
+	}
+}
+
+func TestSubmitErrors(t *testing.T) {
+	s := NewServer(delta.New(nil).Insert("Hi\n", nil))
+	if _, err := s.Submit("a", 1, delta.New(nil).Insert("x", nil)); err != ErrUnknownRevision {
+		t.Errorf("expected ErrUnknownRevision, got %v\n", err)
+	}
+	if _, err := s.Submit("a", 0, delta.New(nil).Retain(10, nil).Insert("x", nil)); err != ErrInvalidChange {
+		t.Errorf("expected ErrInvalidChange, got %v\n", err)
+	}
+	if s.Revision() != 0 {
+		t.Errorf("failed submits should not create revisions")
+	}
+}
+
+func TestSinceAndSubscribe(t *testing.T) {
+	s := NewServer(nil)
+	var got []int
+	cancel := s.Subscribe(ListenerFunc(func(rev Revision) {
+		got = append(got, rev.Number)
+	}))
+	s.Submit("a", 0, delta.New(nil).Insert("a", nil))
+	s.Submit("a", 1, delta.New(nil).Insert("b", nil))
+	cancel()
+	s.Submit("a", 2, delta.New(nil).Insert("c", nil))
+	if len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Errorf("unexpected notifications %v\n", got)
+	}
+	revs, err := s.Since(1)
+	if err != nil || len(revs) != 2 || revs[0].Number != 2 {
+		t.Errorf("unexpected Since(1) %+v, %v\n", revs, err)
+	}
+	if _, err := s.Since(4); err != ErrUnknownRevision {
+		t.Errorf("expected ErrUnknownRevision, got %v\n", err)
+	}
+}
+
+func TestSubmitFromListener(t *testing.T) {
+	s := NewServer(delta.New(nil).Insert("Hi\n", nil))
+	var got []string
+	s.Subscribe(ListenerFunc(func(rev Revision) {
+		got = append(got, rev.Author)
+		if rev.Author == "a" {
+			// a bot that answers every edit
+			if _, err := s.Submit("bot", rev.Number, delta.New(nil).Retain(3, nil).Insert("!", nil)); err != nil {
+				t.Error(err)
+			}
+		}
+	}))
+	s.Subscribe(ListenerFunc(func(rev Revision) {
+		got = append(got, "second "+rev.Author)
+	}))
+	done := make(chan struct{})
+	go func() {
+		s.Submit("a", 0, delta.New(nil).Insert(">", nil))
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Submit from a listener deadlocked")
+	}
+	if expected := []string{"a", "second a", "bot", "second bot"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected notifications %v, got %v\n", expected, got)
+	}
+	if doc, n := s.Document(); n != 2 || docText(doc) != ">Hi!\n" {
+		t.Errorf("unexpected document %q at %d\n", docText(doc), n)
+	}
+}
@@ -0,0 +1,228 @@
+package collab
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+)
+
+// SSEHandler is an HTTP transport for clients that can't use WebSockets.
+//
+// Clients open an event stream with GET ?client=<id> and receive:
+//
+//	event: snapshot  the whole document, only when the client doesn't resume
+//	event: ack       one of the client's own changes was applied
+//	event: op        somebody else's change, already transformed
+//
+// The id of every event is the document revision it leaves the client at, so
+// when the browser reconnects with a Last-Event-ID header, the stream resumes
+// with the revisions after it instead of sending the snapshot again.
+//
+// Changes are sent with POST ?client=<id> and a body like
+// {"base": 4, "delta": {"ops": [...]}}. The response is 202 with the new
+// revision number, the ack also shows up on the stream.
+type SSEHandler struct {
+	Server *Server
+	// Heartbeat is how often we send a comment to keep idle connections
+	// alive through proxies. Zero uses 15 seconds
+	Heartbeat time.Duration
+	// Buffer is how many revisions can be queued for a slow client before
+	// we drop its connection, it will resume with Last-Event-ID. Zero uses 64
+	Buffer int
+	// MaxBodySize limits the size of a POSTed change. Zero uses 1MB
+	MaxBodySize int64
+}
+
+// NewSSEHandler creates an SSEHandler for s using the default settings
+func NewSSEHandler(s *Server) *SSEHandler {
+	return &SSEHandler{Server: s}
+}
+
+type submitRequest struct {
+	Base  int             `json:"base"`
+	Delta json.RawMessage `json:"delta"`
+}
+
+type submitResponse struct {
+	Revision int `json:"revision"`
+}
+
+type snapshotEvent struct {
+	Revision int          `json:"revision"`
+	Delta    *delta.Delta `json:"delta"`
+}
+
+func (h *SSEHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.stream(w, r)
+	case http.MethodPost:
+		h.submit(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *SSEHandler) submit(w http.ResponseWriter, r *http.Request) {
+	maxSize := h.MaxBodySize
+	if maxSize <= 0 {
+		maxSize = 1 << 20
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxSize+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if int64(len(body)) > maxSize {
+		http.Error(w, "change too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	var req submitRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.Delta) == 0 {
+		http.Error(w, "missing delta", http.StatusBadRequest)
+		return
+	}
+	change, err := delta.FromJSON(req.Delta)
+	if err != nil {
+		http.Error(w, "invalid delta: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	rev, err := h.Server.Submit(r.URL.Query().Get("client"), req.Base, change)
+	if err == ErrUnknownRevision || err == ErrInvalidChange {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(submitResponse{Revision: rev.Number})
+}
+
+func (h *SSEHandler) stream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	client := r.URL.Query().Get("client")
+
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		// EventSource polyfills can't always set headers
+		lastID = r.URL.Query().Get("lastEventId")
+	}
+	resume := -1
+	if lastID != "" {
+		n, err := strconv.Atoi(lastID)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+		resume = n
+	}
+
+	bufSize := h.Buffer
+	if bufSize <= 0 {
+		bufSize = 64
+	}
+	revs := make(chan Revision, bufSize)
+	overflow := make(chan struct{})
+	var once sync.Once
+	// subscribe before reading the history, so we can't miss a revision. We
+	// may get some twice, those are skipped using the revision number
+	cancel := h.Server.Subscribe(ListenerFunc(func(rev Revision) {
+		select {
+		case revs <- rev:
+		default:
+			once.Do(func() { close(overflow) })
+		}
+	}))
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	sent := 0
+	if resume < 0 || resume > h.Server.Revision() {
+		doc, rev := h.Server.Document()
+		if err := writeEvent(w, rev, "snapshot", snapshotEvent{Revision: rev, Delta: doc}); err != nil {
+			return
+		}
+		sent = rev
+	} else {
+		missed, err := h.Server.Since(resume)
+		if err != nil {
+			return
+		}
+		sent = resume
+		for _, rev := range missed {
+			if err := writeRevision(w, client, rev); err != nil {
+				return
+			}
+			sent = rev.Number
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := h.Heartbeat
+	if heartbeat <= 0 {
+		heartbeat = 15 * time.Second
+	}
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-overflow:
+			return
+		case <-ticker.C:
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return
+			}
+		case rev := <-revs:
+			if rev.Number <= sent {
+				continue
+			}
+			if err := writeRevision(w, client, rev); err != nil {
+				return
+			}
+			sent = rev.Number
+		}
+		flusher.Flush()
+	}
+}
+
+func writeRevision(w io.Writer, client string, rev Revision) error {
+	name := "op"
+	if client != "" && rev.Author == client {
+		name = "ack"
+	}
+	return writeEvent(w, rev.Number, name, rev)
+}
+
+func writeEvent(w io.Writer, id int, name string, data interface{}) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", id, name, b)
+	return err
+}
@@ -0,0 +1,146 @@
+package collab
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+)
+
+type event struct {
+	id, name, data string
+}
+
+func readEvent(t *testing.T, r *bufio.Reader) event {
+	var e event
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read event: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "":
+			if e.name != "" {
+				return e
+			}
+		case strings.HasPrefix(line, "id: "):
+			e.id = line[4:]
+		case strings.HasPrefix(line, "event: "):
+			e.name = line[7:]
+		case strings.HasPrefix(line, "data: "):
+			e.data = line[6:]
+		}
+	}
+}
+
+func openStream(t *testing.T, ctx context.Context, url, lastID string) *bufio.Reader {
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	req = req.WithContext(ctx)
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	return bufio.NewReader(res.Body)
+}
+
+func post(t *testing.T, url, body string) *http.Response {
+	res, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	return res
+}
+
+func TestSSEStream(t *testing.T) {
+	s := NewServer(delta.New(nil).Insert("Hello\n", nil))
+	ts := httptest.NewServer(&SSEHandler{Server: s, Heartbeat: time.Hour})
+	defer ts.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r := openStream(t, ctx, ts.URL+"?client=a", "")
+	e := readEvent(t, r)
+	if e.name != "snapshot" || e.id != "0" {
+		t.Fatalf("expected snapshot at 0, got %+v", e)
+	}
+	var snap struct {
+		Delta delta.Delta `json:"delta"`
+	}
+	if err := json.Unmarshal([]byte(e.data), &snap); err != nil || docText(&snap.Delta) != "Hello\n" {
+		t.Errorf("unexpected snapshot %s, %v", e.data, err)
+	}
+
+	res := post(t, ts.URL+"?client=a", `{"base":0,"delta":{"ops":[{"retain":5},{"insert":"!"}]}}`)
+	if res.StatusCode != http.StatusAccepted {
+		t.Errorf("expected 202, got %d", res.StatusCode)
+	}
+	if e := readEvent(t, r); e.name != "ack" || e.id != "1" {
+		t.Errorf("expected ack for revision 1, got %+v", e)
+	}
+	post(t, ts.URL+"?client=b", `{"base":0,"delta":{"ops":[{"insert":"> "}]}}`)
+	e = readEvent(t, r)
+	if e.name != "op" || e.id != "2" {
+		t.Errorf("expected op for revision 2, got %+v", e)
+	}
+	var rev Revision
+	if err := json.Unmarshal([]byte(e.data), &rev); err != nil || rev.Author != "b" || rev.Base != 0 {
+		t.Errorf("unexpected revision %s, %v", e.data, err)
+	}
+}
+
+func TestSSEResume(t *testing.T) {
+	s := NewServer(delta.New(nil).Insert("Hello\n", nil))
+	s.Submit("a", 0, delta.New(nil).Insert("1", nil))
+	s.Submit("b", 1, delta.New(nil).Insert("2", nil))
+	s.Submit("b", 2, delta.New(nil).Insert("3", nil))
+	ts := httptest.NewServer(NewSSEHandler(s))
+	defer ts.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r := openStream(t, ctx, ts.URL+"?client=a", "1")
+	for _, id := range []string{"2", "3"} {
+		if e := readEvent(t, r); e.name != "op" || e.id != id {
+			t.Errorf("expected op %s, got %+v", id, e)
+		}
+	}
+	post(t, ts.URL+"?client=a", `{"base":3,"delta":{"ops":[{"insert":"4"}]}}`)
+	if e := readEvent(t, r); e.name != "ack" || e.id != "4" {
+		t.Errorf("expected ack 4, got %+v", e)
+	}
+}
+
+func TestSSESubmitErrors(t *testing.T) {
+	ts := httptest.NewServer(NewSSEHandler(NewServer(nil)))
+	defer ts.Close()
+	if res := post(t, ts.URL, `{"base":0,"delta":{"ops":[{"insert":{"a":1,"b":2}}]}}`); res.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid delta, got %d", res.StatusCode)
+	}
+	if res := post(t, ts.URL, `{"base":3,"delta":{"ops":[{"insert":"x"}]}}`); res.StatusCode != http.StatusConflict {
+		t.Errorf("expected 409 for unknown revision, got %d", res.StatusCode)
+	}
+	req, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
+	req.Header.Set("Last-Event-ID", "abc")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid Last-Event-ID, got %d", res.StatusCode)
+	}
+}
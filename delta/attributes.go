@@ -0,0 +1,319 @@
+package delta
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// Attributes are the formats of an op, like {"bold": true, "header": 1}.
+// A key set to nil is different from a missing key: on a retain it means
+// "remove this format", like null does in quill.
+//
+// Attributes are treated as immutable, none of the methods modify their
+// receiver, With and Without return a modified copy instead. Since the
+// underlying type is a plain map, code written for map[string]interface{}
+// keeps working: map literals can be used wherever Attributes are expected
+// and Attributes can be passed to functions that take a map.
+type Attributes map[string]interface{}
+
+// NewAttributes returns a copy of m as Attributes, nil if m is empty
+func NewAttributes(m map[string]interface{}) Attributes {
+	return Attributes(m).Clone()
+}
+
+// Clone returns a copy of a, nil if a is empty
+func (a Attributes) Clone() Attributes {
+	if len(a) == 0 {
+		return nil
+	}
+	ret := make(Attributes, len(a))
+	for k, v := range a {
+		ret[k] = v
+	}
+	return ret
+}
+
+// With returns a copy of a with key set to value. Use a nil value to remove
+// the format when retaining
+func (a Attributes) With(key string, value interface{}) Attributes {
+	ret := make(Attributes, len(a)+1)
+	for k, v := range a {
+		ret[k] = v
+	}
+	ret[key] = value
+	return ret
+}
+
+// Without returns a copy of a without key
+func (a Attributes) Without(key string) Attributes {
+	if _, found := a[key]; !found {
+		return a
+	}
+	ret := make(Attributes, len(a))
+	for k, v := range a {
+		if k != key {
+			ret[k] = v
+		}
+	}
+	if len(ret) == 0 {
+		return nil
+	}
+	return ret
+}
+
+// Map returns a as a plain map, for code that needs that exact type
+func (a Attributes) Map() map[string]interface{} {
+	return map[string]interface{}(a)
+}
+
+// Keys returns the attribute names, sorted
+func (a Attributes) Keys() []string {
+	keys := make([]string, 0, len(a))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Has tells you if key is set, even if it is set to nil
+func (a Attributes) Has(key string) bool {
+	_, found := a[key]
+	return found
+}
+
+// IsNull tells you if key is set to nil, which is how a retain removes a
+// format
+func (a Attributes) IsNull(key string) bool {
+	v, found := a[key]
+	return found && v == nil
+}
+
+// Bool returns the value of key if it is a bool
+func (a Attributes) Bool(key string) (bool, bool) {
+	v, ok := a[key].(bool)
+	return v, ok
+}
+
+// String returns the value of key if it is a string
+func (a Attributes) String(key string) (string, bool) {
+	v, ok := a[key].(string)
+	return v, ok
+}
+
+// Float returns the value of key if it is a number
+func (a Attributes) Float(key string) (float64, bool) {
+	return toFloat(a[key])
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// Int returns the value of key if it is a whole number. Numbers decoded from
+// JSON are float64, so {"header": 2} works as expected
+func (a Attributes) Int(key string) (int, bool) {
+	if v, ok := a[key].(int); ok {
+		return v, true
+	}
+	f, ok := a.Float(key)
+	if !ok || f != math.Trunc(f) || math.Abs(f) > math.MaxInt32 {
+		return 0, false
+	}
+	return int(f), true
+}
+
+// Equal tells you if two sets of attributes are the same. nil and empty
+// attributes are equal, and the usual values (strings, bools and numbers)
+// are compared without reflection
+func (a Attributes) Equal(b Attributes) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	if len(a) == 0 || sameMap(a, b) {
+		// interned attributes are equal when they are the same map
+		return true
+	}
+	for k, va := range a {
+		vb, found := b[k]
+		if !found || !attrValueEqual(va, vb) {
+			return false
+		}
+	}
+	return true
+}
+
+// Compose combines a with b, b wins. Unless keepNil is true, keys set to nil
+// are removed from the result. The result is always a new map, or nil
+func (a Attributes) Compose(b Attributes, keepNil bool) Attributes {
+	ret := a.compose(b, keepNil)
+	if len(b) == 0 && len(ret) > 0 && sameMap(ret, a) {
+		return ret.Clone()
+	}
+	return ret
+}
+
+// compose is Compose, but returns a itself when b is empty and there is
+// nothing to remove. Only use it where the result goes into an op, which
+// never changes its attributes in place
+func (a Attributes) compose(b Attributes, keepNil bool) Attributes {
+	// composing with nothing is very common (every plain retain in Compose),
+	// avoid building a new map when the result would be a copy of a
+	if len(b) == 0 && (keepNil || !a.hasNil()) {
+		if len(a) == 0 {
+			return nil
+		}
+		return a
+	}
+	attributes := make(Attributes, len(a)+len(b))
+	for k, v := range b {
+		if keepNil || v != nil {
+			attributes[k] = v
+		}
+	}
+	for k, v := range a {
+		if _, found := b[k]; !found && (keepNil || v != nil) {
+			attributes[k] = v
+		}
+	}
+	if len(attributes) > 0 {
+		return attributes
+	}
+	return nil
+}
+
+// Diff returns the attributes you need to retain with to go from a to b
+func (a Attributes) Diff(b Attributes) Attributes {
+	attributes := make(Attributes)
+	for k, va := range a {
+		vb, found := b[k]
+		if !found {
+			attributes[k] = nil
+		} else if !attrValueEqual(va, vb) {
+			attributes[k] = vb
+		}
+	}
+	for k, vb := range b {
+		if _, found := a[k]; !found {
+			attributes[k] = vb
+		}
+	}
+	if len(attributes) > 0 {
+		return attributes
+	}
+	return nil
+}
+
+// Transform returns b transformed against a. When a has priority, the keys
+// a already sets are dropped from b
+func (a Attributes) Transform(b Attributes, priority bool) Attributes {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return nil
+	}
+	// b simply overwrites us without priority
+	if !priority {
+		return b
+	}
+	attributes := make(Attributes)
+	for k, v := range b {
+		if _, found := a[k]; !found {
+			// nil is a valid value
+			attributes[k] = v
+		}
+	}
+	if len(attributes) > 0 {
+		return attributes
+	}
+	return nil
+}
+
+// Invert returns the attributes that undo retaining base with a
+func (a Attributes) Invert(base Attributes) Attributes {
+	ret := make(Attributes)
+	for k, v := range base {
+		v2, exists := a[k]
+		if exists && !attrValueEqual(v2, v) {
+			ret[k] = v
+		}
+	}
+	for k, v := range a {
+		if _, exists := base[k]; !exists && v != nil {
+			ret[k] = nil
+		}
+	}
+	if len(ret) == 0 {
+		return nil
+	}
+	return ret
+}
+
+// MarshalJSON encodes the attributes as a JSON object, keys set to nil are
+// kept as null
+func (a Attributes) MarshalJSON() ([]byte, error) {
+	if a == nil {
+		return []byte("null"), nil
+	}
+	return json.Marshal(map[string]interface{}(a))
+}
+
+// UnmarshalJSON decodes a JSON object, null values are kept as nil so we can
+// tell them from missing keys
+func (a *Attributes) UnmarshalJSON(data []byte) error {
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	// ops decoded from the same document share a handful of formats
+	*a = Intern(m)
+	return nil
+}
+
+// sameMap tells you if a and b are the same map, not just equal ones
+func sameMap(a, b Attributes) bool {
+	return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+}
+
+func (a Attributes) hasNil() bool {
+	for _, v := range a {
+		if v == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func attrValueEqual(a, b interface{}) bool {
+	switch x := a.(type) {
+	case nil:
+		return b == nil
+	case string:
+		y, ok := b.(string)
+		return ok && x == y
+	case bool:
+		y, ok := b.(bool)
+		return ok && x == y
+	case float64:
+		y, ok := b.(float64)
+		return ok && x == y
+	case int:
+		y, ok := b.(int)
+		return ok && x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
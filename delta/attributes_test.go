@@ -0,0 +1,133 @@
+package delta
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestAttributesAccessors(t *testing.T) {
+	var attrs Attributes
+	if err := json.Unmarshal([]byte(`{"bold":true,"header":2,"size":1.5,"color":"red","link":null}`), &attrs); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := attrs.Bool("bold"); !ok || !v {
+		t.Errorf("expected bold to be true")
+	}
+	if _, ok := attrs.Bool("color"); ok {
+		t.Errorf("color is not a bool")
+	}
+	if v, ok := attrs.Int("header"); !ok || v != 2 {
+		t.Errorf("expected header 2, got %v %v", v, ok)
+	}
+	if _, ok := attrs.Int("size"); ok {
+		t.Errorf("1.5 is not an int")
+	}
+	if v, ok := attrs.Float("size"); !ok || v != 1.5 {
+		t.Errorf("expected size 1.5, got %v %v", v, ok)
+	}
+	if v, ok := attrs.String("color"); !ok || v != "red" {
+		t.Errorf("expected color red, got %v %v", v, ok)
+	}
+	if !attrs.IsNull("link") || !attrs.Has("link") {
+		t.Errorf("expected link to be set to null")
+	}
+	if attrs.IsNull("italic") || attrs.Has("italic") {
+		t.Errorf("italic is not set")
+	}
+	if keys := attrs.Keys(); !reflect.DeepEqual(keys, []string{"bold", "color", "header", "link", "size"}) {
+		t.Errorf("unexpected keys %v", keys)
+	}
+	if v, ok := (Attributes{"indent": 3}).Int("indent"); !ok || v != 3 {
+		t.Errorf("expected indent 3, got %v %v", v, ok)
+	}
+}
+
+func TestAttributesCopyOnWrite(t *testing.T) {
+	base := Attributes{"bold": true}
+	with := base.With("italic", true)
+	if base.Has("italic") || !with.Has("italic") || !with.Has("bold") {
+		t.Errorf("With modified its receiver: %v %v", base, with)
+	}
+	without := with.Without("bold")
+	if !with.Has("bold") || without.Has("bold") {
+		t.Errorf("Without modified its receiver: %v %v", with, without)
+	}
+	if base.Without("bold") != nil {
+		t.Errorf("removing the last key should give nil attributes")
+	}
+	m := map[string]interface{}{"bold": true}
+	clone := NewAttributes(m)
+	m["bold"] = false
+	if v, _ := clone.Bool("bold"); !v {
+		t.Errorf("NewAttributes should copy the map")
+	}
+	if NewAttributes(map[string]interface{}{}) != nil {
+		t.Errorf("NewAttributes of an empty map should be nil")
+	}
+}
+
+func TestAttributesComposeCopies(t *testing.T) {
+	a := Attributes{"bold": true}
+	for _, b := range []Attributes{nil, {}, {"italic": true}} {
+		ret := a.Compose(b, false)
+		ret["color"] = "red"
+		if a.Has("color") {
+			t.Errorf("composing with %v modified the receiver: %v", b, a)
+		}
+	}
+	if ret := Attributes(nil).Compose(nil, false); ret != nil {
+		t.Errorf("expected nil, got %v", ret)
+	}
+}
+
+func TestAttributesJSON(t *testing.T) {
+	d := New(nil).Retain(1, Attributes{"bold": nil, "color": "red"})
+	b, err := json.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"ops":[{"retain":1,"attributes":{"bold":null,"color":"red"}}]}` {
+		t.Errorf("unexpected json %s", b)
+	}
+	got, err := FromJSON(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Ops[0].Attributes.IsNull("bold") {
+		t.Errorf("null should survive the round trip, got %v", got.Ops[0].Attributes)
+	}
+	var attrs Attributes
+	if err := json.Unmarshal([]byte(`null`), &attrs); err != nil || attrs != nil {
+		t.Errorf("expected nil attributes, got %v, %v", attrs, err)
+	}
+	if err := json.Unmarshal([]byte(`[1]`), &attrs); err == nil {
+		t.Errorf("expected an error decoding a list")
+	}
+}
+
+func TestAttributesMapCompatibility(t *testing.T) {
+	m := map[string]interface{}{"bold": true}
+	d := New(nil).Insert("a", m).Retain(1, map[string]interface{}{"italic": true})
+	var plain map[string]interface{} = d.Ops[0].Attributes
+	if plain["bold"] != true {
+		t.Errorf("expected to read attributes as a map, got %v", plain)
+	}
+	if composed := AttrCompose(m, d.Ops[1].Attributes, false); len(composed) != 2 {
+		t.Errorf("unexpected compose %v", composed)
+	}
+}
+
+func TestAttributesUncomparableValues(t *testing.T) {
+	a := Attributes{"list": []interface{}{"a"}}
+	b := Attributes{"list": []interface{}{"b"}}
+	if diff := a.Diff(b); !reflect.DeepEqual(diff, b) {
+		t.Errorf("unexpected diff %v", diff)
+	}
+	if diff := a.Diff(a); diff != nil {
+		t.Errorf("expected no diff, got %v", diff)
+	}
+	if inv := b.Invert(a); !reflect.DeepEqual(inv, a) {
+		t.Errorf("unexpected invert %v", inv)
+	}
+}
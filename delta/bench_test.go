@@ -0,0 +1,139 @@
+package delta
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+// benchDocument builds a document that looks like the ones we store: short
+// paragraphs with a few formatted words, some headers and list items
+func benchDocument(paragraphs int) *Delta {
+	d := New(nil)
+	for i := 0; i < paragraphs; i++ {
+		switch i % 10 {
+		case 0:
+			d.Insert("Section title", nil).Insert("\n", map[string]interface{}{"header": 2.0})
+		case 3, 4:
+			d.Insert("A list item with a ", nil).
+				Insert("link", map[string]interface{}{"link": "https://quilljs.com"}).
+				Insert("\n", map[string]interface{}{"list": "bullet"})
+		default:
+			d.Insert(strings.Repeat("Lorem ipsum dolor sit amet ", 4), nil).
+				Insert("bold words", map[string]interface{}{"bold": true}).
+				Insert(" and ", nil).
+				Insert("colored", map[string]interface{}{"color": "#e60000", "italic": true}).
+				Insert(" text.\n", nil)
+		}
+	}
+	return d
+}
+
+// benchFormatChange formats every other word of the document
+func benchFormatChange(length int) *Delta {
+	d := New(nil)
+	for pos := 0; pos+12 < length; pos += 12 {
+		d.Retain(6, nil).Retain(6, map[string]interface{}{"bold": true, "color": nil})
+	}
+	return d
+}
+
+func BenchmarkPushPaste(b *testing.B) {
+	text := []rune(strings.Repeat("Lorem ipsum dolor sit amet ", 400))
+	bold := map[string]interface{}{"bold": true}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		d := New(nil)
+		for j := range text {
+			if j%100 < 50 {
+				d.Push(Op{Insert: text[j : j+1], Attributes: bold})
+			} else {
+				d.Push(Op{Insert: text[j : j+1], Attributes: map[string]interface{}{"bold": true}})
+			}
+		}
+	}
+}
+
+func BenchmarkPushRetain(b *testing.B) {
+	attrs := map[string]interface{}{"bold": true, "color": "#e60000", "font": "serif"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		d := New(nil)
+		for j := 0; j < 1000; j++ {
+			d.Retain(3, map[string]interface{}{"bold": true, "color": "#e60000", "font": "serif"}).Retain(2, attrs)
+		}
+	}
+}
+
+// BenchmarkPushDecoded pushes ops read from JSON, where the ops with the same
+// formats share interned attributes, so comparing them is a pointer check
+func BenchmarkPushDecoded(b *testing.B) {
+	paste := New(nil)
+	attrs := map[string]interface{}{"bold": true, "color": "#e60000", "font": "serif", "size": "large"}
+	for _, r := range strings.Repeat("Lorem ipsum dolor sit amet ", 400) {
+		paste.Ops = append(paste.Ops, Op{Insert: []rune{r}, Attributes: attrs})
+	}
+	data, err := json.Marshal(paste)
+	if err != nil {
+		b.Fatal(err)
+	}
+	var decoded Delta
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d := New(nil)
+		for _, op := range decoded.Ops {
+			d.Push(op)
+		}
+	}
+}
+
+func BenchmarkComposeFormat(b *testing.B) {
+	doc := benchDocument(500)
+	change := benchFormatChange(doc.Length())
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		doc.Compose(*change)
+	}
+}
+
+func BenchmarkComposeTyping(b *testing.B) {
+	doc := benchDocument(500)
+	change := New(nil).Retain(doc.Length()/2, nil).Insert("a", nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		doc.Compose(*change)
+	}
+}
+
+func BenchmarkTransform(b *testing.B) {
+	doc := benchDocument(500)
+	a := benchFormatChange(doc.Length())
+	other := New(nil)
+	for pos := 0; pos+20 < doc.Length(); pos += 20 {
+		other.Retain(10, map[string]interface{}{"italic": true}).Delete(2).Insert("xy", nil).Retain(8, nil)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		a.Transform(*other, true)
+	}
+}
+
+func BenchmarkIteratorNext(b *testing.B) {
+	doc := benchDocument(500)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		iter := NewIterator(doc.Ops)
+		for iter.HasNext() {
+			iter.Next(math.MaxInt64)
+		}
+	}
+}
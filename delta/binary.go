@@ -0,0 +1,498 @@
+package delta
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// The binary encoding is a compact alternative to JSON, meant for storing op
+// logs and for sending high frequency changes. A stream starts with a small
+// header followed by any number of deltas:
+//
+//	stream := "QDB" version:byte delta*
+//	delta  := count:uvarint op*
+//	op     := tag:byte [attributes] body
+//
+// The low bits of tag tell the op type, the 0x80 bit is set when the op has
+// attributes. Deletes never have attributes, encoding one that does fails
+// rather than losing them. Lengths and counts are unsigned varints, text is
+// UTF-8 and embed values and nested attribute values are stored as JSON.
+// Attribute and embed keys are interned: the first time a key shows up it is
+// written as 0 followed by the string, after that as its position in the
+// table plus one. The table lives as long as the stream, so an op log only
+// pays for "bold" once.
+//
+// Numbers are always decoded as float64, same as encoding/json does, so a
+// Delta decoded from its binary form is identical to the one FromJSON would
+// return for its JSON form.
+
+const (
+	binaryVersion = 1
+
+	tagInsert      = 1
+	tagInsertEmbed = 2
+	tagRetain      = 3
+	tagDelete      = 4
+	tagAttributes  = 0x80
+
+	valueNull   = 0
+	valueFalse  = 1
+	valueTrue   = 2
+	valueString = 3
+	valueInt    = 4
+	valueFloat  = 5
+	valueJSON   = 6
+
+	// maxBinaryChunk limits the size of a single string or JSON value we are
+	// willing to allocate while decoding
+	maxBinaryChunk = 64 << 20
+)
+
+var binaryMagic = []byte("QDB")
+
+// ErrInvalidBinary is returned when decoding malformed binary data
+var ErrInvalidBinary = errors.New("invalid binary delta")
+
+// MarshalBinary encodes the delta using the compact binary format
+func (d *Delta) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(d); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a delta encoded with MarshalBinary
+func (d *Delta) UnmarshalBinary(data []byte) error {
+	dec := NewDecoder(bytes.NewReader(data))
+	ret, err := dec.Decode()
+	if err == io.EOF {
+		return ErrInvalidBinary
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := dec.Decode(); err != io.EOF {
+		return ErrInvalidBinary
+	}
+	d.Ops = ret.Ops
+	return nil
+}
+
+// MarshalBinary encodes a single op using the compact binary format
+func (o *Op) MarshalBinary() ([]byte, error) {
+	return New([]Op{*o}).MarshalBinary()
+}
+
+// UnmarshalBinary decodes an op encoded with MarshalBinary
+func (o *Op) UnmarshalBinary(data []byte) error {
+	var d Delta
+	if err := d.UnmarshalBinary(data); err != nil {
+		return err
+	}
+	if len(d.Ops) != 1 {
+		return ErrInvalidBinary
+	}
+	*o = d.Ops[0]
+	return nil
+}
+
+// Encoder writes a stream of binary encoded deltas, usually an op log
+type Encoder struct {
+	w           io.Writer
+	keys        map[string]int
+	staged      map[string]int
+	wroteHeader bool
+	buf         []byte
+}
+
+// NewEncoder returns an Encoder writing to w
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{
+		w:      w,
+		keys:   make(map[string]int),
+		staged: make(map[string]int),
+	}
+}
+
+// Encode writes d to the stream. The keys d adds to the table are only kept
+// once it was written, so an error doesn't leave the table out of sync with
+// what the decoder has seen
+func (e *Encoder) Encode(d *Delta) error {
+	for k := range e.staged {
+		delete(e.staged, k)
+	}
+	buf := e.buf[:0]
+	if !e.wroteHeader {
+		buf = append(buf, binaryMagic...)
+		buf = append(buf, binaryVersion)
+	}
+	buf = appendUvarint(buf, uint64(len(d.Ops)))
+	var err error
+	for i := range d.Ops {
+		if buf, err = e.appendOp(buf, &d.Ops[i]); err != nil {
+			return err
+		}
+	}
+	e.buf = buf
+	if _, err := e.w.Write(buf); err != nil {
+		return err
+	}
+	e.wroteHeader = true
+	for k, idx := range e.staged {
+		e.keys[k] = idx
+	}
+	return nil
+}
+
+func (e *Encoder) appendOp(buf []byte, op *Op) ([]byte, error) {
+	var tag byte
+	switch {
+	case op.Delete != nil:
+		if len(op.Attributes) > 0 {
+			return nil, fmt.Errorf("cannot encode delete op with attributes %v", op.Attributes)
+		}
+		tag = tagDelete
+	case op.Retain != nil:
+		tag = tagRetain
+	case op.Insert != nil:
+		tag = tagInsert
+	case op.InsertEmbed != nil:
+		tag = tagInsertEmbed
+	default:
+		return nil, fmt.Errorf("cannot encode empty op %+v", op)
+	}
+	if len(op.Attributes) > 0 {
+		tag |= tagAttributes
+	}
+	buf = append(buf, tag)
+	if tag&tagAttributes != 0 {
+		keys := make([]string, 0, len(op.Attributes))
+		for k := range op.Attributes {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		buf = appendUvarint(buf, uint64(len(keys)))
+		var err error
+		for _, k := range keys {
+			buf = e.appendKey(buf, k)
+			if buf, err = appendValue(buf, op.Attributes[k]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	switch tag &^ tagAttributes {
+	case tagDelete:
+		buf = appendLength(buf, *op.Delete)
+	case tagRetain:
+		buf = appendLength(buf, *op.Retain)
+	case tagInsert:
+		s := string(op.Insert)
+		buf = appendUvarint(buf, uint64(len(s)))
+		buf = append(buf, s...)
+	case tagInsertEmbed:
+		buf = e.appendKey(buf, op.InsertEmbed.Key)
+		b, err := json.Marshal(op.InsertEmbed.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf = appendUvarint(buf, uint64(len(b)))
+		buf = append(buf, b...)
+	}
+	return buf, nil
+}
+
+func appendLength(buf []byte, n int) []byte {
+	if n < 0 {
+		n = 0
+	}
+	return appendUvarint(buf, uint64(n))
+}
+
+func (e *Encoder) appendKey(buf []byte, key string) []byte {
+	if idx, found := e.keys[key]; found {
+		return appendUvarint(buf, uint64(idx+1))
+	}
+	if idx, found := e.staged[key]; found {
+		return appendUvarint(buf, uint64(idx+1))
+	}
+	e.staged[key] = len(e.keys) + len(e.staged)
+	buf = append(buf, 0)
+	buf = appendUvarint(buf, uint64(len(key)))
+	return append(buf, key...)
+}
+
+func appendValue(buf []byte, v interface{}) ([]byte, error) {
+	switch x := v.(type) {
+	case nil:
+		return append(buf, valueNull), nil
+	case bool:
+		if x {
+			return append(buf, valueTrue), nil
+		}
+		return append(buf, valueFalse), nil
+	case string:
+		buf = append(buf, valueString)
+		buf = appendUvarint(buf, uint64(len(x)))
+		return append(buf, x...), nil
+	case int:
+		return appendNumber(buf, float64(x)), nil
+	case int64:
+		return appendNumber(buf, float64(x)), nil
+	case float64:
+		return appendNumber(buf, x), nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	buf = append(buf, valueJSON)
+	buf = appendUvarint(buf, uint64(len(b)))
+	return append(buf, b...), nil
+}
+
+// appendNumber stores whole numbers as zigzag varints, which is what most
+// attributes (header, indent, width) are
+func appendNumber(buf []byte, f float64) []byte {
+	if f == math.Trunc(f) && math.Abs(f) < 1<<53 && !(f == 0 && math.Signbit(f)) {
+		buf = append(buf, valueInt)
+		return appendVarint(buf, int64(f))
+	}
+	buf = append(buf, valueFloat)
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], math.Float64bits(f))
+	return append(buf, b[:]...)
+}
+
+func appendUvarint(buf []byte, n uint64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	return append(buf, b[:binary.PutUvarint(b[:], n)]...)
+}
+
+func appendVarint(buf []byte, n int64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	return append(buf, b[:binary.PutVarint(b[:], n)]...)
+}
+
+// Decoder reads a stream of binary encoded deltas
+type Decoder struct {
+	r          *bufio.Reader
+	keys       []string
+	readHeader bool
+}
+
+// NewDecoder returns a Decoder reading from r
+func NewDecoder(r io.Reader) *Decoder {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &Decoder{r: br}
+}
+
+// Decode reads the next delta from the stream. It returns io.EOF when there
+// are no more deltas
+func (dec *Decoder) Decode() (*Delta, error) {
+	if !dec.readHeader {
+		header := make([]byte, len(binaryMagic)+1)
+		if _, err := io.ReadFull(dec.r, header); err != nil {
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+			return nil, ErrInvalidBinary
+		}
+		if !bytes.Equal(header[:len(binaryMagic)], binaryMagic) {
+			return nil, ErrInvalidBinary
+		}
+		if header[len(binaryMagic)] != binaryVersion {
+			return nil, fmt.Errorf("unsupported binary delta version %d", header[len(binaryMagic)])
+		}
+		dec.readHeader = true
+	}
+	count, err := binary.ReadUvarint(dec.r)
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, ErrInvalidBinary
+	}
+	ret := New(nil)
+	if count > 0 {
+		capacity := count
+		if capacity > 1024 {
+			capacity = 1024
+		}
+		ret.Ops = make([]Op, 0, capacity)
+	}
+	for i := uint64(0); i < count; i++ {
+		op, err := dec.readOp()
+		if err != nil {
+			return nil, err
+		}
+		ret.Ops = append(ret.Ops, op)
+	}
+	return ret, nil
+}
+
+func (dec *Decoder) readOp() (Op, error) {
+	var op Op
+	tag, err := dec.r.ReadByte()
+	if err != nil {
+		return op, ErrInvalidBinary
+	}
+	if tag&tagAttributes != 0 {
+		n, err := dec.readUvarint()
+		if err != nil {
+			return op, err
+		}
+		op.Attributes = make(Attributes)
+		for i := uint64(0); i < n; i++ {
+			k, err := dec.readKey()
+			if err != nil {
+				return op, err
+			}
+			v, err := dec.readValue()
+			if err != nil {
+				return op, err
+			}
+			op.Attributes[k] = v
+		}
+		op.Attributes = Intern(op.Attributes)
+	}
+	switch tag &^ tagAttributes {
+	case tagDelete, tagRetain:
+		n, err := dec.readUvarint()
+		if err != nil {
+			return op, err
+		}
+		if n > math.MaxInt32 {
+			return op, ErrInvalidBinary
+		}
+		length := int(n)
+		if tag&^tagAttributes == tagDelete {
+			if tag&tagAttributes != 0 {
+				// the encoder never writes attributes on a delete
+				return op, ErrInvalidBinary
+			}
+			op.Delete = &length
+		} else {
+			op.Retain = &length
+		}
+	case tagInsert:
+		b, err := dec.readBytes()
+		if err != nil {
+			return op, err
+		}
+		op.Insert = []rune(string(b))
+	case tagInsertEmbed:
+		k, err := dec.readKey()
+		if err != nil {
+			return op, err
+		}
+		b, err := dec.readBytes()
+		if err != nil {
+			return op, err
+		}
+		var v interface{}
+		if err := json.Unmarshal(b, &v); err != nil {
+			return op, ErrInvalidBinary
+		}
+		op.InsertEmbed = &Embed{Key: k, Value: v}
+	default:
+		return op, ErrInvalidBinary
+	}
+	return op, nil
+}
+
+func (dec *Decoder) readUvarint() (uint64, error) {
+	n, err := binary.ReadUvarint(dec.r)
+	if err != nil {
+		return 0, ErrInvalidBinary
+	}
+	return n, nil
+}
+
+func (dec *Decoder) readBytes() ([]byte, error) {
+	n, err := dec.readUvarint()
+	if err != nil {
+		return nil, err
+	}
+	if n > maxBinaryChunk {
+		return nil, ErrInvalidBinary
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(dec.r, b); err != nil {
+		return nil, ErrInvalidBinary
+	}
+	return b, nil
+}
+
+func (dec *Decoder) readKey() (string, error) {
+	idx, err := dec.readUvarint()
+	if err != nil {
+		return "", err
+	}
+	if idx == 0 {
+		b, err := dec.readBytes()
+		if err != nil {
+			return "", err
+		}
+		dec.keys = append(dec.keys, string(b))
+		return string(b), nil
+	}
+	if idx > uint64(len(dec.keys)) {
+		return "", ErrInvalidBinary
+	}
+	return dec.keys[idx-1], nil
+}
+
+func (dec *Decoder) readValue() (interface{}, error) {
+	t, err := dec.r.ReadByte()
+	if err != nil {
+		return nil, ErrInvalidBinary
+	}
+	switch t {
+	case valueNull:
+		return nil, nil
+	case valueFalse:
+		return false, nil
+	case valueTrue:
+		return true, nil
+	case valueString:
+		b, err := dec.readBytes()
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	case valueInt:
+		n, err := binary.ReadVarint(dec.r)
+		if err != nil {
+			return nil, ErrInvalidBinary
+		}
+		return float64(n), nil
+	case valueFloat:
+		var b [8]byte
+		if _, err := io.ReadFull(dec.r, b[:]); err != nil {
+			return nil, ErrInvalidBinary
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b[:])), nil
+	case valueJSON:
+		b, err := dec.readBytes()
+		if err != nil {
+			return nil, err
+		}
+		var v interface{}
+		if err := json.Unmarshal(b, &v); err != nil {
+			return nil, ErrInvalidBinary
+		}
+		return v, nil
+	}
+	return nil, ErrInvalidBinary
+}
@@ -0,0 +1,221 @@
+package delta
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func binaryFixtures() []*Delta {
+	return []*Delta{
+		New(nil),
+		New(nil).Insert("Hello", map[string]interface{}{"bold": true, "color": "#ff0000"}).
+			Insert(" World\n", nil).
+			Insert("Title\n", map[string]interface{}{"header": 1}),
+		New(nil).Retain(3, nil).Delete(2).Insert("X", map[string]interface{}{"italic": nil}),
+		New(nil).Retain(10, map[string]interface{}{"bold": false, "size": 1.5, "indent": -2}),
+		New(nil).InsertEmbed(Embed{Key: "image", Value: "https://quilljs.com/logo.png"},
+			map[string]interface{}{"alt": "logo", "width": 120}).
+			InsertEmbed(Embed{Key: "video", Value: map[string]interface{}{"src": "a.mp4", "loop": true}}, nil).
+			Insert("日本語 😀\n", map[string]interface{}{"list": []interface{}{"a", 1.0}}),
+	}
+}
+
+func jsonRoundTrip(t *testing.T, d *Delta) *Delta {
+	b, err := json.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret, err := FromJSON(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ret
+}
+
+func TestBinaryRoundTripMatchesJSON(t *testing.T) {
+	for i, d := range binaryFixtures() {
+		b, err := d.MarshalBinary()
+		if err != nil {
+			t.Fatalf("%d: failed to marshal: %v", i, err)
+		}
+		var got Delta
+		if err := got.UnmarshalBinary(b); err != nil {
+			t.Fatalf("%d: failed to unmarshal: %v", i, err)
+		}
+		expected := jsonRoundTrip(t, d)
+		if len(got.Ops) == 0 && len(expected.Ops) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got.Ops, expected.Ops) {
+			t.Errorf("%d: binary and json differ\nbinary: %+v\njson:   %+v\n", i, got.Ops, expected.Ops)
+		}
+		// and the json of both forms is byte for byte the same
+		j1, _ := json.Marshal(&got)
+		j2, _ := json.Marshal(expected)
+		if !bytes.Equal(j1, j2) {
+			t.Errorf("%d: json differs\n%s\n%s\n", i, j1, j2)
+		}
+	}
+}
+
+func TestBinaryDeleteAttributes(t *testing.T) {
+	two := 2
+	// an empty map is left out of the JSON too
+	d := New([]Op{{Retain: &two}, {Delete: &two, Attributes: Attributes{}}})
+	b, err := d.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Delta
+	if err := got.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if expected := jsonRoundTrip(t, d); !reflect.DeepEqual(got.Ops, expected.Ops) {
+		t.Errorf("binary and json differ\nbinary: %+v\njson:   %+v\n", got.Ops, expected.Ops)
+	}
+
+	// JSON keeps attributes on a delete, the binary form can't
+	d = New([]Op{{Delete: &two, Attributes: Attributes{"bold": true}}})
+	if expected := jsonRoundTrip(t, d); !reflect.DeepEqual(expected.Ops, d.Ops) {
+		t.Fatalf("expected json to keep the attributes, got %+v", expected.Ops)
+	}
+	if _, err := d.MarshalBinary(); err == nil {
+		t.Errorf("expected an error encoding attributes on a delete")
+	}
+}
+
+func TestBinaryIsSmallerThanJSON(t *testing.T) {
+	d := New(nil)
+	for i := 0; i < 50; i++ {
+		d.Insert("word ", map[string]interface{}{"bold": true}).Insert("other ", map[string]interface{}{"italic": true})
+	}
+	b, _ := d.MarshalBinary()
+	j, _ := json.Marshal(d)
+	if len(b) >= len(j)/2 {
+		t.Errorf("expected binary (%d bytes) to be much smaller than json (%d bytes)", len(b), len(j))
+	}
+}
+
+func TestBinaryOp(t *testing.T) {
+	op := Op{Insert: []rune("abc"), Attributes: map[string]interface{}{"bold": true}}
+	b, err := op.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Op
+	if err := got.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, op) {
+		t.Errorf("expected %+v but got %+v\n", op, got)
+	}
+}
+
+func TestBinaryStream(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	fixtures := binaryFixtures()
+	for _, d := range fixtures {
+		if err := enc.Encode(d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dec := NewDecoder(&buf)
+	for i, d := range fixtures {
+		got, err := dec.Decode()
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		if expected := jsonRoundTrip(t, d); len(expected.Ops) > 0 && !reflect.DeepEqual(got.Ops, expected.Ops) {
+			t.Errorf("%d: expected %+v but got %+v\n", i, expected.Ops, got.Ops)
+		}
+	}
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Errorf("expected io.EOF at the end of the stream, got %v", err)
+	}
+}
+
+func TestBinaryStreamInternsKeys(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	d := New(nil).Retain(1, map[string]interface{}{"background": "red"})
+	enc.Encode(d)
+	first := buf.Len()
+	enc.Encode(d)
+	if second := buf.Len() - first; second >= first-len("background") {
+		t.Errorf("expected the second delta to reuse the key, got %d and %d bytes", first, second)
+	}
+}
+
+// failingWriter fails the writes in fail
+type failingWriter struct {
+	w    io.Writer
+	n    int
+	fail map[int]bool
+}
+
+func (f *failingWriter) Write(p []byte) (int, error) {
+	f.n++
+	if f.fail[f.n] {
+		// part of the data got through
+		f.w.Write(p[:len(p)/2])
+		return len(p) / 2, io.ErrShortWrite
+	}
+	return f.w.Write(p)
+}
+
+func TestBinaryStreamFailedEncode(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&failingWriter{w: &buf, fail: map[int]bool{2: true}})
+	deltas := []*Delta{
+		New(nil).Insert("a", map[string]interface{}{"bold": true}),
+		New(nil).Insert("b", map[string]interface{}{"italic": true}).InsertEmbed(Embed{Key: "image", Value: "x.png"}, nil),
+		New(nil).Insert("c", map[string]interface{}{"italic": true}).InsertEmbed(Embed{Key: "image", Value: "y.png"}, nil),
+	}
+	for i, d := range deltas {
+		written := buf.Len()
+		err := enc.Encode(d)
+		if i != 1 && err != nil {
+			t.Fatal(err)
+		}
+		if i == 1 {
+			if err == nil {
+				t.Fatal("expected the second write to fail")
+			}
+			// the log keeps the deltas that were written
+			buf.Truncate(written)
+		}
+	}
+	dec := NewDecoder(&buf)
+	for _, i := range []int{0, 2} {
+		got, err := dec.Decode()
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		if expected := jsonRoundTrip(t, deltas[i]); !reflect.DeepEqual(got.Ops, expected.Ops) {
+			t.Errorf("%d: expected %+v but got %+v\n", i, expected.Ops, got.Ops)
+		}
+	}
+}
+
+func TestBinaryInvalid(t *testing.T) {
+	valid, _ := New(nil).Insert("abc", map[string]interface{}{"bold": true}).MarshalBinary()
+	inputs := [][]byte{
+		nil,
+		[]byte("XYZ\x01\x00"),
+		valid[:len(valid)-1],
+		append(append([]byte(nil), valid...), 0x01),
+		[]byte("QDB\x01\x01\x09"),
+		[]byte("QDB\x01\x01\x81\x01\x05"),
+		[]byte("QDB\x01\x01\x84\x00\x02"),
+	}
+	for i, in := range inputs {
+		var d Delta
+		if err := d.UnmarshalBinary(in); err == nil {
+			t.Errorf("%d: expected an error decoding %q", i, in)
+		}
+	}
+}
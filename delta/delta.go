@@ -6,7 +6,6 @@ import (
 	"encoding/json"
 	"errors"
 	"math"
-	"reflect"
 )
 
 // Delta is the main type representing a QuillJs delta
@@ -23,11 +22,11 @@ type Embed struct {
 
 // Op is the smallest "operation"
 type Op struct {
-	Insert      []rune                 `json:"insert,omitempty"`
-	InsertEmbed *Embed                 `json:"-"`
-	Retain      *int                   `json:"retain,omitempty"`
-	Attributes  map[string]interface{} `json:"attributes,omitempty"`
-	Delete      *int                   `json:"delete,omitempty"`
+	Insert      []rune     `json:"insert,omitempty"`
+	InsertEmbed *Embed     `json:"-"`
+	Retain      *int       `json:"retain,omitempty"`
+	Attributes  Attributes `json:"attributes,omitempty"`
+	Delete      *int       `json:"delete,omitempty"`
 }
 
 // IsNil tells you if the current Op is a nil operation
@@ -131,7 +130,7 @@ func (o *Op) MarshalJSON() ([]byte, error) {
 
 // Insert takes a string and a map of attributes and adds them to the Delta d
 // If the string is empty, we return the original delta
-func (d *Delta) Insert(text string, attrs map[string]interface{}) *Delta {
+func (d *Delta) Insert(text string, attrs Attributes) *Delta {
 	if len([]rune(text)) == 0 {
 		return d
 	}
@@ -145,7 +144,7 @@ func (d *Delta) Insert(text string, attrs map[string]interface{}) *Delta {
 
 // InsertEmbed takes a map of embeds and a map of attributes, adds them to the Delta. This
 // can be used to insert images or URLs to the Delta
-func (d *Delta) InsertEmbed(embed Embed, attrs map[string]interface{}) *Delta {
+func (d *Delta) InsertEmbed(embed Embed, attrs Attributes) *Delta {
 	if len(embed.Key) == 0 || embed.Value == nil {
 		return d
 	}
@@ -168,7 +167,7 @@ func (d *Delta) Delete(n int) *Delta {
 }
 
 // Retain keeps n characters and applies the attrs if present
-func (d *Delta) Retain(n int, attrs map[string]interface{}) *Delta {
+func (d *Delta) Retain(n int, attrs Attributes) *Delta {
 	if n <= 0 {
 		return d
 	}
@@ -183,8 +182,14 @@ func (d *Delta) Retain(n int, attrs map[string]interface{}) *Delta {
 	return d
 }
 
-// Push adds the newOp Operation to the delta, but reorganizes the ops based on certain rules
+// Push adds the newOp Operation to the delta, but reorganizes the ops based on certain rules.
+// Inserts are stored with their capacity capped, so merging the next insert
+// never writes into memory that belongs to the caller, and merged inserts
+// grow in place like any other slice
 func (d *Delta) Push(newOp Op) *Delta {
+	if newOp.Insert != nil {
+		newOp.Insert = newOp.Insert[:len(newOp.Insert):len(newOp.Insert)]
+	}
 	idx := len(d.Ops)
 	var lastOp *Op
 	if idx > 0 {
@@ -214,7 +219,7 @@ func (d *Delta) Push(newOp Op) *Delta {
 			}
 			lastOp = &d.Ops[idx-1]
 		}
-		if reflect.DeepEqual(newOp.Attributes, lastOp.Attributes) {
+		if newOp.Attributes.Equal(lastOp.Attributes) {
 			if newOp.Insert != nil && lastOp.Insert != nil {
 				mergedText := append(lastOp.Insert, newOp.Insert...)
 				d.Ops[idx-1] = Op{
@@ -265,6 +270,8 @@ func (d *Delta) Chop() *Delta {
 }
 
 // Compose returns a Delta that is equivalent to applying the operations of own Delta, followed by another Delta.
+// The result has its own retain and delete lengths, but can share insert text
+// and attribute maps with d and other
 func (d *Delta) Compose(other Delta) *Delta {
 	thisIter := OpsIterator(d.Ops)
 	otherIter := OpsIterator(other.Ops)
@@ -275,7 +282,7 @@ func (d *Delta) Compose(other Delta) *Delta {
 		} else if thisIter.PeekType() == "delete" {
 			delta.Push(thisIter.Next(math.MaxInt64))
 		} else {
-			length := int(math.Min(float64(thisIter.PeekLength()), float64(otherIter.PeekLength())))
+			length := minInt(thisIter.PeekLength(), otherIter.PeekLength())
 			thisOp := thisIter.Next(length)
 			otherOp := otherIter.Next(length)
 			if otherOp.Retain != nil {
@@ -283,11 +290,12 @@ func (d *Delta) Compose(other Delta) *Delta {
 				if thisOp.Retain != nil {
 					newOp.Retain = &length
 				} else {
-					newOp.Insert = append([]rune(nil), thisOp.Insert...)
+					// no need to copy the text, Push never modifies it
+					newOp.Insert = thisOp.Insert
 					newOp.InsertEmbed = thisOp.InsertEmbed
 				}
 				// Preserve null when composing with a retain, otherwise remove it for inserts
-				attributes := AttrCompose(thisOp.Attributes, otherOp.Attributes, thisOp.Retain != nil)
+				attributes := thisOp.Attributes.compose(otherOp.Attributes, thisOp.Retain != nil)
 				if attributes != nil {
 					newOp.Attributes = attributes
 				}
@@ -321,7 +329,7 @@ func (d *Delta) TransformPosition(index int, priority bool) int {
 		nextType := thisIter.PeekType()
 		thisIter.Next(math.MaxInt64)
 		if nextType == "delete" {
-			index -= int(math.Min(float64(length), float64(index-offset)))
+			index -= minInt(length, index-offset)
 			continue
 		} else if nextType == "insert" && (offset < index || !priority) {
 			index += length
@@ -331,7 +339,8 @@ func (d *Delta) TransformPosition(index int, priority bool) int {
 	return index
 }
 
-// Transform given Delta against own operations
+// Transform given Delta against own operations. Like Compose, the result
+// shares insert text and attribute maps with the deltas, not lengths
 func (d *Delta) Transform(other Delta, priority bool) *Delta {
 	thisIter := OpsIterator(d.Ops)
 	otherIter := OpsIterator(other.Ops)
@@ -342,7 +351,7 @@ func (d *Delta) Transform(other Delta, priority bool) *Delta {
 		} else if otherIter.PeekType() == "insert" {
 			delta.Push(otherIter.Next(math.MaxInt64))
 		} else {
-			length := int(math.Min(float64(thisIter.PeekLength()), float64(otherIter.PeekLength())))
+			length := minInt(thisIter.PeekLength(), otherIter.PeekLength())
 			thisOp := thisIter.Next(length)
 			otherOp := otherIter.Next(length)
 			if thisOp.Delete != nil {
@@ -352,7 +361,7 @@ func (d *Delta) Transform(other Delta, priority bool) *Delta {
 				delta.Push(otherOp)
 			} else {
 				// We retain either their retain or insert
-				delta.Retain(length, AttrTransform(thisOp.Attributes, otherOp.Attributes, priority))
+				delta.Retain(length, thisOp.Attributes.Transform(otherOp.Attributes, priority))
 			}
 		}
 	}
@@ -404,7 +413,7 @@ func (d *Delta) Invert(base *Delta) *Delta {
 			length := *op.Retain
 			slice := base.Slice(baseIndex, baseIndex+length)
 			for _, baseOp := range slice.Ops {
-				inverted.Retain(baseOp.Length(), AttrInvert(op.Attributes, baseOp.Attributes))
+				inverted.Retain(baseOp.Length(), op.Attributes.Invert(baseOp.Attributes))
 			}
 			baseIndex += length
 		} else if op.Delete != nil {
@@ -111,7 +111,7 @@ func TestInsertEmbedAttributes(t *testing.T) {
 	if !reflect.DeepEqual(*n.Ops[0].InsertEmbed, Embed{Key: "image", Value: "thisisanimage"}) {
 		t.Errorf("failed to create Delta with image insert, got: %+v\n", n.Ops[0].InsertEmbed)
 	}
-	if !reflect.DeepEqual(n.Ops[0].Attributes, map[string]interface{}{"color": "red"}) {
+	if !reflect.DeepEqual(n.Ops[0].Attributes, Attributes{"color": "red"}) {
 		t.Errorf("failed to create Delta with image insert, got: %+v\n", n.Ops[0].Attributes)
 	}
 }
@@ -1400,3 +1400,51 @@ func TestInvertCombined(t *testing.T) {
 		t.Errorf("Wrong applied document delta, got: %+v\n", applied)
 	}
 }
+
+func TestPushDoesNotModifyCallerText(t *testing.T) {
+	text := []rune("abcdef")
+	n := New(nil)
+	n.Push(Op{Insert: text[:3]})
+	n.Push(Op{Insert: []rune("X")})
+	if string(text) != "abcdef" {
+		t.Errorf("Push modified the caller's text, got: %s\n", string(text))
+	}
+	if string(n.Ops[0].Insert) != "abcX" {
+		t.Errorf("failed to merge inserts, got: %s\n", string(n.Ops[0].Insert))
+	}
+}
+
+func TestPushMergesEmptyAndNilAttributes(t *testing.T) {
+	n := New(nil).Insert("a", nil).Insert("b", map[string]interface{}{})
+	if len(n.Ops) != 1 {
+		t.Errorf("expected empty attributes to merge with nil ones, got: %+v\n", n.Ops)
+	}
+}
+
+func TestComposeAndTransformOwnLengths(t *testing.T) {
+	bold := Attributes{"bold": true}
+	a := New(nil).Retain(2, bold).Delete(1).Retain(3, nil)
+	b := New(nil).Retain(4, nil).Delete(2)
+	for name, got := range map[string]*Delta{
+		"compose":   a.Compose(*b),
+		"transform": a.Transform(*b, true),
+		"slice":     a.Slice(0, 6),
+	} {
+		for i := range got.Ops {
+			if got.Ops[i].Retain != nil {
+				*got.Ops[i].Retain = 100
+			}
+			if got.Ops[i].Delete != nil {
+				*got.Ops[i].Delete = 100
+			}
+		}
+		if a.Length() != 6 || b.Length() != 6 {
+			t.Errorf("%s: changing the result changed the deltas, got %v and %v", name, a.Ops, b.Ops)
+		}
+	}
+	// attributes are shared, never changed in place
+	composed := a.Compose(*New(nil).Retain(2, nil))
+	if reflect.ValueOf(composed.Ops[0].Attributes).Pointer() != reflect.ValueOf(bold).Pointer() {
+		t.Errorf("expected composing with a plain retain to keep the attributes, got %v", composed.Ops[0].Attributes)
+	}
+}
@@ -0,0 +1,229 @@
+package delta
+
+import "errors"
+
+// ErrNotDocument is returned by Diff when one of the deltas has retain or
+// delete ops
+var ErrNotDocument = errors.New("diff called with a delta that is not a document")
+
+// embedChar stands for embeds in the text the diff is computed on, like
+// quill's NULL_CHARACTER
+const embedChar = 0
+
+const (
+	diffEqual = iota
+	diffInsert
+	diffDelete
+)
+
+// diffPart is n characters that are in both texts, only in the new one or
+// only in the old one
+type diffPart struct {
+	kind int
+	n    int
+}
+
+// Diff returns the change that turns d into other, both documents (insert
+// only deltas), so d.Compose(*change) gives other back. Embeds are compared
+// by value, and formats that differ become retains with attributes
+func (d *Delta) Diff(other *Delta) (*Delta, error) {
+	if len(d.Ops) == len(other.Ops) && d.equalOps(other) {
+		return New(nil), nil
+	}
+	a, err := d.diffText()
+	if err != nil {
+		return nil, err
+	}
+	b, err := other.diffText()
+	if err != nil {
+		return nil, err
+	}
+	thisIter := OpsIterator(d.Ops)
+	otherIter := OpsIterator(other.Ops)
+	ret := New(nil)
+	for _, part := range diffRunes(a, b) {
+		for length := part.n; length > 0; {
+			var opLength int
+			switch part.kind {
+			case diffInsert:
+				opLength = minInt(otherIter.PeekLength(), length)
+				ret.Push(otherIter.Next(opLength))
+			case diffDelete:
+				opLength = minInt(thisIter.PeekLength(), length)
+				thisIter.Next(opLength)
+				ret.Delete(opLength)
+			default:
+				opLength = minInt(minInt(thisIter.PeekLength(), otherIter.PeekLength()), length)
+				thisOp := thisIter.Next(opLength)
+				otherOp := otherIter.Next(opLength)
+				if sameInsert(thisOp, otherOp) {
+					ret.Retain(opLength, thisOp.Attributes.Diff(otherOp.Attributes))
+				} else {
+					ret.Push(otherOp).Delete(opLength)
+				}
+			}
+			length -= opLength
+		}
+	}
+	return ret.Chop(), nil
+}
+
+// equalOps reports whether d and other have the same ops, other having at
+// least as many ops as d
+func (d *Delta) equalOps(other *Delta) bool {
+	for i, op := range d.Ops {
+		o := other.Ops[i]
+		if !sameInsert(op, o) || op.Retain != nil || op.Delete != nil ||
+			o.Retain != nil || o.Delete != nil || !op.Attributes.Equal(o.Attributes) {
+			return false
+		}
+	}
+	return true
+}
+
+// diffText returns the text of a document, with embedChar for its embeds
+func (d *Delta) diffText() ([]rune, error) {
+	var text []rune
+	for _, op := range d.Ops {
+		switch {
+		case op.Insert != nil:
+			text = append(text, op.Insert...)
+		case op.InsertEmbed != nil:
+			text = append(text, embedChar)
+		default:
+			return nil, ErrNotDocument
+		}
+	}
+	return text, nil
+}
+
+// sameInsert reports whether two ops insert the same text or embed
+func sameInsert(a, b Op) bool {
+	if a.InsertEmbed != nil || b.InsertEmbed != nil {
+		return a.InsertEmbed != nil && b.InsertEmbed != nil &&
+			a.InsertEmbed.Key == b.InsertEmbed.Key && attrValueEqual(a.InsertEmbed.Value, b.InsertEmbed.Value)
+	}
+	if a.Insert == nil || b.Insert == nil || len(a.Insert) != len(b.Insert) {
+		return false
+	}
+	for i := range a.Insert {
+		if a.Insert[i] != b.Insert[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// diffRunes returns the parts of a shortest edit script from a to b, with
+// the linear space version of Myers' algorithm: the middle snake of the
+// script splits it in two halves found the same way, so memory stays
+// proportional to len(a)+len(b) however different the texts are
+func diffRunes(a, b []rune) []diffPart {
+	size := 2*((len(a)+len(b)+1)/2) + 3
+	x := &differ{
+		forward:  make([]int, size),
+		backward: make([]int, size),
+		offset:   size / 2,
+	}
+	x.diff(a, b)
+	return x.parts
+}
+
+// differ holds the state of one diffRunes call. forward and backward are
+// the furthest x reached on each diagonal from the start and from the end,
+// shared by all the sub problems since none is larger than the first
+type differ struct {
+	parts             []diffPart
+	forward, backward []int
+	offset            int
+}
+
+func (x *differ) add(kind, n int) {
+	if n == 0 {
+		return
+	}
+	if l := len(x.parts); l > 0 && x.parts[l-1].kind == kind {
+		x.parts[l-1].n += n
+		return
+	}
+	x.parts = append(x.parts, diffPart{kind, n})
+}
+
+// diff adds the edit script from a to b
+func (x *differ) diff(a, b []rune) {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	x.add(diffEqual, prefix)
+	a, b = a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(a) == 0 || len(b) == 0 {
+		// this also covers scripts of a single edit, once trimmed
+		x.add(diffDelete, len(a))
+		x.add(diffInsert, len(b))
+	} else {
+		startX, startY, endX, endY := x.middleSnake(a, b)
+		x.diff(a[:startX], b[:startY])
+		x.add(diffEqual, endX-startX)
+		x.diff(a[endX:], b[endY:])
+	}
+	x.add(diffEqual, suffix)
+}
+
+// middleSnake returns where the middle snake of the shortest edit script
+// from a to b starts and ends, searching from both ends at once until the
+// paths overlap. a and b are not empty and differ at both ends, so the
+// script has at least two edits and both halves are smaller than a and b
+func (x *differ) middleSnake(a, b []rune) (startX, startY, endX, endY int) {
+	n, m := len(a), len(b)
+	// the diagonal k from the start is the diagonal delta-k from the end
+	delta := n - m
+	odd := delta%2 != 0
+	forward, backward, offset := x.forward, x.backward, x.offset
+	forward[offset+1] = 0
+	backward[offset+1] = 0
+	for d := 0; d <= (n+m+1)/2; d++ {
+		for k := -d; k <= d; k += 2 {
+			var i int
+			if k == -d || (k != d && forward[offset+k-1] < forward[offset+k+1]) {
+				i = forward[offset+k+1]
+			} else {
+				i = forward[offset+k-1] + 1
+			}
+			j := i - k
+			fromI, fromJ := i, j
+			for i < n && j < m && a[i] == b[j] {
+				i++
+				j++
+			}
+			forward[offset+k] = i
+			if odd && delta-k >= -(d-1) && delta-k <= d-1 && i+backward[offset+delta-k] >= n {
+				return fromI, fromJ, i, j
+			}
+		}
+		for k := -d; k <= d; k += 2 {
+			var i int
+			if k == -d || (k != d && backward[offset+k-1] < backward[offset+k+1]) {
+				i = backward[offset+k+1]
+			} else {
+				i = backward[offset+k-1] + 1
+			}
+			j := i - k
+			fromI, fromJ := i, j
+			for i < n && j < m && a[n-1-i] == b[m-1-j] {
+				i++
+				j++
+			}
+			backward[offset+k] = i
+			if !odd && delta-k >= -d && delta-k <= d && i+forward[offset+delta-k] >= n {
+				return n - i, m - j, n - fromI, m - fromJ
+			}
+		}
+	}
+	// can't happen, the paths meet by the time they cover the whole script
+	return 0, 0, 0, 0
+}
@@ -0,0 +1,179 @@
+package delta
+
+import (
+	"math/rand"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     *Delta
+		expected *Delta
+	}{
+		{"insert", New(nil).Insert("A", nil), New(nil).Insert("AB", nil), New(nil).Retain(1, nil).Insert("B", nil)},
+		{"delete", New(nil).Insert("AB", nil), New(nil).Insert("A", nil), New(nil).Retain(1, nil).Delete(1)},
+		{"retain", New(nil).Insert("A", nil), New(nil).Insert("A", nil), New(nil)},
+		{"format", New(nil).Insert("A", nil), New(nil).Insert("A", Attributes{"bold": true}), New(nil).Retain(1, Attributes{"bold": true})},
+		{"unformat", New(nil).Insert("A", Attributes{"bold": true}), New(nil).Insert("A", nil), New(nil).Retain(1, Attributes{"bold": nil})},
+		{
+			"object attributes",
+			New(nil).Insert("A", Attributes{"font": map[string]interface{}{"family": "Helvetica", "size": "15px"}}),
+			New(nil).Insert("A", Attributes{"font": map[string]interface{}{"family": "Helvetica", "size": "15px"}}),
+			New(nil),
+		},
+		{
+			"same embed",
+			New(nil).InsertEmbed(Embed{Key: "image", Value: "a.png"}, nil),
+			New(nil).InsertEmbed(Embed{Key: "image", Value: "a.png"}, Attributes{"alt": "a"}),
+			New(nil).Retain(1, Attributes{"alt": "a"}),
+		},
+		{
+			"other embed",
+			New(nil).InsertEmbed(Embed{Key: "image", Value: "a.png"}, nil),
+			New(nil).InsertEmbed(Embed{Key: "image", Value: "b.png"}, nil),
+			New(nil).InsertEmbed(Embed{Key: "image", Value: "b.png"}, nil).Delete(1),
+		},
+		{
+			"embed against text",
+			New(nil).InsertEmbed(Embed{Key: "image", Value: "a.png"}, nil),
+			New(nil).Insert("a", nil),
+			New(nil).Insert("a", nil).Delete(1),
+		},
+		{
+			"middle",
+			New(nil).Insert("Bad", Attributes{"color": "red"}).Insert("cat", Attributes{"color": "blue"}),
+			New(nil).Insert("Good", Attributes{"bold": true}).Insert("dog", Attributes{"italic": true}),
+			New(nil).Insert("Good", Attributes{"bold": true}).Delete(2).
+				Retain(1, Attributes{"italic": true, "color": nil}).Insert("og", Attributes{"italic": true}).Delete(3),
+		},
+	}
+	for _, test := range tests {
+		got, err := test.a.Diff(test.b)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got.Ops, test.expected.Ops) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected.Ops, got.Ops)
+		}
+	}
+}
+
+func TestDiffComposes(t *testing.T) {
+	texts := []string{"", "\n", "hello world\n", "hello brave new world\n", "world, hello\n", "h\ne\nl\nl\no\n", "abcabba\n", "cbabac\n"}
+	for _, x := range texts {
+		for _, y := range texts {
+			a := New(nil).Insert(x, nil)
+			b := New(nil).Insert(y, Attributes{"bold": true})
+			change, err := a.Diff(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := a.Compose(*change); !reflect.DeepEqual(got.Ops, b.Ops) {
+				t.Errorf("diff of %q and %q doesn't compose back: %v", x, y, got.Ops)
+			}
+		}
+	}
+}
+
+func TestDiffNotDocument(t *testing.T) {
+	doc := New(nil).Insert("A", nil)
+	if _, err := doc.Diff(New(nil).Retain(1, nil).Insert("B", nil)); err != ErrNotDocument {
+		t.Errorf("expected ErrNotDocument, got %v", err)
+	}
+	if _, err := New(nil).Delete(1).Diff(doc); err != ErrNotDocument {
+		t.Errorf("expected ErrNotDocument, got %v", err)
+	}
+}
+
+func TestDiffRunesShortest(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	random := func() []rune {
+		text := make([]rune, r.Intn(30))
+		for i := range text {
+			text[i] = 'a' + rune(r.Intn(3))
+		}
+		return text
+	}
+	for i := 0; i < 500; i++ {
+		a, b := random(), random()
+		edits, ai, bi := 0, 0, 0
+		for _, p := range diffRunes(a, b) {
+			switch p.kind {
+			case diffEqual:
+				if string(a[ai:ai+p.n]) != string(b[bi:bi+p.n]) {
+					t.Fatalf("%q to %q: retains different text", string(a), string(b))
+				}
+				ai += p.n
+				bi += p.n
+			case diffDelete:
+				ai += p.n
+				edits += p.n
+			case diffInsert:
+				bi += p.n
+				edits += p.n
+			}
+		}
+		if ai != len(a) || bi != len(b) {
+			t.Fatalf("%q to %q: script covers %d and %d characters", string(a), string(b), ai, bi)
+		}
+		if shortest := len(a) + len(b) - 2*lcs(a, b); edits != shortest {
+			t.Errorf("%q to %q: %d edits, the shortest script has %d", string(a), string(b), edits, shortest)
+		}
+	}
+}
+
+// lcs returns the length of the longest common subsequence of a and b
+func lcs(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	for i := range a {
+		cur := make([]int, len(b)+1)
+		for j := range b {
+			switch {
+			case a[i] == b[j]:
+				cur[j+1] = prev[j] + 1
+			case prev[j+1] > cur[j]:
+				cur[j+1] = prev[j+1]
+			default:
+				cur[j+1] = cur[j]
+			}
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// diffTexts returns two documents of n characters with nothing in common
+func diffTexts(n int) (*Delta, *Delta) {
+	a := make([]rune, n)
+	b := make([]rune, n)
+	for i := range a {
+		a[i] = 'a' + rune(i%13)
+		b[i] = 'n' + rune(i%13)
+	}
+	return New(nil).Insert(string(a), nil), New(nil).Insert(string(b), nil)
+}
+
+func TestDiffMemory(t *testing.T) {
+	a, b := diffTexts(5000)
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if _, err := a.Diff(b); err != nil {
+		t.Fatal(err)
+	}
+	runtime.ReadMemStats(&after)
+	// keeping the paths of every step would take over a gigabyte
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 4<<20 {
+		t.Errorf("expected diff to allocate in proportion to the documents, allocated %d bytes", allocated)
+	}
+}
+
+func BenchmarkDiff(b *testing.B) {
+	x, y := diffTexts(2000)
+	for i := 0; i < b.N; i++ {
+		x.Diff(y)
+	}
+}
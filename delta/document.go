@@ -0,0 +1,374 @@
+package delta
+
+import (
+	"errors"
+)
+
+// maxRunLength is the longest run of text we keep in one node. Keeping runs
+// short bounds the work done inside a node when splitting or counting lines
+const maxRunLength = 512
+
+// ErrChangeOutOfRange is returned by Document.Apply when the change retains
+// or deletes past the end of the document
+var ErrChangeOutOfRange = errors.New("change goes past the end of the document")
+
+// Document is a document (an insert only Delta) stored as a balanced tree
+// of formatted runs, a rope. Applying a small change to a big document with
+// Compose copies every op, while Document.Apply only touches the runs the
+// change retains with attributes, deletes or inserts next to, plus
+// O(log n) nodes to find them.
+//
+// The result of Apply is always the same as base.Compose(change), which you
+// get back calling ToDelta.
+type Document struct {
+	root *docNode
+	seed uint32
+}
+
+// docNode is a node of a treap keyed by position. Every node holds one run,
+// which is either text or an embed, and the totals of its subtree
+type docNode struct {
+	left, right *docNode
+	priority    uint32
+	op          Op
+	size        int
+	lines       int
+}
+
+// Line describes one line of the document. Index and Length don't include
+// the newline, Attributes are the block attributes found on the newline
+type Line struct {
+	Number     int
+	Index      int
+	Length     int
+	Attributes Attributes
+}
+
+// NewDocument builds a Document from an insert only delta. Retain and delete
+// ops are ignored
+func NewDocument(d *Delta) *Document {
+	doc := &Document{seed: 2463534242}
+	for _, op := range d.Ops {
+		if op.Insert == nil && op.InsertEmbed == nil {
+			continue
+		}
+		// same as composing with an empty change, drop null attributes
+		op.Attributes = op.Attributes.compose(nil, false)
+		doc.root = merge(doc.root, doc.build(op))
+	}
+	return doc
+}
+
+// Length returns the length of the document
+func (doc *Document) Length() int {
+	return doc.root.getSize()
+}
+
+// Apply composes change into the document, in place
+func (doc *Document) Apply(change Delta) error {
+	if baseLength(change) > doc.Length() {
+		return ErrChangeOutOfRange
+	}
+	pos := 0
+	for _, op := range change.Ops {
+		switch {
+		case op.Insert != nil || op.InsertEmbed != nil:
+			if op.Insert != nil {
+				if len(op.Insert) == 0 {
+					continue
+				}
+				op.Insert = append([]rune(nil), op.Insert...)
+			}
+			left, right := doc.split(doc.root, pos)
+			doc.root = merge(merge(left, doc.build(op)), right)
+			pos += op.Length()
+		case op.Retain != nil:
+			n := *op.Retain
+			if op.Attributes != nil && n > 0 {
+				left, rest := doc.split(doc.root, pos)
+				mid, right := doc.split(rest, n)
+				mid.format(op.Attributes)
+				doc.root = merge(merge(left, mid), right)
+			}
+			pos += n
+		case op.Delete != nil:
+			left, rest := doc.split(doc.root, pos)
+			_, right := doc.split(rest, *op.Delete)
+			doc.root = merge(left, right)
+		}
+	}
+	return nil
+}
+
+// ToDelta converts the document back to an insert only Delta
+func (doc *Document) ToDelta() *Delta {
+	return doc.Slice(0, doc.Length())
+}
+
+// Slice returns the ops between start and end as a Delta, like Delta.Slice
+func (doc *Document) Slice(start, end int) *Delta {
+	ret := New(nil)
+	if start < 0 {
+		start = 0
+	}
+	if end <= start {
+		return ret
+	}
+	doc.root.walk(0, start, end, func(op Op) {
+		if op.Insert != nil {
+			// Push may append to the slice, never share it with the tree
+			op.Insert = append([]rune(nil), op.Insert...)
+		}
+		ret.Push(op)
+	})
+	return ret
+}
+
+// LineCount returns the number of lines in the document. A document that
+// ends with a newline, like all Quill documents do, has one line per newline
+func (doc *Document) LineCount() int {
+	lines := doc.root.getLines()
+	if doc.Length() > 0 {
+		last := doc.Slice(doc.Length()-1, doc.Length())
+		if op := last.Ops[0]; op.Insert == nil || op.Insert[0] != '\n' {
+			lines++
+		}
+	}
+	return lines
+}
+
+// LineAt returns the line that contains index. If index is past the end of
+// the document, the last line is returned
+func (doc *Document) LineAt(index int) Line {
+	if index > doc.Length() {
+		index = doc.Length()
+	}
+	if index < 0 {
+		index = 0
+	}
+	return doc.line(doc.root.newlinesBefore(index))
+}
+
+// Line returns the line with the given number, starting at 0
+func (doc *Document) Line(number int) (Line, bool) {
+	if number < 0 || number >= doc.LineCount() {
+		return Line{}, false
+	}
+	return doc.line(number), true
+}
+
+func (doc *Document) line(number int) Line {
+	ret := Line{Number: number}
+	if number > 0 {
+		ret.Index = doc.root.nthNewline(number-1) + 1
+	}
+	end := doc.Length()
+	if number < doc.root.getLines() {
+		end = doc.root.nthNewline(number)
+		nl := doc.Slice(end, end+1)
+		ret.Attributes = nl.Ops[0].Attributes
+	}
+	ret.Length = end - ret.Index
+	return ret
+}
+
+// build creates a subtree for op, splitting long text in several runs
+func (doc *Document) build(op Op) *docNode {
+	if op.Insert == nil || len(op.Insert) <= maxRunLength {
+		return doc.newNode(op)
+	}
+	var ret *docNode
+	for text := op.Insert; len(text) > 0; {
+		n := len(text)
+		if n > maxRunLength {
+			n = maxRunLength
+		}
+		ret = merge(ret, doc.newNode(Op{Insert: text[:n:n], Attributes: op.Attributes}))
+		text = text[n:]
+	}
+	return ret
+}
+
+func (doc *Document) newNode(op Op) *docNode {
+	// xorshift, we only need the priorities to look random
+	doc.seed ^= doc.seed << 13
+	doc.seed ^= doc.seed >> 17
+	doc.seed ^= doc.seed << 5
+	n := &docNode{priority: doc.seed, op: op}
+	n.update()
+	return n
+}
+
+// split returns the characters before pos and the ones from pos on. A run
+// that crosses pos is cut in two nodes
+func (doc *Document) split(n *docNode, pos int) (*docNode, *docNode) {
+	if n == nil {
+		return nil, nil
+	}
+	leftSize := n.left.getSize()
+	runLength := n.op.Length()
+	switch {
+	case pos <= leftSize:
+		left, right := doc.split(n.left, pos)
+		n.left = right
+		n.update()
+		return left, n
+	case pos >= leftSize+runLength:
+		left, right := doc.split(n.right, pos-leftSize-runLength)
+		n.right = left
+		n.update()
+		return n, right
+	}
+	offset := pos - leftSize
+	tail := doc.newNode(Op{Insert: n.op.Insert[offset:], Attributes: n.op.Attributes})
+	n.op.Insert = n.op.Insert[:offset:offset]
+	right := merge(tail, n.right)
+	n.right = nil
+	n.update()
+	return n, right
+}
+
+func merge(a, b *docNode) *docNode {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	if a.priority > b.priority {
+		a.right = merge(a.right, b)
+		a.update()
+		return a
+	}
+	b.left = merge(a, b.left)
+	b.update()
+	return b
+}
+
+func (n *docNode) getSize() int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+func (n *docNode) getLines() int {
+	if n == nil {
+		return 0
+	}
+	return n.lines
+}
+
+func (n *docNode) update() {
+	n.size = n.left.getSize() + n.op.Length() + n.right.getSize()
+	n.lines = n.left.getLines() + countNewlines(n.op.Insert) + n.right.getLines()
+}
+
+// format composes attrs into every run of the subtree
+func (n *docNode) format(attrs Attributes) {
+	if n == nil {
+		return
+	}
+	n.left.format(attrs)
+	n.op.Attributes = n.op.Attributes.compose(attrs, false)
+	n.right.format(attrs)
+}
+
+// walk calls fn, in order, with the part of every run between start and end.
+// offset is the position of the first character of the subtree
+func (n *docNode) walk(offset, start, end int, fn func(Op)) {
+	if n == nil || offset >= end || offset+n.size <= start {
+		return
+	}
+	n.left.walk(offset, start, end, fn)
+	runStart := offset + n.left.getSize()
+	runEnd := runStart + n.op.Length()
+	if runStart < end && runEnd > start {
+		op := n.op
+		if op.Insert != nil {
+			from, to := 0, len(op.Insert)
+			if start > runStart {
+				from = start - runStart
+			}
+			if end < runEnd {
+				to = end - runStart
+			}
+			op.Insert = op.Insert[from:to]
+		}
+		fn(op)
+	}
+	n.right.walk(runEnd, start, end, fn)
+}
+
+// newlinesBefore counts the newlines in the first pos characters
+func (n *docNode) newlinesBefore(pos int) int {
+	count := 0
+	for n != nil {
+		leftSize := n.left.getSize()
+		if pos <= leftSize {
+			n = n.left
+			continue
+		}
+		count += n.left.getLines()
+		pos -= leftSize
+		runLength := n.op.Length()
+		if pos <= runLength {
+			if n.op.Insert != nil {
+				count += countNewlines(n.op.Insert[:pos])
+			}
+			return count
+		}
+		count += countNewlines(n.op.Insert)
+		pos -= runLength
+		n = n.right
+	}
+	return count
+}
+
+// nthNewline returns the position of the newline number k, starting at 0.
+// The caller must make sure there are more than k newlines
+func (n *docNode) nthNewline(k int) int {
+	offset := 0
+	for n != nil {
+		if k < n.left.getLines() {
+			n = n.left
+			continue
+		}
+		k -= n.left.getLines()
+		offset += n.left.getSize()
+		for i, r := range n.op.Insert {
+			if r == '\n' {
+				if k == 0 {
+					return offset + i
+				}
+				k--
+			}
+		}
+		offset += n.op.Length()
+		n = n.right
+	}
+	return -1
+}
+
+func countNewlines(text []rune) int {
+	count := 0
+	for _, r := range text {
+		if r == '\n' {
+			count++
+		}
+	}
+	return count
+}
+
+// baseLength returns the length of the document a change applies to
+func baseLength(change Delta) int {
+	length := 0
+	for _, op := range change.Ops {
+		if op.Retain != nil {
+			length += *op.Retain
+		} else if op.Delete != nil {
+			length += *op.Delete
+		}
+	}
+	return length
+}
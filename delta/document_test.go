@@ -0,0 +1,191 @@
+package delta
+
+import (
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// randomAttributes start with the ones inserts can use, the last ones
+// remove formats
+var randomAttributes = []map[string]interface{}{
+	nil,
+	{"bold": true},
+	{"italic": true, "color": "red"},
+	{"header": 1.0},
+	{"link": "https://a.example"},
+	{"link": "https://b.example", "bold": true},
+	{"bold": nil},
+	{"color": nil, "italic": true},
+	{"link": nil},
+}
+
+// insertAttributes is how many of randomAttributes inserts can use
+const insertAttributes = 6
+
+func randomText(r *rand.Rand, n int) string {
+	letters := []rune("abcde \n日😀")
+	ret := make([]rune, n)
+	for i := range ret {
+		ret[i] = letters[r.Intn(len(letters))]
+	}
+	return string(ret)
+}
+
+func randomDocument(r *rand.Rand) *Delta {
+	d := New(nil)
+	for i := r.Intn(20); i >= 0; i-- {
+		attrs := randomAttributes[r.Intn(insertAttributes)]
+		if r.Intn(8) == 0 {
+			d.InsertEmbed(Embed{Key: "image", Value: "a.png"}, attrs)
+		} else {
+			d.Insert(randomText(r, 1+r.Intn(1200)), attrs)
+		}
+	}
+	return d.Insert("\n", nil)
+}
+
+func randomChange(r *rand.Rand, length int) *Delta {
+	d := New(nil)
+	pos := 0
+	for pos < length {
+		n := 1 + r.Intn(length-pos)
+		if r.Intn(3) > 0 && n > 50 {
+			n = 1 + r.Intn(50)
+		}
+		switch r.Intn(4) {
+		case 0:
+			d.Retain(n, nil)
+			pos += n
+		case 1:
+			d.Retain(n, randomAttributes[r.Intn(len(randomAttributes))])
+			pos += n
+		case 2:
+			d.Delete(n)
+			pos += n
+		case 3:
+			if r.Intn(6) == 0 {
+				d.InsertEmbed(Embed{Key: "video", Value: "b.mp4"}, nil)
+			} else {
+				d.Insert(randomText(r, 1+r.Intn(20)), randomAttributes[r.Intn(insertAttributes)])
+			}
+		}
+		if r.Intn(5) == 0 {
+			break
+		}
+	}
+	return d.Chop()
+}
+
+func TestDocumentApplyMatchesCompose(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	for i := 0; i < 300; i++ {
+		base := randomDocument(r)
+		doc := NewDocument(base)
+		expected := base
+		for j := 0; j < 5; j++ {
+			change := randomChange(r, expected.Length())
+			expected = expected.Compose(*change)
+			if err := doc.Apply(*change); err != nil {
+				t.Fatal(err)
+			}
+			got := doc.ToDelta()
+			if !reflect.DeepEqual(got.Ops, expected.Ops) {
+				a, _ := json.Marshal(expected)
+				b, _ := json.Marshal(got)
+				t.Fatalf("%d/%d: document differs from compose\nexpected: %s\ngot:      %s\n", i, j, a, b)
+			}
+			if doc.Length() != expected.Length() {
+				t.Fatalf("%d/%d: expected length %d but got %d", i, j, expected.Length(), doc.Length())
+			}
+		}
+	}
+}
+
+func TestDocumentSlice(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+	for i := 0; i < 100; i++ {
+		base := randomDocument(r)
+		doc := NewDocument(base)
+		start := r.Intn(base.Length())
+		end := start + r.Intn(base.Length()-start+1)
+		expected := base.Slice(start, end)
+		got := doc.Slice(start, end)
+		if len(expected.Ops) == 0 && len(got.Ops) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got.Ops, expected.Ops) {
+			t.Fatalf("%d: Slice(%d, %d) expected %+v but got %+v", i, start, end, expected.Ops, got.Ops)
+		}
+	}
+}
+
+func TestDocumentApplyOutOfRange(t *testing.T) {
+	doc := NewDocument(New(nil).Insert("abc\n", nil))
+	if err := doc.Apply(*New(nil).Retain(3, nil).Delete(2)); err != ErrChangeOutOfRange {
+		t.Errorf("expected ErrChangeOutOfRange, got %v", err)
+	}
+	if got := doc.ToDelta(); string(got.Ops[0].Insert) != "abc\n" {
+		t.Errorf("failed apply should not change the document, got %+v", got.Ops)
+	}
+}
+
+func TestDocumentLines(t *testing.T) {
+	base := New(nil).Insert("Title", nil).Insert("\n", map[string]interface{}{"header": 1}).
+		Insert("first line\n", nil).
+		Insert(strings.Repeat("long ", 300), map[string]interface{}{"bold": true}).
+		Insert("\n", map[string]interface{}{"list": "bullet"})
+	doc := NewDocument(base)
+	if n := doc.LineCount(); n != 3 {
+		t.Errorf("expected 3 lines, got %d", n)
+	}
+	line := doc.LineAt(2)
+	if line.Number != 0 || line.Index != 0 || line.Length != 5 || line.Attributes["header"] != 1 {
+		t.Errorf("unexpected first line %+v", line)
+	}
+	line = doc.LineAt(5)
+	if line.Number != 0 {
+		t.Errorf("the newline belongs to its line, got %+v", line)
+	}
+	line = doc.LineAt(6)
+	if line.Number != 1 || line.Index != 6 || line.Length != 10 || line.Attributes != nil {
+		t.Errorf("unexpected second line %+v", line)
+	}
+	line, ok := doc.Line(2)
+	if !ok || line.Index != 17 || line.Length != 1500 || line.Attributes["list"] != "bullet" {
+		t.Errorf("unexpected third line %+v", line)
+	}
+	if _, ok := doc.Line(3); ok {
+		t.Errorf("expected no line 3")
+	}
+	doc.Apply(*New(nil).Retain(17+1500, nil).Delete(1))
+	if n := doc.LineCount(); n != 3 {
+		t.Errorf("expected 3 lines after removing the last newline, got %d", n)
+	}
+	if line, _ := doc.Line(2); line.Length != 1500 || line.Attributes != nil {
+		t.Errorf("unexpected unterminated line %+v", line)
+	}
+}
+
+func BenchmarkDocumentApply(b *testing.B) {
+	base := New(nil)
+	for i := 0; i < 20000; i++ {
+		base.Insert(strings.Repeat("x", 200), map[string]interface{}{"bold": i%2 == 0})
+	}
+	base.Insert("\n", nil)
+	change := New(nil).Retain(base.Length()/2, nil).Insert("hello", nil)
+	b.Run("Compose", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			base.Compose(*change)
+		}
+	})
+	b.Run("Document", func(b *testing.B) {
+		doc := NewDocument(base)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			doc.Apply(*change)
+		}
+	})
+}
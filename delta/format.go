@@ -0,0 +1,83 @@
+package delta
+
+// FormatAt returns the formats of the document between index and
+// index+length, like quill's getFormat. It has the inline formats shared
+// by every character in the range, plus the block formats shared by every
+// line the range touches, which live on the newline ending each line.
+// With length 0 it returns the formats of the character at index
+func (d *Delta) FormatAt(index, length int) Attributes {
+	if index < 0 {
+		index = 0
+	}
+	if length <= 0 {
+		length = 0
+		if docLength := d.Length(); index >= docLength && docLength > 0 {
+			index = docLength - 1
+		}
+	}
+	end := index + length
+	if length == 0 {
+		end = index + 1
+	}
+
+	var inline, block Attributes
+	inlineSeen, blockSeen := false, false
+	for _, op := range d.Slice(index, end).Ops {
+		if op.InsertEmbed != nil {
+			inline, inlineSeen = intersectFormats(inline, op.Attributes, inlineSeen), true
+			continue
+		}
+		for _, r := range op.Insert {
+			if r == '\n' {
+				block, blockSeen = intersectFormats(block, op.Attributes, blockSeen), true
+			} else {
+				inline, inlineSeen = intersectFormats(inline, op.Attributes, inlineSeen), true
+			}
+		}
+	}
+
+	// the line where the range ends continues until its newline
+	iter := OpsIterator(d.Ops)
+	for pos := 0; pos < end && iter.HasNext(); {
+		pos += OpsLength(iter.Next(end - pos))
+	}
+	lastIsNewline := false
+	if slice := d.Slice(end-1, end); len(slice.Ops) > 0 {
+		last := slice.Ops[0].Insert
+		lastIsNewline = len(last) > 0 && last[0] == '\n'
+	}
+	for !lastIsNewline && iter.HasNext() {
+		op := iter.Next(1)
+		if op.Insert != nil && op.Insert[0] == '\n' {
+			block, blockSeen = intersectFormats(block, op.Attributes, blockSeen), true
+			break
+		}
+	}
+
+	ret := make(Attributes, len(block)+len(inline))
+	for k, v := range block {
+		ret[k] = v
+	}
+	for k, v := range inline {
+		ret[k] = v
+	}
+	if len(ret) == 0 {
+		return nil
+	}
+	return ret
+}
+
+// intersectFormats keeps the formats of combined that attrs has with the
+// same value. The first time, seen is false and we take all of attrs
+func intersectFormats(combined, attrs Attributes, seen bool) Attributes {
+	if !seen {
+		return attrs
+	}
+	var ret Attributes
+	for k, v := range combined {
+		if other, found := attrs[k]; found && attrValueEqual(v, other) {
+			ret = ret.With(k, v)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,61 @@
+package delta
+
+import (
+	"reflect"
+	"testing"
+)
+
+func formatDocument() *Delta {
+	return New(nil).
+		Insert("Title", Attributes{"bold": true}).
+		Insert("\n", Attributes{"header": 1}).
+		Insert("Hello ", Attributes{"bold": true, "italic": true}).
+		Insert("World", Attributes{"bold": true, "color": "red"}).
+		Insert("\n", Attributes{"list": "bullet", "align": "center"}).
+		InsertEmbed(Embed{Key: "image", Value: "a.png"}, Attributes{"bold": true}).
+		Insert("plain", nil).
+		Insert("\n", Attributes{"list": "bullet"})
+}
+
+func TestFormatAt(t *testing.T) {
+	d := formatDocument()
+	cases := []struct {
+		index, length int
+		expected      Attributes
+	}{
+		// cursor in the title
+		{2, 0, Attributes{"bold": true, "header": 1}},
+		// whole title, with its newline
+		{0, 6, Attributes{"bold": true, "header": 1}},
+		// Hello World
+		{6, 11, Attributes{"bold": true, "list": "bullet", "align": "center"}},
+		// inside Hello
+		{7, 3, Attributes{"bold": true, "italic": true, "list": "bullet", "align": "center"}},
+		// across the two list items
+		{8, 12, Attributes{"list": "bullet"}},
+		// the image
+		{18, 1, Attributes{"bold": true, "list": "bullet"}},
+		// title and first list item share nothing
+		{0, 10, Attributes{"bold": true}},
+		// just a newline
+		{5, 1, Attributes{"header": 1}},
+		// past the end, the last character
+		{100, 0, Attributes{"list": "bullet"}},
+	}
+	for _, c := range cases {
+		got := d.FormatAt(c.index, c.length)
+		if !reflect.DeepEqual(got, c.expected) {
+			t.Errorf("FormatAt(%d, %d) expected %v but got %v", c.index, c.length, c.expected, got)
+		}
+	}
+}
+
+func TestFormatAtWithoutNewline(t *testing.T) {
+	d := New(nil).Insert("abc", Attributes{"italic": true})
+	if got := d.FormatAt(1, 1); !reflect.DeepEqual(got, Attributes{"italic": true}) {
+		t.Errorf("unexpected formats %v", got)
+	}
+	if got := New(nil).FormatAt(0, 0); got != nil {
+		t.Errorf("expected no formats on an empty document, got %v", got)
+	}
+}
@@ -0,0 +1,136 @@
+package delta
+
+import (
+	"math"
+	"reflect"
+	"sync"
+)
+
+// Interning keeps one shared copy of every distinct set of attributes. A
+// document decoded from JSON has a new map for every op, after interning the
+// ops with the same formats share one, so Push and Compose compare them with
+// a pointer check instead of walking the maps
+
+const (
+	// maxInterned bounds the table, attributes from untrusted input could
+	// grow it forever otherwise. Once it is full Intern keeps new attributes
+	// as they are
+	maxInterned = 1 << 16
+	// maxInternedKeys leaves out unusually large attributes
+	maxInternedKeys = 16
+
+	fnvOffset = 14695981039346656037
+	fnvPrime  = 1099511628211
+)
+
+var interned = struct {
+	sync.Mutex
+	// maps holds the canonical attributes by their hash
+	maps map[uint64][]Attributes
+	// hashes caches the hash of every canonical map, by its address, so
+	// interning attributes that are already interned costs one lookup
+	hashes map[uintptr]uint64
+	// keys shares the key strings between canonical maps
+	keys map[string]string
+}{
+	maps:   make(map[uint64][]Attributes),
+	hashes: make(map[uintptr]uint64),
+	keys:   make(map[string]string),
+}
+
+// Intern returns the shared copy of attributes equal to a, adding a copy of
+// a the first time. Like all attributes, the result must never be changed in
+// place. Empty attributes, and those with values other than strings, bools,
+// numbers and nil, are returned as they are
+func Intern(a Attributes) Attributes {
+	if len(a) == 0 || len(a) > maxInternedKeys {
+		return a
+	}
+	ptr := reflect.ValueOf(a).Pointer()
+	interned.Lock()
+	defer interned.Unlock()
+	if _, found := interned.hashes[ptr]; found {
+		return a
+	}
+	h, ok := a.hash()
+	if !ok {
+		return a
+	}
+	for _, c := range interned.maps[h] {
+		if c.Equal(a) {
+			return c
+		}
+	}
+	if len(interned.hashes) >= maxInterned {
+		return a
+	}
+	c := make(Attributes, len(a))
+	for k, v := range a {
+		key, found := interned.keys[k]
+		if !found {
+			interned.keys[k] = k
+			key = k
+		}
+		c[key] = v
+	}
+	interned.maps[h] = append(interned.maps[h], c)
+	interned.hashes[reflect.ValueOf(c).Pointer()] = h
+	return c
+}
+
+// hash returns a hash of a that doesn't depend on the order of the keys.
+// Equal attributes have the same hash. It fails for values that Intern
+// doesn't keep
+func (a Attributes) hash() (uint64, bool) {
+	var sum uint64
+	for k, v := range a {
+		h := hashString(fnvOffset, k)
+		switch v := v.(type) {
+		case nil:
+			h = hashUint(h, 0)
+		case string:
+			h = hashString(hashUint(h, 1), v)
+		case bool:
+			b := uint64(0)
+			if v {
+				b = 1
+			}
+			h = hashUint(hashUint(h, 2), b)
+		case float64:
+			if v != v {
+				// NaN is never equal to itself
+				return 0, false
+			}
+			if v == 0 {
+				// 0 and -0 are equal
+				v = 0
+			}
+			h = hashUint(hashUint(h, 3), math.Float64bits(v))
+		case int:
+			h = hashUint(hashUint(h, 4), uint64(v))
+		default:
+			return 0, false
+		}
+		// adding the hashes of the entries makes the order irrelevant
+		sum += h
+	}
+	return sum, true
+}
+
+// hashString and hashUint feed FNV-1a without the allocations of hash/fnv
+func hashString(h uint64, s string) uint64 {
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= fnvPrime
+	}
+	return h
+}
+
+func hashUint(h, v uint64) uint64 {
+	for i := 0; i < 8; i++ {
+		h ^= v & 0xff
+		h *= fnvPrime
+		v >>= 8
+	}
+	return h
+}
@@ -0,0 +1,68 @@
+package delta
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestIntern(t *testing.T) {
+	a := Intern(Attributes{"bold": true, "color": "#e60000", "header": 2.0, "link": nil})
+	b := Intern(Attributes{"link": nil, "header": 2.0, "color": "#e60000", "bold": true})
+	if !sameMap(a, b) {
+		t.Errorf("equal attributes should be interned to the same map")
+	}
+	if c := Intern(a); !sameMap(a, c) {
+		t.Errorf("interning interned attributes should return them")
+	}
+	if c := Intern(Attributes{"bold": true}); sameMap(a, c) || c.Equal(a) {
+		t.Errorf("different attributes should not share a map")
+	}
+	src := Attributes{"italic": true}
+	if c := Intern(src); sameMap(src, c) {
+		t.Errorf("Intern should keep a copy, not the caller's map")
+	}
+	list := Attributes{"list": []interface{}{"a"}}
+	if c := Intern(list); !sameMap(list, c) {
+		t.Errorf("attributes with composite values should not be interned")
+	}
+	nan := Attributes{"size": math.NaN()}
+	if c := Intern(nan); !sameMap(nan, c) {
+		t.Errorf("NaN should not be interned")
+	}
+}
+
+func TestInternHash(t *testing.T) {
+	zero, _ := Attributes{"size": 0.0}.hash()
+	negZero, _ := Attributes{"size": math.Copysign(0, -1)}.hash()
+	if zero != negZero {
+		t.Errorf("0 and -0 are equal and should have the same hash")
+	}
+	f, _ := Attributes{"header": 1.0}.hash()
+	i, _ := Attributes{"header": 1}.hash()
+	s, _ := Attributes{"header": "1"}.hash()
+	if f == i || f == s || i == s {
+		t.Errorf("values of different types should have different hashes")
+	}
+}
+
+func TestInternDecoded(t *testing.T) {
+	var d Delta
+	if err := json.Unmarshal([]byte(`{"ops":[{"insert":"a","attributes":{"bold":true}},{"insert":"\n"},{"insert":"b","attributes":{"bold":true}}]}`), &d); err != nil {
+		t.Fatal(err)
+	}
+	if !sameMap(d.Ops[0].Attributes, d.Ops[2].Attributes) {
+		t.Errorf("ops decoded from JSON should share their attributes")
+	}
+	buf, err := d.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Delta
+	if err := decoded.UnmarshalBinary(buf); err != nil {
+		t.Fatal(err)
+	}
+	if !sameMap(decoded.Ops[0].Attributes, d.Ops[0].Attributes) {
+		t.Errorf("ops decoded from binary should share their attributes")
+	}
+}
@@ -26,7 +26,9 @@ func (x *Iterator) HasNext() bool {
 	return x.PeekLength() < math.MaxInt64
 }
 
-// Next moves the index on item
+// Next moves the index on item. The op returned shares its insert text and
+// attributes with the iterated op, which are never changed in place, but
+// always has its own retain or delete length
 func (x *Iterator) Next(length int) Op {
 	// TODO: see where we need to allow passing infinity as length
 	// js code does that if length is missing
@@ -47,15 +49,22 @@ func (x *Iterator) Next(length int) Op {
 	} else {
 		x.Offset += length
 	}
+	if offset == 0 && length == opLength && nextOp.Retain == nil && nextOp.Delete == nil {
+		// a whole insert, share it instead of allocating a new op
+		return nextOp
+	}
+	// a new variable, so length itself doesn't escape to the heap when we
+	// return a whole insert
+	n := length
 	if nextOp.Delete != nil {
-		return Op{Delete: &length}
+		return Op{Delete: &n}
 	}
 	retOp := Op{}
 	if nextOp.Attributes != nil {
 		retOp.Attributes = nextOp.Attributes
 	}
 	if nextOp.Retain != nil {
-		retOp.Retain = &length
+		retOp.Retain = &n
 	}
 	if nextOp.Insert != nil {
 		// when using Go's slice syntax to extract characters from a string, note that the
@@ -369,3 +369,28 @@ func TestAttrInvertCombined(t *testing.T) {
 		t.Errorf("Wrong inverted attribute map, got: %+v\n", ret)
 	}
 }
+
+func TestAttrEqual(t *testing.T) {
+	cases := []struct {
+		a, b  map[string]interface{}
+		equal bool
+	}{
+		{nil, nil, true},
+		{nil, map[string]interface{}{}, true},
+		{map[string]interface{}{"bold": true}, map[string]interface{}{"bold": true}, true},
+		{map[string]interface{}{"bold": true}, map[string]interface{}{"bold": false}, false},
+		{map[string]interface{}{"bold": nil}, map[string]interface{}{"bold": nil}, true},
+		{map[string]interface{}{"bold": nil}, map[string]interface{}{"italic": nil}, false},
+		{map[string]interface{}{"bold": nil}, map[string]interface{}{}, false},
+		{map[string]interface{}{"header": 1.0}, map[string]interface{}{"header": 1.0}, true},
+		{map[string]interface{}{"header": 1.0}, map[string]interface{}{"header": 1}, false},
+		{map[string]interface{}{"color": "red"}, map[string]interface{}{"color": "blue"}, false},
+		{map[string]interface{}{"x": []interface{}{"a"}}, map[string]interface{}{"x": []interface{}{"a"}}, true},
+		{map[string]interface{}{"x": []interface{}{"a"}}, map[string]interface{}{"x": []interface{}{"b"}}, false},
+	}
+	for i, c := range cases {
+		if got := AttrEqual(c.a, c.b); got != c.equal {
+			t.Errorf("%d: AttrEqual(%+v, %+v) expected %v", i, c.a, c.b, c.equal)
+		}
+	}
+}
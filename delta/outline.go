@@ -0,0 +1,244 @@
+package delta
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Heading is a header line of the document
+type Heading struct {
+	Level int
+	Text  string
+	// Index is where the line starts in the document
+	Index int
+	// ID is a slug of the text, unique in the document, good for anchors.
+	// Repeated titles get -1, -2... in document order
+	ID string
+}
+
+// OutlineNode is a heading with the headings under it
+type OutlineNode struct {
+	Heading
+	Children []*OutlineNode
+}
+
+// HeadingChangeKind tells what happened to a heading
+type HeadingChangeKind int
+
+const (
+	// HeadingAdded is a new heading, only New is set
+	HeadingAdded HeadingChangeKind = iota + 1
+	// HeadingRemoved is a heading that is gone, only Old is set
+	HeadingRemoved
+	// HeadingUpdated is a heading whose level, text or id changed
+	HeadingUpdated
+)
+
+// HeadingChange is reported by UpdateOutline
+type HeadingChange struct {
+	Kind HeadingChangeKind
+	Old  Heading
+	New  Heading
+}
+
+// Outline returns the headings of the document, in order. Headings are the
+// lines whose newline has a header attribute
+func (d *Delta) Outline() []Heading {
+	headings, _ := findHeadings(d, 0)
+	return assignIDs(headings)
+}
+
+// OutlineTree nests the headings by level. A heading is a child of the
+// closest heading before it with a lower level
+func OutlineTree(headings []Heading) []*OutlineNode {
+	var roots []*OutlineNode
+	var stack []*OutlineNode
+	for _, h := range headings {
+		node := &OutlineNode{Heading: h}
+		for len(stack) > 0 && stack[len(stack)-1].Level >= h.Level {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			roots = append(roots, node)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, node)
+		}
+		stack = append(stack, node)
+	}
+	return roots
+}
+
+// UpdateOutline returns the outline of doc.Compose(change), given headings,
+// the outline of doc, and what changed. Only the lines touched by change are
+// looked at, the headings after them just move. Headings that only moved
+// are not reported as changes
+func UpdateOutline(headings []Heading, doc *Delta, change *Delta) ([]Heading, []HeadingChange) {
+	from, to, ok := touchedLines(doc, change)
+	if !ok {
+		return headings, nil
+	}
+	before := doc.Slice(from, to)
+	local := sliceChange(change, from, to, doc.Length())
+	after := before.Compose(*local)
+	shift := after.Length() - before.Length()
+
+	var ret []Heading
+	var moved []int
+	for i, h := range headings {
+		if h.Index < from {
+			ret = append(ret, h)
+		} else if h.Index >= to {
+			moved = append(moved, i)
+		}
+	}
+	oldRegion, oldEnds := findHeadings(before, from)
+	newRegion, newEnds := findHeadings(after, from)
+	first := len(ret)
+	ret = append(ret, newRegion...)
+	for _, i := range moved {
+		h := headings[i]
+		h.Index += shift
+		ret = append(ret, h)
+	}
+	ret = assignIDs(ret)
+	for k := range oldRegion {
+		oldRegion[k].ID = headings[first+k].ID
+	}
+
+	// a heading inside the touched lines is the same heading as before if
+	// its newline survived the change
+	paired := make(map[int]int)
+	for k, end := range oldEnds {
+		if pos, ok := mapIndex(local, end); ok {
+			paired[pos] = k
+		}
+	}
+	var changes []HeadingChange
+	kept := make([]bool, len(oldRegion))
+	for i := range ret {
+		var old Heading
+		switch {
+		case i < first:
+			old = headings[i]
+		case i < first+len(newRegion):
+			k, ok := paired[newEnds[i-first]]
+			if !ok {
+				changes = append(changes, HeadingChange{Kind: HeadingAdded, New: ret[i]})
+				continue
+			}
+			kept[k] = true
+			old = oldRegion[k]
+		default:
+			old = headings[moved[i-first-len(newRegion)]]
+		}
+		if old.Level != ret[i].Level || old.Text != ret[i].Text || old.ID != ret[i].ID {
+			changes = append(changes, HeadingChange{Kind: HeadingUpdated, Old: old, New: ret[i]})
+		}
+	}
+	for k, h := range oldRegion {
+		if !kept[k] {
+			changes = append(changes, HeadingChange{Kind: HeadingRemoved, Old: h})
+		}
+	}
+	return ret, changes
+}
+
+// mapIndex returns where the character at index ends up after change, false
+// if change deletes it
+func mapIndex(change *Delta, index int) (int, bool) {
+	offset, shift := 0, 0
+	for _, op := range change.Ops {
+		if offset > index {
+			break
+		}
+		switch {
+		case op.Delete != nil:
+			if index < offset+*op.Delete {
+				return 0, false
+			}
+			offset += *op.Delete
+			shift -= *op.Delete
+		case op.Retain != nil:
+			offset += *op.Retain
+		default:
+			shift += op.Length()
+		}
+	}
+	return index + shift, true
+}
+
+// findHeadings returns the headings of d, without ids, adding offset to
+// their index, and where their newlines are in d
+func findHeadings(d *Delta, offset int) ([]Heading, []int) {
+	var ret []Heading
+	var ends []int
+	var text []rune
+	lineStart := 0
+	pos := 0
+	for _, op := range d.Ops {
+		if op.Insert == nil {
+			pos += op.Length()
+			continue
+		}
+		for _, r := range op.Insert {
+			pos++
+			if r != '\n' {
+				text = append(text, r)
+				continue
+			}
+			if level, ok := op.Attributes.Int("header"); ok && level > 0 {
+				ret = append(ret, Heading{
+					Level: level,
+					Text:  strings.TrimSpace(string(text)),
+					Index: offset + lineStart,
+				})
+				ends = append(ends, pos-1)
+			}
+			text = text[:0]
+			lineStart = pos
+		}
+	}
+	return ret, ends
+}
+
+// assignIDs sets the id of every heading, in place
+func assignIDs(headings []Heading) []Heading {
+	used := make(map[string]bool)
+	count := make(map[string]int)
+	for i := range headings {
+		base := Slug(headings[i].Text)
+		id := base
+		for used[id] {
+			count[base]++
+			id = base + "-" + strconv.Itoa(count[base])
+		}
+		used[id] = true
+		headings[i].ID = id
+	}
+	return headings
+}
+
+// Slug turns a heading text into something usable as an id: lowercase
+// letters and digits separated by dashes
+func Slug(text string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(r)
+		case unicode.IsSpace(r) || r == '-' || r == '_':
+			dash = true
+		}
+	}
+	if b.Len() == 0 {
+		return "heading"
+	}
+	return b.String()
+}
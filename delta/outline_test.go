@@ -0,0 +1,102 @@
+package delta
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func outlineDocument() *Delta {
+	return New(nil).
+		Insert("Intro", nil).Insert("\n", Attributes{"header": 1}).
+		Insert("Some text\n", nil).
+		Insert("Setup ", nil).Insert("steps", Attributes{"bold": true}).Insert("\n", Attributes{"header": 2}).
+		Insert("Intro", nil).Insert("\n", Attributes{"header": 3}).
+		Insert("Usage!", nil).Insert("\n", Attributes{"header": 2}).
+		Insert("¿Qué tal?", nil).Insert("\n", Attributes{"header": 1})
+}
+
+func TestOutline(t *testing.T) {
+	got := outlineDocument().Outline()
+	expected := []Heading{
+		{Level: 1, Text: "Intro", Index: 0, ID: "intro"},
+		{Level: 2, Text: "Setup steps", Index: 16, ID: "setup-steps"},
+		{Level: 3, Text: "Intro", Index: 28, ID: "intro-1"},
+		{Level: 2, Text: "Usage!", Index: 34, ID: "usage"},
+		{Level: 1, Text: "¿Qué tal?", Index: 41, ID: "qué-tal"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected outline\ngot:      %+v\nexpected: %+v", got, expected)
+	}
+	tree := OutlineTree(got)
+	if len(tree) != 2 || len(tree[0].Children) != 2 || len(tree[0].Children[0].Children) != 1 ||
+		tree[0].Children[0].Children[0].ID != "intro-1" || len(tree[1].Children) != 0 {
+		t.Errorf("unexpected tree %+v", tree)
+	}
+}
+
+func TestUpdateOutline(t *testing.T) {
+	doc := outlineDocument()
+	headings := doc.Outline()
+
+	// typing in a paragraph changes nothing, the headings after it move
+	change := New(nil).Retain(10, nil).Insert("more ", nil)
+	got, changes := UpdateOutline(headings, doc, change)
+	if len(changes) != 0 || got[1].Index != 21 || got[0].Index != 0 {
+		t.Errorf("unexpected update %+v %+v", got, changes)
+	}
+
+	// renaming the first heading changes its id, and the duplicate's
+	change = New(nil).Insert("New ", nil)
+	got, changes = UpdateOutline(headings, doc, change)
+	if len(changes) != 2 || changes[0].Kind != HeadingUpdated || changes[0].New.ID != "new-intro" ||
+		changes[1].Old.ID != "intro-1" || changes[1].New.ID != "intro" {
+		t.Errorf("unexpected changes %+v", changes)
+	}
+
+	// turning a paragraph into a heading and removing one
+	change = New(nil).Retain(15, nil).Retain(1, Attributes{"header": 2}).Retain(24, nil).Retain(1, Attributes{"header": nil})
+	got, changes = UpdateOutline(headings, doc, change)
+	if len(changes) != 2 || changes[0].Kind != HeadingAdded || changes[0].New.Text != "Some text" ||
+		changes[1].Kind != HeadingRemoved || changes[1].Old.Text != "Usage!" {
+		t.Errorf("unexpected changes %+v", changes)
+	}
+	if !reflect.DeepEqual(got, doc.Compose(*change).Outline()) {
+		t.Errorf("unexpected outline %+v", got)
+	}
+}
+
+func randomOutlineDocument(r *rand.Rand) *Delta {
+	d := New(nil)
+	titles := []string{"Intro", "Setup", "Usage", "intro", "", "A b"}
+	for i := r.Intn(30); i >= 0; i-- {
+		d.Insert(titles[r.Intn(len(titles))], randomAttributes[r.Intn(3)])
+		if r.Intn(3) == 0 {
+			d.Insert("\n", Attributes{"header": float64(1 + r.Intn(3))})
+		} else {
+			d.Insert("\n", nil)
+		}
+	}
+	return d
+}
+
+func TestUpdateOutlineMatchesOutline(t *testing.T) {
+	r := rand.New(rand.NewSource(11))
+	for i := 0; i < 500; i++ {
+		doc := randomOutlineDocument(r)
+		headings := doc.Outline()
+		change := randomChange(r, doc.Length())
+		if r.Intn(2) == 0 {
+			// formatting lines is the interesting part of an outline
+			change = New(nil).Retain(r.Intn(doc.Length()), nil).Retain(1, Attributes{"header": float64(r.Intn(3))})
+		}
+		got, _ := UpdateOutline(headings, doc, change)
+		expected := doc.Compose(*change).Outline()
+		if len(got) == 0 && len(expected) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Fatalf("%d: incremental outline differs\ngot:      %+v\nexpected: %+v", i, got, expected)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package delta
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Sanitizer cleans deltas that come from untrusted clients before they are
+// stored or rendered into other users' browsers. It works on documents and
+// on change deltas alike. Use NewSanitizer to get the default settings
+type Sanitizer struct {
+	// Protocols lists the URL schemes allowed in links and embeds
+	Protocols []string
+	// AllowRelative allows URLs without a scheme, like /docs or #top
+	AllowRelative bool
+	// DataImages allows image embeds with data: URLs of raster images.
+	// SVG is never allowed, it can run scripts
+	DataImages bool
+	// URLAttributes are the attributes that hold a URL
+	URLAttributes []string
+	// URLEmbeds are the embeds whose value is a URL
+	URLEmbeds []string
+	// ColorAttributes are the attributes that hold a CSS color
+	ColorAttributes []string
+	// Schema is used to strip unknown attributes and embeds, see
+	// Delta.Conform. A nil Schema keeps all of them
+	Schema *Schema
+}
+
+// NewSanitizer returns a Sanitizer that allows http, https, mailto and tel
+// URLs and quill's default formats
+func NewSanitizer() *Sanitizer {
+	return &Sanitizer{
+		Protocols:       []string{"http", "https", "mailto", "tel"},
+		AllowRelative:   true,
+		URLAttributes:   []string{"link"},
+		URLEmbeds:       []string{"image", "video"},
+		ColorAttributes: []string{"color", "background"},
+		Schema:          DefaultSchema(),
+	}
+}
+
+var dataImageTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp", "image/bmp"}
+
+// Sanitize returns a clean copy of d and the list of problems it fixed.
+// Unsafe URLs are removed, along with their attribute or embed, and colors
+// are normalized to lowercase #rrggbb, or transparent
+func (s *Sanitizer) Sanitize(d *Delta) (*Delta, []Violation) {
+	var report []Violation
+	add := func(v Violation) {
+		report = append(report, v)
+	}
+	ret := New(nil)
+	index := 0
+	for i, op := range d.Ops {
+		pos := index
+		index += op.Length()
+		if op.InsertEmbed != nil && contains(s.URLEmbeds, op.InsertEmbed.Key) {
+			url, ok := op.InsertEmbed.Value.(string)
+			if !ok || !s.ValidURL(url, op.InsertEmbed.Key == "image") {
+				add(Violation{Index: pos, Op: i, Format: op.InsertEmbed.Key,
+					Reason: fmt.Sprintf("unsafe url %v", op.InsertEmbed.Value)})
+				continue
+			}
+		}
+		if op.Delete == nil && len(op.Attributes) > 0 {
+			op.Attributes = s.sanitizeAttributes(op.Attributes, pos, i, &report)
+		}
+		if s.Schema == nil {
+			ret.Push(op)
+		} else {
+			// one op at a time, so the schema reports the positions of d
+			s.Schema.conformOp(ret, op, pos, i, add)
+		}
+	}
+	return ret, report
+}
+
+func (s *Sanitizer) sanitizeAttributes(attrs Attributes, index, opIndex int, report *[]Violation) Attributes {
+	ret := attrs
+	for _, k := range attrs.Keys() {
+		v := attrs[k]
+		if v == nil {
+			// removing a format is always safe
+			continue
+		}
+		if contains(s.URLAttributes, k) {
+			if url, ok := v.(string); !ok || !s.ValidURL(url, false) {
+				*report = append(*report, Violation{Index: index, Op: opIndex, Format: k, Reason: fmt.Sprintf("unsafe url %v", v)})
+				ret = ret.Without(k)
+			}
+		} else if contains(s.ColorAttributes, k) {
+			str, _ := v.(string)
+			color, ok := NormalizeColor(str)
+			if !ok && strings.EqualFold(strings.TrimSpace(str), "transparent") {
+				// a valid color, NormalizeColor rejects it for lack of
+				// an #rrggbb form
+				color, ok = "transparent", true
+			}
+			if !ok {
+				*report = append(*report, Violation{Index: index, Op: opIndex, Format: k, Reason: fmt.Sprintf("invalid color %v", v)})
+				ret = ret.Without(k)
+			} else if color != v {
+				ret = ret.With(k, color)
+			}
+		}
+	}
+	return ret
+}
+
+// ValidURL tells you if url is safe to use in a link, or in an image when
+// image is true
+func (s *Sanitizer) ValidURL(url string, image bool) bool {
+	// browsers ignore whitespace and control characters in URLs, so
+	// "java\tscript:" is still javascript
+	cleaned := strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, url)
+	if cleaned == "" {
+		return false
+	}
+	colon := strings.IndexByte(cleaned, ':')
+	if colon < 0 || strings.IndexAny(cleaned[:colon], "/?#") >= 0 {
+		// a relative URL, but reject anything that could be an encoded
+		// scheme once a renderer decodes entities
+		return s.AllowRelative && !strings.Contains(strings.SplitN(cleaned, "/", 2)[0], "&")
+	}
+	scheme := strings.ToLower(cleaned[:colon])
+	for _, r := range scheme {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '+' || r == '-' || r == '.') {
+			return false
+		}
+	}
+	if scheme == "data" {
+		if !image || !s.DataImages {
+			return false
+		}
+		mime := strings.ToLower(cleaned[colon+1:])
+		for _, t := range dataImageTypes {
+			if strings.HasPrefix(mime, t+";") || strings.HasPrefix(mime, t+",") {
+				return true
+			}
+		}
+		return false
+	}
+	return contains(s.Protocols, scheme)
+}
+
+var namedColors = map[string]string{
+	"black": "#000000", "silver": "#c0c0c0", "gray": "#808080", "grey": "#808080",
+	"white": "#ffffff", "maroon": "#800000", "red": "#ff0000", "purple": "#800080",
+	"fuchsia": "#ff00ff", "green": "#008000", "lime": "#00ff00", "olive": "#808000",
+	"yellow": "#ffff00", "navy": "#000080", "blue": "#0000ff", "teal": "#008080",
+	"aqua": "#00ffff", "orange": "#ffa500",
+}
+
+// NormalizeColor turns a CSS color into lowercase #rrggbb. It understands
+// #rgb, #rrggbb, rgb(r, g, b) and the basic color names. Anything else is
+// rejected, which also keeps CSS injection out of style attributes.
+// transparent is rejected too, it has no #rrggbb form
+func NormalizeColor(color string) (string, bool) {
+	c := strings.ToLower(strings.TrimSpace(color))
+	if named, found := namedColors[c]; found {
+		return named, true
+	}
+	if strings.HasPrefix(c, "#") {
+		hex := c[1:]
+		for _, r := range hex {
+			if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'f') {
+				return "", false
+			}
+		}
+		switch len(hex) {
+		case 3:
+			return "#" + string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]}), true
+		case 6:
+			return c, true
+		}
+		return "", false
+	}
+	if strings.HasPrefix(c, "rgb(") && strings.HasSuffix(c, ")") {
+		parts := strings.Split(c[4:len(c)-1], ",")
+		if len(parts) != 3 {
+			return "", false
+		}
+		ret := "#"
+		for _, p := range parts {
+			n, err := strconv.Atoi(strings.TrimSpace(p))
+			if err != nil || n < 0 || n > 255 {
+				return "", false
+			}
+			ret += fmt.Sprintf("%02x", n)
+		}
+		return ret, true
+	}
+	return "", false
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package delta
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestSanitizeLinks(t *testing.T) {
+	d := New(nil).
+		Insert("ok", Attributes{"link": "https://quilljs.com"}).
+		Insert("js", Attributes{"link": "javascript:alert(1)", "bold": true}).
+		Insert("tab", Attributes{"link": " JaVa\tScript:alert(1)"}).
+		Insert("rel", Attributes{"link": "/docs#top"}).
+		Insert("entity", Attributes{"link": "javascript&colon;alert(1)"}).
+		Insert("\n", nil)
+	got, report := NewSanitizer().Sanitize(d)
+	expected := New(nil).
+		Insert("ok", Attributes{"link": "https://quilljs.com"}).
+		Insert("js", Attributes{"bold": true}).
+		Insert("tab", nil).
+		Insert("rel", Attributes{"link": "/docs#top"}).
+		Insert("entity\n", nil)
+	if !reflect.DeepEqual(got.Ops, expected.Ops) {
+		a, _ := json.Marshal(got)
+		t.Errorf("unexpected sanitized delta %s", a)
+	}
+	if len(report) != 3 || report[0].Index != 2 || report[1].Index != 4 || report[2].Index != 10 {
+		t.Errorf("unexpected report %v", report)
+	}
+}
+
+func TestSanitizeEmbeds(t *testing.T) {
+	d := New(nil).
+		InsertEmbed(Embed{Key: "image", Value: "data:image/svg+xml;base64,PHN2Zz4="}, nil).
+		InsertEmbed(Embed{Key: "image", Value: "https://example.com/a.png"}, nil).
+		InsertEmbed(Embed{Key: "image", Value: "data:image/png;base64,iVBORw0KGgo="}, nil).
+		InsertEmbed(Embed{Key: "video", Value: "vbscript:msgbox"}, nil).
+		Insert("x", Attributes{"evil": "1"}).
+		Insert("\n", nil)
+	s := NewSanitizer()
+	got, report := s.Sanitize(d)
+	expected := New(nil).
+		InsertEmbed(Embed{Key: "image", Value: "https://example.com/a.png"}, nil).
+		Insert("x\n", nil)
+	if !reflect.DeepEqual(got.Ops, expected.Ops) {
+		a, _ := json.Marshal(got)
+		t.Errorf("unexpected sanitized delta %s", a)
+	}
+	if len(report) != 4 || report[3].Format != "evil" || report[3].Index != 4 {
+		t.Errorf("unexpected report %v", report)
+	}
+
+	s.DataImages = true
+	got, _ = s.Sanitize(d)
+	if len(got.Ops) != 3 || got.Ops[1].InsertEmbed.Value != "data:image/png;base64,iVBORw0KGgo=" {
+		t.Errorf("expected the png data url to be kept, got %+v", got.Ops)
+	}
+}
+
+func TestSanitizeColorsAndChanges(t *testing.T) {
+	d := New(nil).
+		Retain(2, Attributes{"color": "#ABC", "background": "rgb(255, 0, 10)", "link": nil}).
+		Retain(1, Attributes{"color": "red;position:fixed"}).
+		Delete(1).
+		Insert("x", Attributes{"color": "Blue", "background": " Transparent"})
+	got, report := NewSanitizer().Sanitize(d)
+	expected := New(nil).
+		Retain(2, Attributes{"color": "#aabbcc", "background": "#ff000a", "link": nil}).
+		Retain(1, nil).
+		Delete(1).
+		Insert("x", Attributes{"color": "#0000ff", "background": "transparent"})
+	if !reflect.DeepEqual(got.Ops, expected.Ops) {
+		a, _ := json.Marshal(got)
+		t.Errorf("unexpected sanitized change %s", a)
+	}
+	if len(report) != 1 || report[0].Index != 2 {
+		t.Errorf("unexpected report %v", report)
+	}
+}
+
+func TestSanitizePositions(t *testing.T) {
+	two, three := 2, 3
+	d := New([]Op{
+		{Retain: &two, Attributes: Attributes{"link": "javascript:alert(1)"}},
+		{Delete: &three},
+		{InsertEmbed: &Embed{Key: "video", Value: "javascript:alert(1)"}},
+		{Insert: []rune("x"), Attributes: Attributes{"foo": true, "link": "javascript:alert(1)"}},
+	})
+	d.Retain(1, Attributes{"bar": true})
+	_, report := NewSanitizer().Sanitize(d)
+	expected := []Violation{
+		{Index: 0, Op: 0, Format: "link", Reason: "unsafe url javascript:alert(1)"},
+		{Index: 5, Op: 2, Format: "video", Reason: "unsafe url javascript:alert(1)"},
+		{Index: 6, Op: 3, Format: "link", Reason: "unsafe url javascript:alert(1)"},
+		{Index: 6, Op: 3, Format: "foo", Reason: "unknown format"},
+		{Index: 7, Op: 4, Format: "bar", Reason: "unknown format"},
+	}
+	if !reflect.DeepEqual(report, expected) {
+		t.Errorf("unexpected report %v", report)
+	}
+}
+
+func TestNormalizeColor(t *testing.T) {
+	tests := map[string]string{"#ABC": "#aabbcc", "rgb(0, 128, 255)": "#0080ff", " Navy": "#000080", "transparent": "", "#abcd": ""}
+	for color, expected := range tests {
+		if got, ok := NormalizeColor(color); got != expected || ok != (expected != "") {
+			t.Errorf("%q: expected %q, got %q, %v", color, expected, got, ok)
+		}
+	}
+}
+
+func TestValidURL(t *testing.T) {
+	s := NewSanitizer()
+	s.AllowRelative = false
+	cases := map[string]bool{
+		"https://quilljs.com":     true,
+		"mailto:me@example.com":   true,
+		"HTTP://EXAMPLE.COM":      true,
+		"ftp://example.com":       false,
+		"relative/path":           false,
+		"":                        false,
+		"javascript:alert(1)":     false,
+		"\x00javascript:alert(1)": false,
+	}
+	for url, valid := range cases {
+		if s.ValidURL(url, false) != valid {
+			t.Errorf("ValidURL(%q) expected %v", url, valid)
+		}
+	}
+}
@@ -0,0 +1,402 @@
+package delta
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Scope tells where a format can be used
+type Scope int
+
+const (
+	// ScopeInline formats apply to characters and embeds, like bold or link
+	ScopeInline Scope = iota + 1
+	// ScopeBlock formats apply to a whole line and live on its newline, like
+	// header or list
+	ScopeBlock
+	// ScopeEmbed describes an embed type, like image or video, the Format's
+	// Name is the embed key
+	ScopeEmbed
+)
+
+func (s Scope) String() string {
+	switch s {
+	case ScopeInline:
+		return "inline"
+	case ScopeBlock:
+		return "block"
+	case ScopeEmbed:
+		return "embed"
+	}
+	return "unknown"
+}
+
+// ValueType is the type of value a format accepts
+type ValueType int
+
+const (
+	// AnyValue accepts any value
+	AnyValue ValueType = iota
+	// BoolValue only accepts true, like quill does for bold
+	BoolValue
+	// StringValue accepts strings
+	StringValue
+	// NumberValue accepts numbers
+	NumberValue
+)
+
+// Format describes one format of a Schema
+type Format struct {
+	Name  string
+	Scope Scope
+	Type  ValueType
+	// Values, if present, lists all the allowed values
+	Values []interface{}
+	// Exclusive lists the formats that can't be used together with this
+	// one, like a header that is also a list item. When both show up, the
+	// one registered first wins
+	Exclusive []string
+	// Attributes lists the extra attributes an embed accepts, besides the
+	// inline formats, like width or alt for images
+	Attributes []string
+	// Normalize, if present, gets a chance to fix a value before it is
+	// validated, like turning the string "2" into the number 2
+	Normalize func(v interface{}) interface{}
+}
+
+// Schema is a registry of formats. The zero value has no formats, use
+// NewSchema or DefaultSchema
+type Schema struct {
+	formats map[string]*Format
+	order   map[string]int
+}
+
+// Violation is a problem Validate found in a delta. Index is the position
+// in the document where it happens, Op is the index of the op in Ops
+type Violation struct {
+	Index  int
+	Op     int
+	Format string
+	Reason string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("index %d (op %d): %s: %s", v.Index, v.Op, v.Format, v.Reason)
+}
+
+// NewSchema creates a Schema with the given formats
+func NewSchema(formats ...Format) *Schema {
+	s := &Schema{}
+	for _, f := range formats {
+		s.Register(f)
+	}
+	return s
+}
+
+// Register adds f to the schema, replacing any format with the same name
+func (s *Schema) Register(f Format) {
+	if s.formats == nil {
+		s.formats = make(map[string]*Format)
+		s.order = make(map[string]int)
+	}
+	if _, found := s.order[f.Name]; !found {
+		s.order[f.Name] = len(s.order)
+	}
+	s.formats[f.Name] = &f
+}
+
+// Lookup returns the format called name
+func (s *Schema) Lookup(name string) (Format, bool) {
+	f, found := s.formats[name]
+	if !found {
+		return Format{}, false
+	}
+	return *f, true
+}
+
+// DefaultSchema returns the formats quill supports out of the box
+func DefaultSchema() *Schema {
+	blocks := []string{"header", "list", "blockquote", "code-block"}
+	exclusive := func(name string) []string {
+		var ret []string
+		for _, b := range blocks {
+			if b != name {
+				ret = append(ret, b)
+			}
+		}
+		return ret
+	}
+	return NewSchema(
+		Format{Name: "bold", Scope: ScopeInline, Type: BoolValue},
+		Format{Name: "italic", Scope: ScopeInline, Type: BoolValue},
+		Format{Name: "underline", Scope: ScopeInline, Type: BoolValue},
+		Format{Name: "strike", Scope: ScopeInline, Type: BoolValue},
+		Format{Name: "code", Scope: ScopeInline, Type: BoolValue},
+		Format{Name: "link", Scope: ScopeInline, Type: StringValue},
+		Format{Name: "script", Scope: ScopeInline, Type: StringValue, Values: []interface{}{"sub", "super"}},
+		Format{Name: "color", Scope: ScopeInline, Type: StringValue},
+		Format{Name: "background", Scope: ScopeInline, Type: StringValue},
+		Format{Name: "font", Scope: ScopeInline, Type: StringValue},
+		Format{Name: "size", Scope: ScopeInline, Type: StringValue},
+		Format{Name: "header", Scope: ScopeBlock, Type: NumberValue,
+			Values: []interface{}{1.0, 2.0, 3.0, 4.0, 5.0, 6.0}, Exclusive: exclusive("header"), Normalize: numberFromString},
+		Format{Name: "list", Scope: ScopeBlock, Type: StringValue,
+			Values: []interface{}{"ordered", "bullet", "checked", "unchecked"}, Exclusive: exclusive("list")},
+		Format{Name: "blockquote", Scope: ScopeBlock, Type: BoolValue, Exclusive: exclusive("blockquote")},
+		Format{Name: "code-block", Scope: ScopeBlock, Exclusive: exclusive("code-block")},
+		Format{Name: "align", Scope: ScopeBlock, Type: StringValue, Values: []interface{}{"center", "right", "justify"}},
+		Format{Name: "direction", Scope: ScopeBlock, Type: StringValue, Values: []interface{}{"rtl"}},
+		Format{Name: "indent", Scope: ScopeBlock, Type: NumberValue,
+			Values: []interface{}{1.0, 2.0, 3.0, 4.0, 5.0, 6.0, 7.0, 8.0}, Normalize: numberFromString},
+		Format{Name: "image", Scope: ScopeEmbed, Type: StringValue, Attributes: []string{"alt", "width", "height"}},
+		Format{Name: "video", Scope: ScopeEmbed, Type: StringValue, Attributes: []string{"width", "height"}},
+		Format{Name: "formula", Scope: ScopeEmbed, Type: StringValue},
+	)
+}
+
+func numberFromString(v interface{}) interface{} {
+	if s, ok := v.(string); ok {
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	}
+	return v
+}
+
+// Validate checks d against schema and returns every violation found, in
+// order. Insert ops are checked as a document: block formats must be on
+// newlines and inline formats must not. Retains can't be checked that way
+// without the document they apply to, so only their values are checked
+func (d *Delta) Validate(schema *Schema) []Violation {
+	var ret []Violation
+	schema.conform(d, func(v Violation) {
+		ret = append(ret, v)
+	})
+	return ret
+}
+
+// Conform returns a copy of d without the attributes and embeds that
+// violate schema. Block formats are only kept on the newlines of an insert
+// and dropped from its text, exclusive formats keep the one registered
+// first and values are normalized when the format knows how
+func (d *Delta) Conform(schema *Schema) *Delta {
+	return schema.conform(d, func(Violation) {})
+}
+
+func (s *Schema) conform(d *Delta, report func(Violation)) *Delta {
+	ret := New(nil)
+	index := 0
+	for i, op := range d.Ops {
+		s.conformOp(ret, op, index, i, report)
+		index += op.Length()
+	}
+	return ret
+}
+
+// conformOp pushes what is valid of op to ret. index and opIndex are the
+// position and index of op in the delta it comes from, violations always
+// use them
+func (s *Schema) conformOp(ret *Delta, op Op, index, opIndex int, report func(Violation)) {
+	switch {
+	case op.Delete != nil:
+		ret.Push(op)
+	case op.Retain != nil:
+		op.Attributes = s.conformAttributes(op.Attributes, index, opIndex, 0, true, report)
+		ret.Push(op)
+	case op.InsertEmbed != nil:
+		f, found := s.formats[op.InsertEmbed.Key]
+		if !found || f.Scope != ScopeEmbed {
+			report(Violation{Index: index, Op: opIndex, Format: op.InsertEmbed.Key, Reason: "unknown embed"})
+			return
+		}
+		if f.Normalize != nil {
+			op.InsertEmbed = &Embed{Key: op.InsertEmbed.Key, Value: f.Normalize(op.InsertEmbed.Value)}
+		}
+		if !f.accepts(op.InsertEmbed.Value) {
+			report(Violation{Index: index, Op: opIndex, Format: f.Name, Reason: "invalid embed value"})
+			return
+		}
+		op.Attributes = s.conformEmbedAttributes(f, op.Attributes, index, opIndex, report)
+		ret.Push(op)
+	case op.Insert != nil:
+		// split the text in runs of newlines and other characters, each
+		// gets the formats of its scope
+		start := 0
+		for start < len(op.Insert) {
+			newline := op.Insert[start] == '\n'
+			end := start + 1
+			for end < len(op.Insert) && (op.Insert[end] == '\n') == newline {
+				end++
+			}
+			scope := ScopeInline
+			if newline {
+				scope = ScopeBlock
+			}
+			ret.Push(Op{
+				Insert:     op.Insert[start:end],
+				Attributes: s.conformAttributes(op.Attributes, index+start, opIndex, scope, false, report),
+			})
+			start = end
+		}
+	}
+}
+
+// conformAttributes returns the valid attributes for the scope. A scope of 0
+// accepts both inline and block formats, and retains can use nil to remove
+// a format. Retains can also change the attributes of embeds, like the width
+// of an image
+func (s *Schema) conformAttributes(attrs Attributes, index, opIndex int, scope Scope, retain bool, report func(Violation)) Attributes {
+	if len(attrs) == 0 {
+		return attrs
+	}
+	ret := make(Attributes, len(attrs))
+	for _, k := range s.sortedKeys(attrs) {
+		v := attrs[k]
+		f, found := s.formats[k]
+		if !found && retain && s.embedAttribute(k) {
+			ret[k] = v
+			continue
+		}
+		if !found || f.Scope == ScopeEmbed {
+			report(Violation{Index: index, Op: opIndex, Format: k, Reason: "unknown format"})
+			continue
+		}
+		if scope != 0 && f.Scope != scope {
+			// quill puts inline formats on newlines all the time, for
+			// example when a whole bold paragraph is pasted, they are
+			// harmless and we just drop them
+			if f.Scope == ScopeBlock {
+				report(Violation{Index: index, Op: opIndex, Format: k, Reason: "block format on text"})
+			}
+			continue
+		}
+		if v == nil {
+			if !retain {
+				report(Violation{Index: index, Op: opIndex, Format: k, Reason: "null value on insert"})
+				continue
+			}
+			ret[k] = nil
+			continue
+		}
+		if f.Normalize != nil {
+			v = f.Normalize(v)
+		}
+		if !f.accepts(v) {
+			report(Violation{Index: index, Op: opIndex, Format: k, Reason: fmt.Sprintf("invalid value %v", v)})
+			continue
+		}
+		if winner := s.conflict(ret, f); winner != "" {
+			report(Violation{Index: index, Op: opIndex, Format: k, Reason: "exclusive with " + winner})
+			continue
+		}
+		ret[k] = v
+	}
+	if len(ret) == 0 {
+		return nil
+	}
+	return ret
+}
+
+func (s *Schema) conformEmbedAttributes(embed *Format, attrs Attributes, index, opIndex int, report func(Violation)) Attributes {
+	if len(attrs) == 0 {
+		return attrs
+	}
+	ret := make(Attributes, len(attrs))
+	extra := make(Attributes)
+	for _, k := range attrs.Keys() {
+		allowed := false
+		for _, a := range embed.Attributes {
+			allowed = allowed || a == k
+		}
+		if allowed {
+			extra[k] = attrs[k]
+		} else {
+			ret[k] = attrs[k]
+		}
+	}
+	ret = s.conformAttributes(ret, index, opIndex, ScopeInline, false, report)
+	for k, v := range extra {
+		ret = ret.With(k, v)
+	}
+	if len(ret) == 0 {
+		return nil
+	}
+	return ret
+}
+
+// embedAttribute tells if an embed of the schema accepts the attribute k
+func (s *Schema) embedAttribute(k string) bool {
+	for _, f := range s.formats {
+		if f.Scope == ScopeEmbed && contains(f.Attributes, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// conflict returns the name of a format in attrs that is exclusive with f
+func (s *Schema) conflict(attrs Attributes, f *Format) string {
+	for k := range attrs {
+		for _, e := range f.Exclusive {
+			if e == k {
+				return k
+			}
+		}
+		if other, found := s.formats[k]; found {
+			for _, e := range other.Exclusive {
+				if e == f.Name {
+					return k
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// sortedKeys returns the keys of attrs in registration order, unknown keys
+// last
+func (s *Schema) sortedKeys(attrs Attributes) []string {
+	keys := attrs.Keys()
+	sort.SliceStable(keys, func(i, j int) bool {
+		oi, fi := s.order[keys[i]]
+		oj, fj := s.order[keys[j]]
+		if fi != fj {
+			return fi
+		}
+		return oi < oj
+	})
+	return keys
+}
+
+func (f *Format) accepts(v interface{}) bool {
+	switch f.Type {
+	case BoolValue:
+		if b, ok := v.(bool); !ok || !b {
+			return false
+		}
+	case StringValue:
+		if _, ok := v.(string); !ok {
+			return false
+		}
+	case NumberValue:
+		if _, ok := toFloat(v); !ok {
+			return false
+		}
+	}
+	if len(f.Values) == 0 {
+		return true
+	}
+	for _, allowed := range f.Values {
+		if attrValueEqual(allowed, v) {
+			return true
+		}
+		// numbers may be float64 or int depending on where they come from
+		a, aok := toFloat(allowed)
+		b, bok := toFloat(v)
+		if aok && bok && a == b {
+			return true
+		}
+	}
+	return false
+}
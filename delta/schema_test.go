@@ -0,0 +1,126 @@
+package delta
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestConformKeepsBlockFormatsOnNewlines(t *testing.T) {
+	d := New(nil).Insert("Title\nBody", Attributes{"header": 1, "bold": true}).Insert("\n", nil)
+	got := d.Conform(DefaultSchema())
+	expected := New(nil).
+		Insert("Title", Attributes{"bold": true}).
+		Insert("\n", Attributes{"header": 1}).
+		Insert("Body", Attributes{"bold": true}).
+		Insert("\n", nil)
+	if !reflect.DeepEqual(got.Ops, expected.Ops) {
+		a, _ := json.Marshal(got)
+		t.Errorf("unexpected conformed delta %s", a)
+	}
+	violations := d.Validate(DefaultSchema())
+	if len(violations) != 2 || violations[0].Index != 0 || violations[1].Index != 6 ||
+		violations[0].Format != "header" {
+		t.Errorf("unexpected violations %v", violations)
+	}
+}
+
+func TestConformValues(t *testing.T) {
+	d := New(nil).
+		Insert("a", Attributes{"bold": "yes", "script": "super", "unknown": true}).
+		Insert("\n", Attributes{"header": 9}).
+		Insert("\n", Attributes{"header": "2", "list": "bullet"}).
+		Insert("b", Attributes{"italic": nil}).
+		Insert("\n", Attributes{"list": "ordered", "indent": 1})
+	got := d.Conform(DefaultSchema())
+	expected := New(nil).
+		Insert("a", Attributes{"script": "super"}).
+		Insert("\n", nil).
+		Insert("\n", Attributes{"header": 2.0}).
+		Insert("b", nil).
+		Insert("\n", Attributes{"list": "ordered", "indent": 1})
+	if !reflect.DeepEqual(got.Ops, expected.Ops) {
+		a, _ := json.Marshal(got)
+		b, _ := json.Marshal(expected)
+		t.Errorf("unexpected conformed delta\n%s\n%s", a, b)
+	}
+	reasons := map[string]bool{}
+	for _, v := range d.Validate(DefaultSchema()) {
+		reasons[v.Format] = true
+	}
+	for _, f := range []string{"bold", "unknown", "header", "list", "italic"} {
+		if !reasons[f] {
+			t.Errorf("expected a violation for %s, got %v", f, reasons)
+		}
+	}
+}
+
+func TestConformEmbeds(t *testing.T) {
+	d := New(nil).
+		InsertEmbed(Embed{Key: "image", Value: "a.png"}, Attributes{"width": "100", "link": "https://quilljs.com", "header": 1}).
+		InsertEmbed(Embed{Key: "tweet", Value: "123"}, nil).
+		InsertEmbed(Embed{Key: "video", Value: 3.0}, nil).
+		Insert("\n", nil)
+	got := d.Conform(DefaultSchema())
+	expected := New(nil).
+		InsertEmbed(Embed{Key: "image", Value: "a.png"}, Attributes{"width": "100", "link": "https://quilljs.com"}).
+		Insert("\n", nil)
+	if !reflect.DeepEqual(got.Ops, expected.Ops) {
+		a, _ := json.Marshal(got)
+		t.Errorf("unexpected conformed delta %s", a)
+	}
+	violations := d.Validate(DefaultSchema())
+	if len(violations) != 3 || violations[1].Index != 1 || violations[2].Index != 2 {
+		t.Errorf("unexpected violations %v", violations)
+	}
+}
+
+func TestConformEmbedAttributesOnRetain(t *testing.T) {
+	d := New(nil).Retain(5, nil).Retain(1, Attributes{"width": "200", "alt": nil, "foo": 1})
+	expected := New(nil).Retain(5, nil).Retain(1, Attributes{"width": "200", "alt": nil})
+	if got := d.Conform(DefaultSchema()); !reflect.DeepEqual(got.Ops, expected.Ops) {
+		a, _ := json.Marshal(got)
+		t.Errorf("unexpected conformed change %s", a)
+	}
+	if got, _ := NewSanitizer().Sanitize(d); !reflect.DeepEqual(got.Ops, expected.Ops) {
+		a, _ := json.Marshal(got)
+		t.Errorf("unexpected sanitized change %s", a)
+	}
+	if violations := d.Validate(DefaultSchema()); len(violations) != 1 || violations[0].Format != "foo" {
+		t.Errorf("unexpected violations %v", violations)
+	}
+}
+
+func TestConformChange(t *testing.T) {
+	d := New(nil).Retain(3, Attributes{"bold": nil, "header": 1, "foo": 1}).Delete(2).Insert("x", Attributes{"italic": true})
+	got := d.Conform(DefaultSchema())
+	expected := New(nil).Retain(3, Attributes{"bold": nil, "header": 1}).Delete(2).Insert("x", Attributes{"italic": true})
+	if !reflect.DeepEqual(got.Ops, expected.Ops) {
+		a, _ := json.Marshal(got)
+		t.Errorf("unexpected conformed change %s", a)
+	}
+	// Push would move the insert before the delete
+	two, three := 2, 3
+	d = New([]Op{{Retain: &two}, {Delete: &three}, {Insert: []rune("x"), Attributes: Attributes{"foo": true}}})
+	d.Retain(1, Attributes{"bar": true})
+	violations := d.Validate(DefaultSchema())
+	if len(violations) != 2 || violations[0].Index != 5 || violations[0].Op != 2 ||
+		violations[1].Index != 6 || violations[1].Op != 3 {
+		t.Errorf("unexpected violations %v", violations)
+	}
+}
+
+func TestCustomSchema(t *testing.T) {
+	s := NewSchema(Format{Name: "mention", Scope: ScopeInline, Type: StringValue})
+	s.Register(Format{Name: "align", Scope: ScopeBlock, Values: []interface{}{"center"}})
+	if _, found := s.Lookup("mention"); !found {
+		t.Errorf("expected to find mention")
+	}
+	d := New(nil).Insert("@diego", Attributes{"mention": "diego", "bold": true}).Insert("\n", Attributes{"align": "right"})
+	if v := d.Validate(s); len(v) != 2 {
+		t.Errorf("expected bold and align violations, got %v", v)
+	}
+	if v := New(nil).Insert("ok\n", nil).Validate(s); len(v) != 0 {
+		t.Errorf("expected no violations, got %v", v)
+	}
+}
@@ -0,0 +1,313 @@
+package delta
+
+import (
+	"time"
+	"unicode"
+)
+
+// WordsPerMinute is the reading speed used to estimate ReadingTime
+const WordsPerMinute = 200
+
+// Stats are the numbers we show in the editor's footer and use for quotas
+type Stats struct {
+	// Characters counts the runes of the text, newlines included and
+	// embeds excluded. CharactersUTF16 counts UTF-16 code units instead,
+	// which is what String.length gives you in the browser
+	Characters      int
+	CharactersUTF16 int
+	// Words uses Unicode letters, digits and marks. Each Chinese or
+	// Japanese character counts as a word, like word processors do
+	Words int
+	// Lines counts every line, Paragraphs only the ones that aren't blank
+	Lines      int
+	Paragraphs int
+	// Embeds counts the embeds by key, like image or video
+	Embeds map[string]int
+	// Links counts the runs of text (and embeds) with the same link
+	Links       int
+	ReadingTime time.Duration
+}
+
+// Stats computes the statistics of a document, in a single pass over its
+// ops
+func (d *Delta) Stats() Stats {
+	var c statsCounter
+	for _, op := range d.Ops {
+		c.add(op)
+	}
+	return c.finish()
+}
+
+// Update returns the stats of doc.Compose(change), given s, the stats of
+// doc. Only the lines the change touches are counted again, so typing in a
+// big document stays cheap. Links can span lines, the runs that go on past
+// those lines are only counted once
+func (s Stats) Update(doc *Delta, change *Delta) Stats {
+	from, to, ok := touchedLines(doc, change)
+	if !ok {
+		return s
+	}
+	before := doc.Slice(from, to)
+	after := before.Compose(*sliceChange(change, from, to, doc.Length()))
+	ret := s.sub(before.Stats()).addStats(after.Stats())
+	prev, next := linkAt(doc, from-1), linkAt(doc, to)
+	ret.Links += linkJoins(prev, before, next) - linkJoins(prev, after, next)
+	return ret
+}
+
+// linkAt returns the link of the character at index, nil outside of doc
+func linkAt(doc *Delta, index int) interface{} {
+	if index < 0 {
+		return nil
+	}
+	ops := doc.Slice(index, index+1).Ops
+	if len(ops) == 0 {
+		return nil
+	}
+	return ops[0].Attributes["link"]
+}
+
+// linkJoins counts the link runs of lines that go on in the text around
+// them, prev and next being the links before and after. They were counted
+// on both sides of the join
+func linkJoins(prev interface{}, lines *Delta, next interface{}) int {
+	links := []interface{}{prev}
+	if n := len(lines.Ops); n > 0 {
+		links = append(links, lines.Ops[0].Attributes["link"], lines.Ops[n-1].Attributes["link"])
+	}
+	links = append(links, next)
+	joins := 0
+	for i := 0; i < len(links); i += 2 {
+		if links[i] != nil && attrValueEqual(links[i], links[i+1]) {
+			joins++
+		}
+	}
+	return joins
+}
+
+type statsCounter struct {
+	s           Stats
+	inWord      bool
+	joiner      bool
+	lineContent bool
+	lineText    bool
+	link        interface{}
+}
+
+func (c *statsCounter) add(op Op) {
+	if op.InsertEmbed == nil && op.Insert == nil {
+		return
+	}
+	if link := op.Attributes["link"]; link != nil {
+		if c.link == nil || !attrValueEqual(link, c.link) {
+			c.s.Links++
+		}
+		c.link = link
+	} else {
+		c.link = nil
+	}
+	if op.InsertEmbed != nil {
+		if c.s.Embeds == nil {
+			c.s.Embeds = make(map[string]int)
+		}
+		c.s.Embeds[op.InsertEmbed.Key]++
+		c.inWord, c.joiner = false, false
+		c.lineContent, c.lineText = true, true
+		return
+	}
+	for _, r := range op.Insert {
+		c.s.Characters++
+		c.s.CharactersUTF16++
+		if r >= 0x10000 {
+			c.s.CharactersUTF16++
+		}
+		if r == '\n' {
+			c.endLine()
+			continue
+		}
+		c.lineText = true
+		if !unicode.IsSpace(r) {
+			c.lineContent = true
+		}
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana):
+			c.s.Words++
+			c.inWord, c.joiner = false, false
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) || r == '_':
+			if !c.inWord {
+				c.s.Words++
+			}
+			c.inWord, c.joiner = true, false
+		case c.inWord && !c.joiner && (r == '\'' || r == '’' || r == '-'):
+			// don't, well-known: keep the word going if a letter follows
+			c.joiner = true
+		default:
+			c.inWord, c.joiner = false, false
+		}
+	}
+}
+
+func (c *statsCounter) endLine() {
+	c.s.Lines++
+	if c.lineContent {
+		c.s.Paragraphs++
+	}
+	c.inWord, c.joiner = false, false
+	c.lineContent, c.lineText = false, false
+}
+
+func (c *statsCounter) finish() Stats {
+	if c.lineText {
+		c.endLine()
+	}
+	c.s.ReadingTime = readingTime(c.s.Words)
+	return c.s
+}
+
+func readingTime(words int) time.Duration {
+	return (time.Duration(words) * time.Minute / WordsPerMinute).Round(time.Second)
+}
+
+func (s Stats) sub(o Stats) Stats {
+	return s.combine(o, -1)
+}
+
+func (s Stats) addStats(o Stats) Stats {
+	return s.combine(o, 1)
+}
+
+func (s Stats) combine(o Stats, sign int) Stats {
+	ret := s
+	ret.Characters += sign * o.Characters
+	ret.CharactersUTF16 += sign * o.CharactersUTF16
+	ret.Words += sign * o.Words
+	ret.Lines += sign * o.Lines
+	ret.Paragraphs += sign * o.Paragraphs
+	ret.Links += sign * o.Links
+	ret.Embeds = make(map[string]int)
+	for k, v := range s.Embeds {
+		ret.Embeds[k] = v
+	}
+	for k, v := range o.Embeds {
+		ret.Embeds[k] += sign * v
+		if ret.Embeds[k] == 0 {
+			delete(ret.Embeds, k)
+		}
+	}
+	if len(ret.Embeds) == 0 {
+		ret.Embeds = nil
+	}
+	ret.ReadingTime = readingTime(ret.Words)
+	return ret
+}
+
+// touchedLines returns the range of whole lines of doc that change
+// modifies. ok is false when the change does nothing
+func touchedLines(doc *Delta, change *Delta) (from, to int, ok bool) {
+	length := doc.Length()
+	from, to = -1, -1
+	touch := func(start, end int) {
+		if from < 0 || start < from {
+			from = start
+		}
+		if end > to {
+			to = end
+		}
+	}
+	pos := 0
+	for _, op := range change.Ops {
+		switch {
+		case op.Retain != nil:
+			if op.Attributes != nil {
+				touch(pos, pos+*op.Retain)
+			}
+			pos += *op.Retain
+		case op.Delete != nil:
+			touch(pos, pos+*op.Delete)
+			pos += *op.Delete
+		default:
+			// an insert changes the line it lands on
+			touch(pos, pos+1)
+		}
+	}
+	if from < 0 {
+		return 0, 0, false
+	}
+	if to > length {
+		to = length
+	}
+	// from goes back to the start of its line
+	text := func(start, end int) []rune {
+		var ret []rune
+		for _, op := range doc.Slice(start, end).Ops {
+			if op.Insert != nil {
+				ret = append(ret, op.Insert...)
+			} else {
+				ret = append(ret, 0)
+			}
+		}
+		return ret
+	}
+	for size := 64; from > 0; size *= 2 {
+		start := from - size
+		if start < 0 {
+			start = 0
+		}
+		chunk := text(start, from)
+		i := len(chunk) - 1
+		for ; i >= 0 && chunk[i] != '\n'; i-- {
+		}
+		if i >= 0 {
+			from = start + i + 1
+			break
+		}
+		from = start
+	}
+	// to goes to the end of the line it is on, even when the last touched
+	// character is a newline, a delete may have joined the two lines
+	for size := 64; to < length; size *= 2 {
+		end := to + size
+		chunk := text(to, end)
+		i := 0
+		for ; i < len(chunk) && chunk[i] != '\n'; i++ {
+		}
+		if i < len(chunk) {
+			return from, to + i + 1, true
+		}
+		to += len(chunk)
+	}
+	return from, length, true
+}
+
+// sliceChange returns the part of change that applies between from and to
+// of the document, as a change of that slice. Inserts at to are only kept
+// when to is the end of the document
+func sliceChange(change *Delta, from, to, length int) *Delta {
+	ret := New(nil)
+	pos := 0
+	for _, op := range change.Ops {
+		if op.Insert != nil || op.InsertEmbed != nil {
+			if pos >= from && (pos < to || to == length) {
+				ret.Push(op)
+			}
+			continue
+		}
+		n := op.Length()
+		start, end := pos, pos+n
+		if start < from {
+			start = from
+		}
+		if end > to {
+			end = to
+		}
+		if start < end {
+			if op.Delete != nil {
+				ret.Delete(end - start)
+			} else {
+				ret.Retain(end-start, op.Attributes)
+			}
+		}
+		pos += n
+	}
+	return ret
+}
@@ -0,0 +1,96 @@
+package delta
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	d := New(nil).
+		Insert("Hello wor", nil).
+		Insert("ld", Attributes{"bold": true}).
+		Insert(", don't well-known 3.14\n", nil).
+		Insert("\n", nil).
+		Insert("   \n", nil).
+		Insert("日本語 text ", nil).
+		Insert("a link", Attributes{"link": "https://quilljs.com"}).
+		InsertEmbed(Embed{Key: "image", Value: "a.png"}, Attributes{"link": "https://quilljs.com"}).
+		Insert(" other", Attributes{"link": "https://example.com"}).
+		Insert("😀\n", nil).
+		InsertEmbed(Embed{Key: "image", Value: "b.png"}, nil).
+		InsertEmbed(Embed{Key: "video", Value: "c.mp4"}, nil).
+		Insert("\n", nil)
+	s := d.Stats()
+	expected := Stats{
+		Characters:      64,
+		CharactersUTF16: 65,
+		// hello world don't well-known 3 14 日 本 語 text a link other
+		Words:       13,
+		Lines:       5,
+		Paragraphs:  3,
+		Embeds:      map[string]int{"image": 2, "video": 1},
+		Links:       2,
+		ReadingTime: 4 * time.Second,
+	}
+	if !reflect.DeepEqual(s, expected) {
+		t.Errorf("unexpected stats\ngot:      %+v\nexpected: %+v", s, expected)
+	}
+}
+
+func TestStatsUnterminatedLine(t *testing.T) {
+	s := New(nil).Insert("one\ntwo", nil).Stats()
+	if s.Lines != 2 || s.Paragraphs != 2 || s.Words != 2 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+	if s := New(nil).Stats(); s.Lines != 0 || s.Embeds != nil {
+		t.Errorf("unexpected stats for an empty document %+v", s)
+	}
+}
+
+func TestStatsUpdate(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	for i := 0; i < 300; i++ {
+		doc := randomDocument(r)
+		s := doc.Stats()
+		for j := 0; j < 4; j++ {
+			change := randomChange(r, doc.Length())
+			s = s.Update(doc, change)
+			doc = doc.Compose(*change)
+			if expected := doc.Stats(); !reflect.DeepEqual(s, expected) {
+				t.Fatalf("%d/%d: incremental stats differ\ngot:      %+v\nexpected: %+v", i, j, s, expected)
+			}
+		}
+	}
+}
+
+func TestStatsUpdateTyping(t *testing.T) {
+	doc := New(nil).Insert("Hello\nWorld\n", nil)
+	s := doc.Stats()
+	change := New(nil).Retain(5, nil).Insert(" there", nil)
+	s = s.Update(doc, change)
+	if s.Words != 3 || s.Characters != 18 {
+		t.Errorf("unexpected stats after typing %+v", s)
+	}
+	if same := s.Update(doc.Compose(*change), New(nil).Retain(3, nil)); !reflect.DeepEqual(same, s) {
+		t.Errorf("a retain should not change the stats")
+	}
+}
+
+func TestStatsUpdateLinkAcrossLines(t *testing.T) {
+	link := Attributes{"link": "https://example.com"}
+	doc := New(nil).Insert("one\ntwo\nthree", link).Insert("\n", nil)
+	s := doc.Stats()
+	for _, change := range []*Delta{
+		New(nil).Retain(5, nil).Insert("x", link),
+		New(nil).Retain(5, nil).Insert("x", nil),
+		New(nil).Retain(4, Attributes{"link": nil}),
+		New(nil).Retain(3, nil).Delete(1),
+	} {
+		got := s.Update(doc, change)
+		if expected := doc.Compose(*change).Stats(); got.Links != expected.Links {
+			t.Errorf("%v: expected %d links, got %d", change.Ops, expected.Links, got.Links)
+		}
+	}
+}
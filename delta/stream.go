@@ -0,0 +1,158 @@
+package delta
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// OpsReader decodes the ops of a JSON delta one at a time, without loading
+// the whole document in memory. It works like Iterator:
+//
+//	r := NewOpsReader(file)
+//	for r.HasNext() {
+//		op, err := r.Next()
+//		...
+//	}
+//	if err := r.Err(); err != nil { ... }
+//
+// The input can be a delta object, {"ops": [...]}, or a bare list of ops.
+type OpsReader struct {
+	dec     *json.Decoder
+	started bool
+	done    bool
+	wrapped bool
+	err     error
+}
+
+// NewOpsReader returns an OpsReader reading from r
+func NewOpsReader(r io.Reader) *OpsReader {
+	return &OpsReader{dec: json.NewDecoder(r)}
+}
+
+// DecodeOps calls fn for every op in the JSON delta read from r, stopping at
+// the first error, either decoding or returned by fn
+func DecodeOps(r io.Reader, fn func(Op) error) error {
+	reader := NewOpsReader(r)
+	for reader.HasNext() {
+		op, err := reader.Next()
+		if err != nil {
+			return err
+		}
+		if err := fn(op); err != nil {
+			return err
+		}
+	}
+	return reader.Err()
+}
+
+// HasNext returns true if there are more ops to read. It returns false at the
+// end of the input and when we found an error, see Err
+func (r *OpsReader) HasNext() bool {
+	if r.err != nil || r.done {
+		return false
+	}
+	if !r.started {
+		r.started = true
+		if r.err = r.start(); r.err != nil || r.done {
+			return false
+		}
+	}
+	if r.dec.More() {
+		return true
+	}
+	r.err = r.finish()
+	r.done = true
+	return false
+}
+
+// Next decodes the next op. It returns io.EOF after the last one
+func (r *OpsReader) Next() (Op, error) {
+	var op Op
+	if !r.HasNext() {
+		if r.err != nil {
+			return op, r.err
+		}
+		return op, io.EOF
+	}
+	if err := r.dec.Decode(&op); err != nil {
+		r.err = err
+		return Op{}, err
+	}
+	return op, nil
+}
+
+// Err returns the first error found while reading, if any
+func (r *OpsReader) Err() error {
+	return r.err
+}
+
+// start moves the decoder to the first op
+func (r *OpsReader) start() error {
+	tok, err := r.dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == json.Delim('[') {
+		return nil
+	}
+	if tok != json.Delim('{') {
+		return fmt.Errorf("expected a delta, found %v", tok)
+	}
+	r.wrapped = true
+	for r.dec.More() {
+		key, err := r.dec.Token()
+		if err != nil {
+			return err
+		}
+		if key != "ops" {
+			if err := r.skip(); err != nil {
+				return err
+			}
+			continue
+		}
+		tok, err := r.dec.Token()
+		if err != nil {
+			return err
+		}
+		if tok == nil {
+			// "ops": null is an empty delta
+			continue
+		}
+		if tok != json.Delim('[') {
+			return errors.New("ops should be a list")
+		}
+		return nil
+	}
+	// we never found the ops, close the object
+	r.done = true
+	_, err = r.dec.Token()
+	return err
+}
+
+// finish consumes the end of the ops list and, for delta objects, the keys
+// after it
+func (r *OpsReader) finish() error {
+	if _, err := r.dec.Token(); err != nil {
+		return err
+	}
+	if !r.wrapped {
+		return nil
+	}
+	for r.dec.More() {
+		if _, err := r.dec.Token(); err != nil {
+			return err
+		}
+		if err := r.skip(); err != nil {
+			return err
+		}
+	}
+	_, err := r.dec.Token()
+	return err
+}
+
+func (r *OpsReader) skip() error {
+	var raw json.RawMessage
+	return r.dec.Decode(&raw)
+}
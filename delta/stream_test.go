@@ -0,0 +1,107 @@
+package delta
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOpsReaderMatchesFromJSON(t *testing.T) {
+	d := New(nil).Insert("Hello", map[string]interface{}{"bold": true}).
+		InsertEmbed(Embed{Key: "image", Value: "a.png"}, nil).
+		Insert(" World\n", nil).Retain(3, nil).Delete(2)
+	b, _ := json.Marshal(d)
+	expected, _ := FromJSON(b)
+
+	var got []Op
+	r := NewOpsReader(bytes.NewReader(b))
+	for r.HasNext() {
+		op, err := r.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, op)
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, expected.Ops) {
+		t.Errorf("expected %+v but got %+v\n", expected.Ops, got)
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF after the last op, got %v", err)
+	}
+}
+
+func TestOpsReaderInputs(t *testing.T) {
+	inputs := map[string]int{
+		`{"ops":[{"insert":"a"},{"retain":1}]}`:                 2,
+		`[{"insert":"a"},{"delete":1}]`:                         2,
+		`{"version":3,"ops":[{"insert":"a"}],"meta":{"x":[1]}}`: 1,
+		`{"ops":null}`: 0,
+		`{}`:           0,
+		`{"ops":[]}`:   0,
+	}
+	for in, count := range inputs {
+		n := 0
+		err := DecodeOps(strings.NewReader(in), func(op Op) error {
+			n++
+			return nil
+		})
+		if err != nil || n != count {
+			t.Errorf("%s: expected %d ops, got %d, %v", in, count, n, err)
+		}
+	}
+}
+
+func TestOpsReaderErrors(t *testing.T) {
+	inputs := []string{
+		``,
+		`"ops"`,
+		`{"ops":{"insert":"a"}}`,
+		`{"ops":[{"insert":{"a":1,"b":2}}]}`,
+		`{"ops":[{"insert":"a"}`,
+	}
+	for _, in := range inputs {
+		err := DecodeOps(strings.NewReader(in), func(op Op) error { return nil })
+		if err == nil {
+			t.Errorf("%s: expected an error", in)
+		}
+	}
+}
+
+func TestDecodeOpsStopsOnCallbackError(t *testing.T) {
+	stop := errors.New("stop")
+	n := 0
+	err := DecodeOps(strings.NewReader(`[{"insert":"a"},{"insert":"b"}]`), func(op Op) error {
+		n++
+		return stop
+	})
+	if err != stop || n != 1 {
+		t.Errorf("expected to stop after the first op, got %d, %v", n, err)
+	}
+}
+
+func TestDecodeOpsLargeDocument(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString(`{"ops":[`)
+	for i := 0; i < 10000; i++ {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString(`{"insert":"line of text\n","attributes":{"bold":true}}`)
+	}
+	buf.WriteString(`]}`)
+	length := 0
+	err := DecodeOps(&buf, func(op Op) error {
+		length += op.Length()
+		return nil
+	})
+	if err != nil || length != 10000*len("line of text\n") {
+		t.Errorf("unexpected length %d, %v", length, err)
+	}
+}
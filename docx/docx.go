@@ -0,0 +1,34 @@
+// Package docx exports Quill documents, insert only deltas, as Word files
+// (Office Open XML), and imports Word files back into deltas. It only uses
+// the standard library: the package is a zip file with the main document,
+// the styles, the list numbering and the relationships to links and images.
+package docx
+
+const (
+	nsW   = "http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+	nsR   = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+	nsWP  = "http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing"
+	nsA   = "http://schemas.openxmlformats.org/drawingml/2006/main"
+	nsPic = "http://schemas.openxmlformats.org/drawingml/2006/picture"
+
+	relStyles    = nsR + "/styles"
+	relNumbering = nsR + "/numbering"
+	relHyperlink = nsR + "/hyperlink"
+	relImage     = nsR + "/image"
+	relDocument  = nsR + "/officeDocument"
+
+	xmlHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+)
+
+// alignments maps quill's align format to w:jc values
+var alignments = map[string]string{"center": "center", "right": "right", "justify": "both"}
+
+const (
+	// twipsPerIndent is how much each level of quill's indent format moves
+	// a paragraph, half an inch
+	twipsPerIndent = 720
+	// emuPerPixel converts pixels, at 96 dpi, to the unit of drawings
+	emuPerPixel = 9525
+	// maxImageWidth is the width of the text on the page, in pixels
+	maxImageWidth = 624
+)
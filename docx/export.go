@@ -0,0 +1,501 @@
+package docx
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+	"github.com/fmpwizard/go-quilljs-delta/model"
+)
+
+// imageTypes are the image formats Word displays, by content type, with
+// the extension used for them in the package
+var imageTypes = map[string]string{
+	"image/png":  "png",
+	"image/jpeg": "jpeg",
+	"image/gif":  "gif",
+	"image/bmp":  "bmp",
+	"image/tiff": "tiff",
+}
+
+// Exporter writes documents as docx files
+type Exporter struct {
+	// Images gets the content of image embeds. When it returns no image, or
+	// one Word can't show, the alt text of the embed is used instead
+	Images model.ImageResolver
+}
+
+// NewExporter returns an Exporter that only includes images from data: URLs
+func NewExporter() *Exporter {
+	return &Exporter{Images: model.DataURLs}
+}
+
+// Export writes doc as a docx file to w, with NewExporter()
+func Export(w io.Writer, doc *delta.Delta) error {
+	return NewExporter().Export(w, doc)
+}
+
+// Export writes doc, an insert only delta, as a docx file to w. It only
+// fails if doc is not a document, the image resolver fails, or w does
+func (e *Exporter) Export(w io.Writer, doc *delta.Delta) error {
+	parsed, err := model.Parse(doc)
+	if err != nil {
+		return err
+	}
+	x := &exporter{images: e.Images, extensions: make(map[string]string)}
+	if x.images == nil {
+		x.images = model.DataURLs
+	}
+	x.rel(relStyles, "styles.xml", false)
+	x.rel(relNumbering, "numbering.xml", false)
+	for _, b := range parsed.Blocks {
+		x.block(b)
+	}
+	if x.err != nil {
+		return x.err
+	}
+	if len(parsed.Blocks) == 0 {
+		x.body.WriteString("<w:p/>")
+	}
+
+	z := zip.NewWriter(w)
+	parts := []struct {
+		name string
+		data []byte
+	}{
+		{"[Content_Types].xml", x.contentTypes()},
+		{"_rels/.rels", relationships([]relationship{{ID: "rId1", Type: relDocument, Target: "word/document.xml"}})},
+		{"word/document.xml", x.document()},
+		{"word/styles.xml", []byte(stylesXML)},
+		{"word/numbering.xml", x.numbering()},
+		{"word/_rels/document.xml.rels", relationships(x.rels)},
+	}
+	for _, m := range x.media {
+		parts = append(parts, struct {
+			name string
+			data []byte
+		}{"word/" + m.name, m.data})
+	}
+	for _, p := range parts {
+		f, err := z.Create(p.name)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(p.data); err != nil {
+			return err
+		}
+	}
+	return z.Close()
+}
+
+type relationship struct {
+	ID       string
+	Type     string
+	Target   string
+	External bool
+}
+
+type media struct {
+	name string
+	data []byte
+}
+
+// exporter holds the state of one Export call
+type exporter struct {
+	images model.ImageResolver
+	body   bytes.Buffer
+	rels   []relationship
+	media  []media
+	// extensions are the image extensions used, with their content type
+	extensions map[string]string
+	// nums are the abstract numbering of every w:num, the numId is the
+	// index plus one
+	nums     []int
+	bulletID int
+	drawings int
+	err      error
+}
+
+func (x *exporter) rel(typ, target string, external bool) string {
+	id := "rId" + strconv.Itoa(len(x.rels)+1)
+	x.rels = append(x.rels, relationship{ID: id, Type: typ, Target: target, External: external})
+	return id
+}
+
+// numbering instances: abstract 0 is bullets and 1 is numbers
+const (
+	abstractBullet = iota
+	abstractOrdered
+)
+
+func (x *exporter) num(abstract int) int {
+	x.nums = append(x.nums, abstract)
+	return len(x.nums)
+}
+
+type paragraph struct {
+	style string
+	// numID and level are set for numbered and bulleted list items
+	numID, level int
+	// indent is the left indentation in twips when not numbered
+	indent int
+	prefix string
+}
+
+func (x *exporter) block(b model.Block) {
+	switch b := b.(type) {
+	case *model.Paragraph:
+		x.paragraph(b.Line, paragraph{})
+	case *model.Heading:
+		level := b.Level
+		if level > 6 {
+			level = 6
+		}
+		x.paragraph(b.Line, paragraph{style: "Heading" + strconv.Itoa(level)})
+	case *model.List:
+		x.list(b)
+	case *model.Blockquote:
+		for _, l := range b.Lines {
+			x.paragraph(l, paragraph{style: "Quote"})
+		}
+	case *model.CodeBlock:
+		for _, l := range b.Lines {
+			x.paragraph(l, paragraph{style: "Code"})
+		}
+	case *model.EmbedBlock:
+		run := model.Run{Embed: &b.Embed, Attributes: b.Attributes}
+		if src, ok := b.Embed.Value.(string); ok && b.Embed.Key == "video" {
+			run = model.Run{Text: src, Attributes: delta.Attributes{"link": src}}
+		}
+		x.paragraph(model.Line{Runs: []model.Run{run}}, paragraph{})
+	}
+}
+
+func (x *exporter) list(l *model.List) {
+	level := l.Indent
+	if level > 8 {
+		level = 8
+	}
+	p := paragraph{style: "ListParagraph", level: level}
+	switch l.Type {
+	case "ordered":
+		// every list restarts its numbering
+		p.numID = x.num(abstractOrdered)
+	case "bullet":
+		if x.bulletID == 0 {
+			x.bulletID = x.num(abstractBullet)
+		}
+		p.numID = x.bulletID
+	default:
+		// Word has no check lists, the boxes are written as text
+		p.indent = (level + 1) * twipsPerIndent
+	}
+	for _, item := range l.Items {
+		p.prefix = ""
+		if l.Type == "checked" {
+			p.prefix = "☐ "
+			if item.Checked {
+				p.prefix = "☑ "
+			}
+		}
+		x.paragraph(item.Line, p)
+		for _, child := range item.Children {
+			x.list(child)
+		}
+	}
+}
+
+func (x *exporter) paragraph(l model.Line, p paragraph) {
+	b := &x.body
+	b.WriteString("<w:p><w:pPr>")
+	if p.style != "" {
+		fmt.Fprintf(b, `<w:pStyle w:val="%s"/>`, p.style)
+	}
+	if p.numID != 0 {
+		fmt.Fprintf(b, `<w:numPr><w:ilvl w:val="%d"/><w:numId w:val="%d"/></w:numPr>`, p.level, p.numID)
+	}
+	if dir, _ := l.Attributes.String("direction"); dir == "rtl" {
+		b.WriteString("<w:bidi/>")
+	}
+	if p.numID == 0 && p.indent == 0 {
+		if n, ok := l.Attributes.Int("indent"); ok && n > 0 {
+			p.indent = n * twipsPerIndent
+		}
+	}
+	if p.indent > 0 {
+		fmt.Fprintf(b, `<w:ind w:left="%d"/>`, p.indent)
+	}
+	if align, _ := l.Attributes.String("align"); alignments[align] != "" {
+		fmt.Fprintf(b, `<w:jc w:val="%s"/>`, alignments[align])
+	}
+	b.WriteString("</w:pPr>")
+	if p.prefix != "" {
+		x.text(p.prefix, nil)
+	}
+	for i := 0; i < len(l.Runs); i++ {
+		link := model.Link(l.Runs[i].Attributes)
+		if link == "" {
+			x.run(l.Runs[i])
+			continue
+		}
+		// runs next to each other with the same link share the hyperlink
+		fmt.Fprintf(b, `<w:hyperlink r:id="%s">`, x.rel(relHyperlink, link, true))
+		for ; i < len(l.Runs); i++ {
+			if next := model.Link(l.Runs[i].Attributes); next != link {
+				break
+			}
+			x.run(l.Runs[i])
+		}
+		i--
+		b.WriteString("</w:hyperlink>")
+	}
+	b.WriteString("</w:p>")
+}
+
+func (x *exporter) run(r model.Run) {
+	if r.Embed == nil {
+		x.text(r.Text, r.Attributes)
+		return
+	}
+	src, _ := r.Embed.Value.(string)
+	switch r.Embed.Key {
+	case "image":
+		x.image(src, r.Attributes)
+	case "formula":
+		x.text(src, r.Attributes)
+	}
+}
+
+// text writes a run, tabs are written as w:tab
+func (x *exporter) text(text string, attrs delta.Attributes) {
+	b := &x.body
+	b.WriteString("<w:r>")
+	x.runProperties(attrs)
+	for i, part := range strings.Split(text, "\t") {
+		if i > 0 {
+			b.WriteString("<w:tab/>")
+		}
+		if part != "" {
+			fmt.Fprintf(b, `<w:t xml:space="preserve">%s</w:t>`, model.EscapeXML(part))
+		}
+	}
+	b.WriteString("</w:r>")
+}
+
+// runProperties writes the w:rPr of attrs, in the order the schema wants
+func (x *exporter) runProperties(attrs delta.Attributes) {
+	if len(attrs) == 0 {
+		return
+	}
+	var b bytes.Buffer
+	link := model.Link(attrs)
+	code, _ := attrs.Bool("code")
+	// a run only has one character style, code in a link gets the font
+	font, _ := attrs.String("font")
+	font = model.FontName(font)
+	switch {
+	case link != "":
+		b.WriteString(`<w:rStyle w:val="Hyperlink"/>`)
+		if code {
+			font = model.CodeFont
+		}
+	case code:
+		b.WriteString(`<w:rStyle w:val="CodeChar"/>`)
+	}
+	if font != "" {
+		fmt.Fprintf(&b, `<w:rFonts w:ascii="%[1]s" w:hAnsi="%[1]s" w:cs="%[1]s"/>`, model.EscapeXML(font))
+	}
+	if v, _ := attrs.Bool("bold"); v {
+		b.WriteString("<w:b/>")
+	}
+	if v, _ := attrs.Bool("italic"); v {
+		b.WriteString("<w:i/>")
+	}
+	if v, _ := attrs.Bool("strike"); v {
+		b.WriteString("<w:strike/>")
+	}
+	if color, ok := attrs.String("color"); ok {
+		if c, ok := delta.NormalizeColor(color); ok {
+			fmt.Fprintf(&b, `<w:color w:val="%s"/>`, strings.ToUpper(c[1:]))
+		}
+	}
+	if size, ok := attrs.String("size"); ok {
+		if n, ok := model.HalfPoints(size); ok {
+			fmt.Fprintf(&b, `<w:sz w:val="%[1]d"/><w:szCs w:val="%[1]d"/>`, n)
+		}
+	}
+	if v, _ := attrs.Bool("underline"); v {
+		b.WriteString(`<w:u w:val="single"/>`)
+	}
+	if background, ok := attrs.String("background"); ok {
+		if c, ok := delta.NormalizeColor(background); ok {
+			fmt.Fprintf(&b, `<w:shd w:val="clear" w:color="auto" w:fill="%s"/>`, strings.ToUpper(c[1:]))
+		}
+	}
+	switch script, _ := attrs.String("script"); script {
+	case "super":
+		b.WriteString(`<w:vertAlign w:val="superscript"/>`)
+	case "sub":
+		b.WriteString(`<w:vertAlign w:val="subscript"/>`)
+	}
+	if b.Len() > 0 {
+		x.body.WriteString("<w:rPr>")
+		x.body.Write(b.Bytes())
+		x.body.WriteString("</w:rPr>")
+	}
+}
+
+// image writes an inline drawing, or the alt text when there is no image
+func (x *exporter) image(src string, attrs delta.Attributes) {
+	alt, _ := attrs.String("alt")
+	img, err := x.images.ResolveImage(src)
+	if err != nil {
+		if x.err == nil {
+			x.err = err
+		}
+		return
+	}
+	ext := ""
+	if img != nil {
+		ext = imageTypes[strings.ToLower(img.ContentType)]
+	}
+	if ext == "" {
+		if alt != "" {
+			x.text(alt, attrs)
+		}
+		return
+	}
+	width, height := img.DisplaySize(attrs, maxImageWidth)
+	x.drawings++
+	name := fmt.Sprintf("media/image%d.%s", x.drawings, ext)
+	x.media = append(x.media, media{name: name, data: img.Data})
+	x.extensions[ext] = strings.ToLower(img.ContentType)
+	id := x.rel(relImage, name, false)
+
+	b := &x.body
+	b.WriteString("<w:r>")
+	x.runProperties(attrs.Without("alt").Without("width").Without("height"))
+	fmt.Fprintf(b, `<w:drawing><wp:inline distT="0" distB="0" distL="0" distR="0">`+
+		`<wp:extent cx="%[1]d" cy="%[2]d"/><wp:docPr id="%[3]d" name="Picture %[3]d" descr="%[4]s"/>`+
+		`<wp:cNvGraphicFramePr><a:graphicFrameLocks noChangeAspect="1"/></wp:cNvGraphicFramePr>`+
+		`<a:graphic><a:graphicData uri="%[5]s"><pic:pic>`+
+		`<pic:nvPicPr><pic:cNvPr id="%[3]d" name="%[6]s"/><pic:cNvPicPr/></pic:nvPicPr>`+
+		`<pic:blipFill><a:blip r:embed="%[7]s"/><a:stretch><a:fillRect/></a:stretch></pic:blipFill>`+
+		`<pic:spPr><a:xfrm><a:off x="0" y="0"/><a:ext cx="%[1]d" cy="%[2]d"/></a:xfrm>`+
+		`<a:prstGeom prst="rect"><a:avLst/></a:prstGeom></pic:spPr>`+
+		`</pic:pic></a:graphicData></a:graphic></wp:inline></w:drawing></w:r>`,
+		width*emuPerPixel, height*emuPerPixel, x.drawings, model.EscapeXML(alt), nsPic, name[len("media/"):], id)
+}
+
+func (x *exporter) document() []byte {
+	var b bytes.Buffer
+	b.WriteString(xmlHeader)
+	fmt.Fprintf(&b, `<w:document xmlns:w="%s" xmlns:r="%s" xmlns:wp="%s" xmlns:a="%s" xmlns:pic="%s"><w:body>`,
+		nsW, nsR, nsWP, nsA, nsPic)
+	b.Write(x.body.Bytes())
+	b.WriteString(`<w:sectPr><w:pgSz w:w="12240" w:h="15840"/>` +
+		`<w:pgMar w:top="1440" w:right="1440" w:bottom="1440" w:left="1440" w:header="720" w:footer="720" w:gutter="0"/>` +
+		`</w:sectPr></w:body></w:document>`)
+	return b.Bytes()
+}
+
+func (x *exporter) contentTypes() []byte {
+	var b bytes.Buffer
+	b.WriteString(xmlHeader)
+	b.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>`)
+	for _, ext := range []string{"png", "jpeg", "gif", "bmp", "tiff"} {
+		if ct, ok := x.extensions[ext]; ok {
+			fmt.Fprintf(&b, `<Default Extension="%s" ContentType="%s"/>`, ext, ct)
+		}
+	}
+	b.WriteString(`<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>` +
+		`<Override PartName="/word/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"/>` +
+		`<Override PartName="/word/numbering.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.numbering+xml"/>` +
+		`</Types>`)
+	return b.Bytes()
+}
+
+func relationships(rels []relationship) []byte {
+	var b bytes.Buffer
+	b.WriteString(xmlHeader)
+	b.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for _, r := range rels {
+		mode := ""
+		if r.External {
+			mode = ` TargetMode="External"`
+		}
+		fmt.Fprintf(&b, `<Relationship Id="%s" Type="%s" Target="%s"%s/>`, r.ID, r.Type, model.EscapeXML(r.Target), mode)
+	}
+	b.WriteString(`</Relationships>`)
+	return b.Bytes()
+}
+
+// numbering writes the two abstract numberings, bullets and numbers, and
+// the w:num used by the lists
+func (x *exporter) numbering() []byte {
+	var b bytes.Buffer
+	b.WriteString(xmlHeader)
+	fmt.Fprintf(&b, `<w:numbering xmlns:w="%s">`, nsW)
+	bullets := []string{"•", "◦", "▪"}
+	formats := []string{"decimal", "lowerLetter", "lowerRoman"}
+	for abstract := abstractBullet; abstract <= abstractOrdered; abstract++ {
+		fmt.Fprintf(&b, `<w:abstractNum w:abstractNumId="%d"><w:multiLevelType w:val="hybridMultilevel"/>`, abstract)
+		for lvl := 0; lvl < 9; lvl++ {
+			format, text := "bullet", bullets[lvl%3]
+			if abstract == abstractOrdered {
+				format, text = formats[lvl%3], "%"+strconv.Itoa(lvl+1)+"."
+			}
+			fmt.Fprintf(&b, `<w:lvl w:ilvl="%d"><w:start w:val="1"/><w:numFmt w:val="%s"/><w:lvlText w:val="%s"/>`+
+				`<w:lvlJc w:val="left"/><w:pPr><w:ind w:left="%d" w:hanging="360"/></w:pPr></w:lvl>`,
+				lvl, format, text, (lvl+1)*twipsPerIndent)
+		}
+		b.WriteString(`</w:abstractNum>`)
+	}
+	for i, abstract := range x.nums {
+		fmt.Fprintf(&b, `<w:num w:numId="%d"><w:abstractNumId w:val="%d"/>`, i+1, abstract)
+		if abstract == abstractOrdered {
+			for lvl := 0; lvl < 9; lvl++ {
+				fmt.Fprintf(&b, `<w:lvlOverride w:ilvl="%d"><w:startOverride w:val="1"/></w:lvlOverride>`, lvl)
+			}
+		}
+		b.WriteString(`</w:num>`)
+	}
+	b.WriteString(`</w:numbering>`)
+	return b.Bytes()
+}
+
+var stylesXML = xmlHeader + `<w:styles xmlns:w="` + nsW + `">` +
+	`<w:docDefaults><w:rPrDefault><w:rPr><w:rFonts w:ascii="Calibri" w:hAnsi="Calibri" w:cs="Calibri"/>` +
+	`<w:sz w:val="22"/><w:szCs w:val="22"/></w:rPr></w:rPrDefault>` +
+	`<w:pPrDefault><w:pPr><w:spacing w:after="120" w:line="264" w:lineRule="auto"/></w:pPr></w:pPrDefault></w:docDefaults>` +
+	`<w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:name w:val="Normal"/><w:qFormat/></w:style>` +
+	`<w:style w:type="character" w:default="1" w:styleId="DefaultParagraphFont"><w:name w:val="Default Paragraph Font"/><w:uiPriority w:val="1"/><w:semiHidden/></w:style>` +
+	headingStyle(1, 40) + headingStyle(2, 32) + headingStyle(3, 28) +
+	headingStyle(4, 24) + headingStyle(5, 22) + headingStyle(6, 22) +
+	`<w:style w:type="paragraph" w:styleId="Quote"><w:name w:val="Quote"/><w:basedOn w:val="Normal"/><w:qFormat/>` +
+	`<w:pPr><w:pBdr><w:left w:val="single" w:sz="18" w:space="8" w:color="CCCCCC"/></w:pBdr><w:ind w:left="360"/></w:pPr>` +
+	`<w:rPr><w:i/><w:color w:val="555555"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Code"><w:name w:val="Code"/><w:basedOn w:val="Normal"/>` +
+	`<w:pPr><w:shd w:val="clear" w:color="auto" w:fill="F0F0F0"/><w:spacing w:after="0" w:line="240" w:lineRule="auto"/></w:pPr>` +
+	`<w:rPr><w:rFonts w:ascii="` + model.CodeFont + `" w:hAnsi="` + model.CodeFont + `" w:cs="` + model.CodeFont + `"/><w:sz w:val="20"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="ListParagraph"><w:name w:val="List Paragraph"/><w:basedOn w:val="Normal"/><w:qFormat/>` +
+	`<w:pPr><w:spacing w:after="0"/><w:ind w:left="720"/><w:contextualSpacing/></w:pPr></w:style>` +
+	`<w:style w:type="character" w:styleId="Hyperlink"><w:name w:val="Hyperlink"/><w:basedOn w:val="DefaultParagraphFont"/>` +
+	`<w:rPr><w:color w:val="0563C1"/><w:u w:val="single"/></w:rPr></w:style>` +
+	`<w:style w:type="character" w:styleId="CodeChar"><w:name w:val="Code Char"/><w:basedOn w:val="DefaultParagraphFont"/>` +
+	`<w:rPr><w:rFonts w:ascii="` + model.CodeFont + `" w:hAnsi="` + model.CodeFont + `" w:cs="` + model.CodeFont + `"/>` +
+	`<w:shd w:val="clear" w:color="auto" w:fill="F0F0F0"/></w:rPr></w:style>` +
+	`</w:styles>`
+
+func headingStyle(level, size int) string {
+	return fmt.Sprintf(`<w:style w:type="paragraph" w:styleId="Heading%[1]d"><w:name w:val="heading %[1]d"/>`+
+		`<w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:qFormat/>`+
+		`<w:pPr><w:keepNext/><w:spacing w:before="240" w:after="60"/><w:outlineLvl w:val="%[3]d"/></w:pPr>`+
+		`<w:rPr><w:b/><w:sz w:val="%[2]d"/><w:szCs w:val="%[2]d"/></w:rPr></w:style>`, level, size, level-1)
+}
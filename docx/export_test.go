@@ -0,0 +1,204 @@
+package docx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+	"github.com/fmpwizard/go-quilljs-delta/model"
+)
+
+func testPNG(w, h int) []byte {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h)))
+	return buf.Bytes()
+}
+
+// unzip returns the parts of a docx file, checking every xml part is well
+// formed
+func unzip(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := make(map[string]string)
+	for _, f := range z.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := ioutil.ReadAll(r)
+		r.Close()
+		parts[f.Name] = string(b)
+		if !strings.HasSuffix(f.Name, ".xml") && !strings.HasSuffix(f.Name, ".rels") {
+			continue
+		}
+		dec := xml.NewDecoder(bytes.NewReader(b))
+		for {
+			if _, err := dec.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s is not well formed: %v", f.Name, err)
+			}
+		}
+	}
+	return parts
+}
+
+func TestExport(t *testing.T) {
+	img := "data:image/png;base64," + base64.StdEncoding.EncodeToString(testPNG(800, 400))
+	doc := delta.New(nil).
+		Insert("Title", nil).Insert("\n", delta.Attributes{"header": 1}).
+		Insert("Some ", nil).Insert("bold", delta.Attributes{"bold": true, "color": "red"}).
+		Insert(" & ", nil).Insert("linked", delta.Attributes{"link": "https://example.com/?a=1&b=2", "italic": true}).
+		Insert(" text", delta.Attributes{"link": "https://example.com/?a=1&b=2"}).
+		Insert("\n", delta.Attributes{"align": "center"}).
+		Insert("one", nil).Insert("\n", delta.Attributes{"list": "ordered"}).
+		Insert("nested", nil).Insert("\n", delta.Attributes{"list": "bullet", "indent": 1}).
+		Insert("two", nil).Insert("\n", delta.Attributes{"list": "ordered"}).
+		Insert("todo", nil).Insert("\n", delta.Attributes{"list": "unchecked"}).
+		Insert("x := 1", nil).Insert("\n", delta.Attributes{"code-block": true}).
+		InsertEmbed(delta.Embed{Key: "image", Value: img}, delta.Attributes{"alt": "a <picture>"}).
+		InsertEmbed(delta.Embed{Key: "image", Value: "https://example.com/a.png"}, delta.Attributes{"alt": "remote"}).
+		Insert("\n", nil)
+
+	var buf bytes.Buffer
+	if err := Export(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	parts := unzip(t, buf.Bytes())
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "word/document.xml", "word/styles.xml",
+		"word/numbering.xml", "word/_rels/document.xml.rels", "word/media/image1.png"} {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("missing part %s", name)
+		}
+	}
+
+	body := parts["word/document.xml"]
+	for _, s := range []string{
+		`<w:pStyle w:val="Heading1"/>`,
+		`<w:rPr><w:b/><w:color w:val="FF0000"/></w:rPr><w:t xml:space="preserve">bold</w:t>`,
+		`<w:t xml:space="preserve"> &amp; </w:t>`,
+		`<w:jc w:val="center"/>`,
+		`<w:hyperlink r:id="rId3"><w:r><w:rPr><w:rStyle w:val="Hyperlink"/><w:i/></w:rPr><w:t xml:space="preserve">linked</w:t></w:r>` +
+			`<w:r><w:rPr><w:rStyle w:val="Hyperlink"/></w:rPr><w:t xml:space="preserve"> text</w:t></w:r></w:hyperlink>`,
+		`<w:numPr><w:ilvl w:val="0"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t xml:space="preserve">one</w:t>`,
+		`<w:numPr><w:ilvl w:val="1"/><w:numId w:val="2"/></w:numPr></w:pPr><w:r><w:t xml:space="preserve">nested</w:t>`,
+		`<w:numPr><w:ilvl w:val="0"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t xml:space="preserve">two</w:t>`,
+		`<w:t xml:space="preserve">☐ </w:t>`,
+		`<w:pStyle w:val="Code"/>`,
+		// 800x400 pixels are made to fit the page
+		`<wp:extent cx="5943600" cy="2971800"/><wp:docPr id="1" name="Picture 1" descr="a &lt;picture&gt;"/>`,
+		`<a:blip r:embed="rId4"/>`,
+		`<w:t xml:space="preserve">remote</w:t>`,
+	} {
+		if !strings.Contains(body, s) {
+			t.Errorf("document.xml is missing %s\n%s", s, body)
+		}
+	}
+
+	rels := parts["word/_rels/document.xml.rels"]
+	for _, s := range []string{
+		`<Relationship Id="rId3" Type="` + relHyperlink + `" Target="https://example.com/?a=1&amp;b=2" TargetMode="External"/>`,
+		`<Relationship Id="rId4" Type="` + relImage + `" Target="media/image1.png"/>`,
+	} {
+		if !strings.Contains(rels, s) {
+			t.Errorf("document.xml.rels is missing %s\n%s", s, rels)
+		}
+	}
+	if !strings.Contains(parts["[Content_Types].xml"], `<Default Extension="png" ContentType="image/png"/>`) {
+		t.Errorf("missing png content type")
+	}
+	numbering := parts["word/numbering.xml"]
+	if !strings.Contains(numbering, `<w:num w:numId="1"><w:abstractNumId w:val="1"/>`) ||
+		!strings.Contains(numbering, `<w:num w:numId="2"><w:abstractNumId w:val="0"/></w:num>`) {
+		t.Errorf("unexpected numbering %s", numbering)
+	}
+}
+
+func TestExportImageResolver(t *testing.T) {
+	doc := delta.New(nil).
+		InsertEmbed(delta.Embed{Key: "image", Value: "https://example.com/a.png"}, delta.Attributes{"width": "100"}).
+		Insert("\n", nil)
+	data := testPNG(50, 20)
+	e := &Exporter{Images: model.ImageResolverFunc(func(src string) (*model.Image, error) {
+		return &model.Image{Data: data, ContentType: "image/png"}, nil
+	})}
+	var buf bytes.Buffer
+	if err := e.Export(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	parts := unzip(t, buf.Bytes())
+	if parts["word/media/image1.png"] != string(data) {
+		t.Errorf("image not in the package")
+	}
+	if !strings.Contains(parts["word/document.xml"], `<wp:extent cx="952500" cy="381000"/>`) {
+		t.Errorf("unexpected image size %s", parts["word/document.xml"])
+	}
+
+	fail := errors.New("fail")
+	e.Images = model.ImageResolverFunc(func(src string) (*model.Image, error) {
+		return nil, fail
+	})
+	if err := e.Export(&buf, doc); err != fail {
+		t.Errorf("expected the resolver error, got %v", err)
+	}
+}
+
+func TestExportNotDocument(t *testing.T) {
+	if err := Export(ioutil.Discard, delta.New(nil).Retain(1, nil)); err != model.ErrNotDocument {
+		t.Errorf("expected ErrNotDocument, got %v", err)
+	}
+	var buf bytes.Buffer
+	if err := Export(&buf, delta.New(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(unzip(t, buf.Bytes())["word/document.xml"], "<w:body><w:p/>") {
+		t.Errorf("expected an empty paragraph")
+	}
+}
+
+func TestExportUnsafeLinks(t *testing.T) {
+	doc := delta.New(nil).
+		Insert("click", delta.Attributes{"link": "javascript:alert(1)"}).
+		Insert(" here", delta.Attributes{"link": " JaVa\tscript:alert(1)"}).
+		Insert("\n", nil).
+		InsertEmbed(delta.Embed{Key: "video", Value: "javascript:alert(1)"}, nil).
+		Insert("\n", nil)
+	var buf bytes.Buffer
+	if err := Export(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	parts := unzip(t, buf.Bytes())
+	if body := parts["word/document.xml"]; strings.Contains(body, "<w:hyperlink") || strings.Contains(body, "Hyperlink") ||
+		!strings.Contains(body, ">click</w:t>") {
+		t.Errorf("expected unsafe links as plain text\n%s", body)
+	}
+	if rels := parts["word/_rels/document.xml.rels"]; strings.Contains(rels, relHyperlink) {
+		t.Errorf("expected no hyperlink relationship\n%s", rels)
+	}
+}
+
+func TestExportTransparentColors(t *testing.T) {
+	doc := delta.New(nil).
+		Insert("clear", delta.Attributes{"color": "transparent", "background": "transparent"}).
+		Insert("\n", nil)
+	var buf bytes.Buffer
+	if err := Export(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	if body := unzip(t, buf.Bytes())["word/document.xml"]; strings.Contains(body, "<w:color") || strings.Contains(body, "<w:shd") ||
+		!strings.Contains(body, ">clear</w:t>") {
+		t.Errorf("expected transparent colors to be left out\n%s", body)
+	}
+}
@@ -0,0 +1,774 @@
+package docx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+	"github.com/fmpwizard/go-quilljs-delta/model"
+)
+
+// ErrInvalidDocx is returned by Import for zip files that are not Word
+// documents, or have parts too large to read
+var ErrInvalidDocx = errors.New("docx: not a word document")
+
+// urls checks the targets of links and external images, the ones that
+// aren't safe are left out
+var urls = delta.NewSanitizer()
+
+// maxPartSize is the largest part of a docx file Import reads
+const maxPartSize = 64 << 20
+
+// ImageSink stores the images found while importing a docx file, and
+// returns the URL of the image embed. name is the name of the image in the
+// package, like media/image1.png. An empty URL leaves the image out
+type ImageSink interface {
+	StoreImage(name string, img *model.Image) (string, error)
+}
+
+// ImageSinkFunc lets you use a plain function as an ImageSink
+type ImageSinkFunc func(name string, img *model.Image) (string, error)
+
+// StoreImage calls f(name, img)
+func (f ImageSinkFunc) StoreImage(name string, img *model.Image) (string, error) {
+	return f(name, img)
+}
+
+// DataURLSink keeps the images in the document, as data: URLs
+var DataURLSink ImageSink = ImageSinkFunc(func(name string, img *model.Image) (string, error) {
+	return img.DataURL(), nil
+})
+
+// Importer reads docx files into deltas
+type Importer struct {
+	// Images stores the images of the file, for the image embeds
+	Images ImageSink
+}
+
+// NewImporter returns an Importer that keeps images as data: URLs
+func NewImporter() *Importer {
+	return &Importer{Images: DataURLSink}
+}
+
+// Import reads a docx file with NewImporter()
+func Import(r io.ReaderAt, size int64) (*delta.Delta, error) {
+	return NewImporter().Import(r, size)
+}
+
+// Import reads the docx file in r, of size bytes, and returns it as an
+// insert only delta. Paragraph styles and numbering become block formats,
+// run properties become inline formats, and images go through the
+// ImageSink. Text boxes, deleted text, comments, notes and page breaks are
+// left out, and so are links and external images with unsafe URLs, see
+// delta.Sanitizer. Numbers, like header levels and indents, are float64 as
+// in a delta read from JSON, so an imported delta compares equal to a
+// stored one
+func (i *Importer) Import(r io.ReaderAt, size int64) (*delta.Delta, error) {
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	x := &importer{
+		sink:   i.Images,
+		files:  make(map[string]*zip.File),
+		images: make(map[string]string),
+		doc:    delta.New(nil),
+	}
+	if x.sink == nil {
+		x.sink = DataURLSink
+	}
+	for _, f := range z.File {
+		x.files[strings.TrimPrefix(f.Name, "/")] = f
+	}
+
+	main := "word/document.xml"
+	var root node
+	if ok, err := x.part("_rels/.rels", &root); err != nil {
+		return nil, err
+	} else if ok {
+		for _, rel := range root.Nodes {
+			if rel.attr("Type") == relDocument {
+				main = strings.TrimPrefix(rel.attr("Target"), "/")
+			}
+		}
+	}
+	x.dir = path.Dir(main)
+	if err := x.readRelationships(path.Join(x.dir, "_rels", path.Base(main)+".rels")); err != nil {
+		return nil, err
+	}
+	if err := x.readStyles(); err != nil {
+		return nil, err
+	}
+	if err := x.readNumbering(); err != nil {
+		return nil, err
+	}
+
+	var document node
+	if ok, err := x.part(main, &document); err != nil {
+		return nil, err
+	} else if !ok || document.XMLName.Local != "document" {
+		return nil, ErrInvalidDocx
+	}
+	if body := document.child("body"); body != nil {
+		x.blocks(body)
+	}
+	if x.err != nil {
+		return nil, x.err
+	}
+	if x.doc.Length() == 0 {
+		x.doc.Insert("\n", nil)
+	}
+	return x.doc, nil
+}
+
+// node is any xml element, the parts of a docx file are small enough to be
+// read in memory. Elements are looked up by local name, Word documents don't
+// reuse names across namespaces where it matters
+type node struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Nodes   []node     `xml:",any"`
+	Text    string     `xml:",chardata"`
+}
+
+func (n *node) attr(local string) string {
+	if n == nil {
+		return ""
+	}
+	for _, a := range n.Attrs {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func (n *node) child(local string) *node {
+	if n == nil {
+		return nil
+	}
+	for i := range n.Nodes {
+		if n.Nodes[i].XMLName.Local == local {
+			return &n.Nodes[i]
+		}
+	}
+	return nil
+}
+
+// val returns the w:val of the child element
+func (n *node) val(local string) string {
+	return n.child(local).attr("val")
+}
+
+// on tells if a toggle property, like w:b, is set
+func (n *node) on(local string) bool {
+	c := n.child(local)
+	if c == nil {
+		return false
+	}
+	switch c.attr("val") {
+	case "false", "0", "off", "none":
+		return false
+	}
+	return true
+}
+
+// find returns the first element named local under n, depth first
+func (n *node) find(local string) *node {
+	for i := range n.Nodes {
+		if n.Nodes[i].XMLName.Local == local {
+			return &n.Nodes[i]
+		}
+		if found := n.Nodes[i].find(local); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func (n *node) attrInt(local string) (int, bool) {
+	if n == nil {
+		return 0, false
+	}
+	i, err := strconv.Atoi(n.attr(local))
+	return i, err == nil
+}
+
+type style struct {
+	basedOn string
+	header  int
+	quote   bool
+	code    bool
+	numID   string
+	level   int
+}
+
+// importer holds the state of one Import call
+type importer struct {
+	sink  ImageSink
+	files map[string]*zip.File
+	dir   string
+	// rels are the relationships of the main document, by id
+	rels   map[string]relationship
+	styles map[string]*style
+	// abstracts maps numIds to abstract numberings, formats has the number
+	// format of every level of the abstract numberings
+	abstracts map[string]string
+	formats   map[string]map[int]string
+	// images maps image parts to their URLs, so every image is stored once
+	images map[string]string
+	doc    *delta.Delta
+	// link is the target of the HYPERLINK field being read
+	link    string
+	inField bool
+	instr   string
+	err     error
+}
+
+// part reads and parses the xml part name, false if there is no such part
+func (x *importer) part(name string, v interface{}) (bool, error) {
+	data, err := x.read(name)
+	if err != nil || data == nil {
+		return false, err
+	}
+	return true, xml.Unmarshal(data, v)
+}
+
+func (x *importer) read(name string) ([]byte, error) {
+	f, ok := x.files[name]
+	if !ok {
+		return nil, nil
+	}
+	if f.UncompressedSize64 > maxPartSize {
+		return nil, ErrInvalidDocx
+	}
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	data, err := ioutil.ReadAll(io.LimitReader(r, maxPartSize+1))
+	if err == nil && len(data) > maxPartSize {
+		err = ErrInvalidDocx
+	}
+	return data, err
+}
+
+func (x *importer) readRelationships(name string) error {
+	x.rels = make(map[string]relationship)
+	var root node
+	if _, err := x.part(name, &root); err != nil {
+		return err
+	}
+	for _, rel := range root.Nodes {
+		x.rels[rel.attr("Id")] = relationship{
+			ID:       rel.attr("Id"),
+			Type:     rel.attr("Type"),
+			Target:   rel.attr("Target"),
+			External: rel.attr("TargetMode") == "External",
+		}
+	}
+	return nil
+}
+
+// target returns the part a relationship of the main document with the
+// given type points to
+func (x *importer) target(typ, fallback string) string {
+	for _, rel := range x.rels {
+		if rel.Type == typ && !rel.External {
+			fallback = rel.Target
+			break
+		}
+	}
+	return x.partName(fallback)
+}
+
+// partName resolves a relationship target of the main document
+func (x *importer) partName(target string) string {
+	if strings.HasPrefix(target, "/") {
+		return target[1:]
+	}
+	return path.Join(x.dir, target)
+}
+
+func (x *importer) readStyles() error {
+	x.styles = make(map[string]*style)
+	var root node
+	if _, err := x.part(x.target(relStyles, "styles.xml"), &root); err != nil {
+		return err
+	}
+	for _, n := range root.Nodes {
+		if n.XMLName.Local != "style" {
+			continue
+		}
+		id := n.attr("styleId")
+		name := strings.ToLower(n.val("name"))
+		s := &style{basedOn: n.val("basedOn")}
+		switch {
+		case strings.HasPrefix(name, "heading "):
+			s.header, _ = strconv.Atoi(name[len("heading "):])
+		case name == "title":
+			s.header = 1
+		case name == "subtitle":
+			s.header = 2
+		case name == "quote" || name == "intense quote":
+			s.quote = true
+		case id == "Code" || name == "code" || name == "html preformatted":
+			s.code = true
+		}
+		pPr := n.child("pPr")
+		if lvl, ok := pPr.child("outlineLvl").attrInt("val"); ok && s.header == 0 && lvl < 9 {
+			s.header = lvl + 1
+		}
+		if numPr := pPr.child("numPr"); numPr != nil {
+			s.numID = numPr.val("numId")
+			s.level, _ = numPr.child("ilvl").attrInt("val")
+		}
+		x.styles[id] = s
+	}
+	return nil
+}
+
+// style returns the paragraph style id, with what it inherits
+func (x *importer) style(id string) style {
+	var ret style
+	seen := make(map[string]bool)
+	for id != "" && !seen[id] {
+		seen[id] = true
+		s, ok := x.styles[id]
+		if !ok {
+			break
+		}
+		if ret.header == 0 {
+			ret.header = s.header
+		}
+		ret.quote = ret.quote || s.quote
+		ret.code = ret.code || s.code
+		if ret.numID == "" {
+			ret.numID, ret.level = s.numID, s.level
+		}
+		id = s.basedOn
+	}
+	return ret
+}
+
+func (x *importer) readNumbering() error {
+	x.abstracts = make(map[string]string)
+	x.formats = make(map[string]map[int]string)
+	var root node
+	if _, err := x.part(x.target(relNumbering, "numbering.xml"), &root); err != nil {
+		return err
+	}
+	for _, n := range root.Nodes {
+		switch n.XMLName.Local {
+		case "abstractNum":
+			levels := make(map[int]string)
+			for _, lvl := range n.Nodes {
+				if i, ok := lvl.attrInt("ilvl"); ok && lvl.XMLName.Local == "lvl" {
+					levels[i] = lvl.val("numFmt")
+				}
+			}
+			x.formats[n.attr("abstractNumId")] = levels
+		case "num":
+			x.abstracts[n.attr("numId")] = n.val("abstractNumId")
+		}
+	}
+	return nil
+}
+
+// listType returns the list format of a numbering level
+func (x *importer) listType(numID string, level int) string {
+	if numID == "" || numID == "0" {
+		return ""
+	}
+	if x.formats[x.abstracts[numID]][level] == "bullet" {
+		return "bullet"
+	}
+	return "ordered"
+}
+
+// blocks reads the paragraphs under n, tables are read cell by cell
+func (x *importer) blocks(n *node) {
+	for i := range n.Nodes {
+		c := &n.Nodes[i]
+		switch c.XMLName.Local {
+		case "p":
+			x.paragraph(c)
+		case "tbl", "tr", "tc", "sdt", "sdtContent", "customXml", "ins":
+			x.blocks(c)
+		}
+	}
+}
+
+func (x *importer) paragraph(p *node) {
+	pPr := p.child("pPr")
+	attrs := x.blockAttributes(pPr)
+	if _, ok := attrs["list"]; !ok {
+		if first := p.find("t"); first != nil {
+			for prefix, value := range map[string]string{"☐ ": "unchecked", "☑ ": "checked"} {
+				if !strings.HasPrefix(first.Text, prefix) {
+					continue
+				}
+				first.Text = first.Text[len(prefix):]
+				// check lists are exported as text, they only keep the
+				// formats of the line
+				checklist := delta.Attributes{"list": value}
+				for _, k := range []string{"align", "direction"} {
+					if v, ok := attrs[k]; ok {
+						checklist[k] = v
+					}
+				}
+				if n := indentLevel(pPr) - 1; n > 0 {
+					checklist["indent"] = float64(n)
+				}
+				attrs = checklist
+			}
+		}
+	}
+	if len(attrs) == 0 {
+		attrs = nil
+	}
+	x.inline(p, nil, attrs)
+	x.doc.Insert("\n", attrs)
+}
+
+// blockAttributes returns the block formats of a paragraph
+func (x *importer) blockAttributes(pPr *node) delta.Attributes {
+	attrs := delta.Attributes{}
+	s := x.style(pPr.val("pStyle"))
+	numID, level := s.numID, s.level
+	if numPr := pPr.child("numPr"); numPr != nil {
+		if id := numPr.val("numId"); id != "" {
+			numID = id
+		}
+		level, _ = numPr.child("ilvl").attrInt("val")
+	}
+	list := x.listType(numID, level)
+	switch {
+	case s.code:
+		attrs["code-block"] = true
+	case s.header > 0:
+		if s.header > 6 {
+			s.header = 6
+		}
+		attrs["header"] = float64(s.header)
+	case list != "":
+		attrs["list"] = list
+		if level > 0 {
+			attrs["indent"] = float64(level)
+		}
+	case s.quote:
+		attrs["blockquote"] = true
+	}
+	if list == "" {
+		if n := indentLevel(pPr); n > 0 && !s.code && !s.quote {
+			attrs["indent"] = float64(n)
+		}
+	}
+	switch pPr.val("jc") {
+	case "center":
+		attrs["align"] = "center"
+	case "right", "end":
+		attrs["align"] = "right"
+	case "both", "distribute":
+		attrs["align"] = "justify"
+	}
+	if pPr.on("bidi") {
+		attrs["direction"] = "rtl"
+	}
+	return attrs
+}
+
+// indentLevel returns the left indentation of a paragraph in quill's
+// indent levels
+func indentLevel(pPr *node) int {
+	ind := pPr.child("ind")
+	left, ok := ind.attrInt("left")
+	if !ok {
+		left, ok = ind.attrInt("start")
+	}
+	if !ok {
+		return 0
+	}
+	n := int(math.Floor(float64(left)/twipsPerIndent + 0.5))
+	if n > 8 {
+		n = 8
+	}
+	return n
+}
+
+// inline reads the runs under n, link is set inside hyperlinks. Line breaks
+// end the line with the formats of the paragraph
+func (x *importer) inline(n *node, link delta.Attributes, block delta.Attributes) {
+	for i := range n.Nodes {
+		c := &n.Nodes[i]
+		switch c.XMLName.Local {
+		case "r":
+			x.run(c, link, block)
+		case "hyperlink":
+			target := ""
+			if rel, ok := x.rels[c.attr("id")]; ok && c.attr("id") != "" {
+				target = rel.Target
+			} else if anchor := c.attr("anchor"); anchor != "" {
+				target = "#" + anchor
+			}
+			if target == "" || !urls.ValidURL(target, false) {
+				x.inline(c, link, block)
+			} else {
+				x.inline(c, delta.Attributes{"link": target}, block)
+			}
+		case "fldSimple":
+			if target := fieldLink(c.attr("instr")); target != "" {
+				x.inline(c, delta.Attributes{"link": target}, block)
+			} else {
+				x.inline(c, link, block)
+			}
+		case "ins", "smartTag", "sdt", "sdtContent", "customXml", "moveTo":
+			x.inline(c, link, block)
+		}
+	}
+}
+
+// fieldLink returns the target of a HYPERLINK field, "" when it isn't safe
+func fieldLink(instr string) string {
+	args := fieldArgs(instr)
+	if len(args) < 2 || args[0] != "HYPERLINK" {
+		return ""
+	}
+	target := ""
+	for i := 1; i < len(args); i++ {
+		if args[i] == `\l` && i+1 < len(args) {
+			target = "#" + args[i+1]
+			break
+		}
+		if target == "" && !strings.HasPrefix(args[i], `\`) {
+			target = args[i]
+		}
+	}
+	if target == "" || !urls.ValidURL(target, false) {
+		return ""
+	}
+	return target
+}
+
+// fieldArgs splits the instruction of a field into its arguments, quoted
+// arguments keep their spaces
+func fieldArgs(instr string) []string {
+	var ret []string
+	for {
+		instr = strings.TrimLeft(instr, " \t\r\n")
+		if instr == "" {
+			return ret
+		}
+		end := strings.IndexAny(instr, " \t\r\n")
+		if instr[0] == '"' {
+			instr = instr[1:]
+			end = strings.IndexByte(instr, '"')
+		}
+		if end < 0 {
+			return append(ret, instr)
+		}
+		ret = append(ret, instr[:end])
+		instr = instr[end+1:]
+	}
+}
+
+func (x *importer) run(r *node, link delta.Attributes, block delta.Attributes) {
+	attrs := runAttributes(r.child("rPr"))
+	if link == nil && x.link != "" {
+		link = delta.Attributes{"link": x.link}
+	}
+	if link != nil {
+		attrs["link"] = link["link"]
+	}
+	if len(attrs) == 0 {
+		attrs = nil
+	}
+	for i := range r.Nodes {
+		c := &r.Nodes[i]
+		switch c.XMLName.Local {
+		case "fldChar":
+			switch c.attr("fldCharType") {
+			case "begin":
+				x.inField, x.instr = true, ""
+			case "separate":
+				// the runs after this one are the text of the field
+				x.inField = false
+				x.link = fieldLink(x.instr)
+			case "end":
+				x.inField, x.link = false, ""
+			}
+		case "instrText":
+			x.instr += c.Text
+		case "t":
+			if !x.inField {
+				x.doc.Insert(c.Text, attrs)
+			}
+		case "tab":
+			x.doc.Insert("\t", attrs)
+		case "noBreakHyphen":
+			x.doc.Insert("-", attrs)
+		case "br":
+			// page and column breaks have no place in a delta
+			if typ := c.attr("type"); typ == "" || typ == "textWrapping" {
+				x.doc.Insert("\n", block)
+			}
+		case "cr":
+			x.doc.Insert("\n", block)
+		case "drawing", "pict":
+			x.image(c, attrs)
+		case "AlternateContent":
+			if choice := c.child("Choice"); choice != nil {
+				x.run(&node{Nodes: choice.Nodes}, link, block)
+			}
+		}
+	}
+}
+
+// runAttributes returns the inline formats of a run
+func runAttributes(rPr *node) delta.Attributes {
+	attrs := delta.Attributes{}
+	if rPr == nil {
+		return attrs
+	}
+	if rPr.val("rStyle") == "CodeChar" {
+		attrs["code"] = true
+	}
+	for _, f := range [][2]string{{"b", "bold"}, {"i", "italic"}, {"strike", "strike"}, {"dstrike", "strike"}} {
+		if rPr.on(f[0]) {
+			attrs[f[1]] = true
+		}
+	}
+	if u := rPr.child("u"); u != nil && u.attr("val") != "none" {
+		attrs["underline"] = true
+	}
+	if color := rPr.val("color"); len(color) == 6 {
+		if c, ok := delta.NormalizeColor("#" + color); ok {
+			attrs["color"] = c
+		}
+	}
+	if fill := rPr.child("shd").attr("fill"); len(fill) == 6 {
+		if c, ok := delta.NormalizeColor("#" + fill); ok {
+			attrs["background"] = c
+		}
+	} else if c, ok := highlights[rPr.val("highlight")]; ok {
+		attrs["background"] = c
+	}
+	if font := rPr.child("rFonts").attr("ascii"); font != "" {
+		for name, f := range model.Fonts {
+			if strings.EqualFold(f, font) {
+				attrs["font"] = name
+			}
+		}
+	}
+	if sz, ok := rPr.child("sz").attrInt("val"); ok {
+		switch {
+		case sz < 20:
+			attrs["size"] = "small"
+		case sz >= 40:
+			attrs["size"] = "huge"
+		case sz >= 28:
+			attrs["size"] = "large"
+		}
+	}
+	switch rPr.val("vertAlign") {
+	case "superscript":
+		attrs["script"] = "super"
+	case "subscript":
+		attrs["script"] = "sub"
+	}
+	return attrs
+}
+
+// highlights are the colors of Word's highlight names
+var highlights = map[string]string{
+	"black": "#000000", "blue": "#0000ff", "cyan": "#00ffff", "green": "#00ff00",
+	"magenta": "#ff00ff", "red": "#ff0000", "yellow": "#ffff00", "white": "#ffffff",
+	"darkBlue": "#000080", "darkCyan": "#008080", "darkGreen": "#008000", "darkMagenta": "#800080",
+	"darkRed": "#800000", "darkYellow": "#808000", "darkGray": "#808080", "lightGray": "#c0c0c0",
+}
+
+// image inserts the image of a drawing, or of a legacy VML picture
+func (x *importer) image(n *node, attrs delta.Attributes) {
+	id := ""
+	external := false
+	if blip := n.find("blip"); blip != nil {
+		id = blip.attr("embed")
+		if id == "" {
+			id, external = blip.attr("link"), true
+		}
+	} else if data := n.find("imagedata"); data != nil {
+		id = data.attr("id")
+	}
+	rel, ok := x.rels[id]
+	if !ok {
+		return
+	}
+	embed := delta.Attributes{}
+	if link, ok := attrs.String("link"); ok {
+		embed["link"] = link
+	}
+	if docPr := n.find("docPr"); docPr != nil {
+		if alt := docPr.attr("descr"); alt != "" {
+			embed["alt"] = alt
+		}
+	}
+
+	url := rel.Target
+	if external || rel.External {
+		if !urls.ValidURL(url, true) {
+			return
+		}
+	} else {
+		name := x.partName(rel.Target)
+		var img *model.Image
+		url, img = x.store(name)
+		if url == "" {
+			return
+		}
+		if cx, ok := n.find("extent").attrInt("cx"); ok && cx > 0 {
+			width := (cx + emuPerPixel/2) / emuPerPixel
+			if w, _, ok := img.Size(); !ok || w != width {
+				embed["width"] = strconv.Itoa(width)
+			}
+		}
+	}
+	if len(embed) == 0 {
+		embed = nil
+	}
+	x.doc.InsertEmbed(delta.Embed{Key: "image", Value: url}, embed)
+}
+
+// store gives an image part to the sink, once
+func (x *importer) store(name string) (string, *model.Image) {
+	data, err := x.read(name)
+	if err != nil || data == nil {
+		if x.err == nil {
+			x.err = err
+		}
+		return "", nil
+	}
+	img := &model.Image{Data: data, ContentType: http.DetectContentType(data)}
+	for ct, ext := range imageTypes {
+		if strings.EqualFold(path.Ext(name), "."+ext) {
+			img.ContentType = ct
+		}
+	}
+	if url, ok := x.images[name]; ok {
+		return url, img
+	}
+	url, err := x.sink.StoreImage(strings.TrimPrefix(name, x.dir+"/"), img)
+	if err != nil && x.err == nil {
+		x.err = err
+	}
+	x.images[name] = url
+	return url, img
+}
@@ -0,0 +1,191 @@
+package docx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+	"github.com/fmpwizard/go-quilljs-delta/model"
+)
+
+func importBytes(t *testing.T, i *Importer, data []byte) *delta.Delta {
+	t.Helper()
+	d, err := i.Import(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestImportExported(t *testing.T) {
+	img := "data:image/png;base64," + base64.StdEncoding.EncodeToString(testPNG(30, 20))
+	doc := delta.New(nil).
+		Insert("Title", nil).Insert("\n", delta.Attributes{"header": 1}).
+		Insert("Some ", nil).Insert("bold", delta.Attributes{"bold": true, "color": "#ff0000"}).
+		Insert(" ", nil).Insert("linked", delta.Attributes{"link": "https://example.com/", "italic": true}).
+		Insert(" ", nil).Insert("code", delta.Attributes{"code": true}).
+		Insert(" ", nil).Insert("big", delta.Attributes{"size": "large", "underline": true, "strike": true}).
+		Insert("x", nil).Insert("2", delta.Attributes{"script": "super", "background": "#ffff00"}).
+		Insert("\tserif", delta.Attributes{"font": "serif"}).
+		Insert("\n", delta.Attributes{"align": "center", "direction": "rtl"}).
+		Insert("indented", nil).Insert("\n", delta.Attributes{"indent": 2}).
+		Insert("one", nil).Insert("\n", delta.Attributes{"list": "ordered"}).
+		Insert("nested", nil).Insert("\n", delta.Attributes{"list": "bullet", "indent": 1}).
+		Insert("two", nil).Insert("\n", delta.Attributes{"list": "ordered"}).
+		Insert("todo", nil).Insert("\n", delta.Attributes{"list": "unchecked"}).
+		Insert("done", nil).Insert("\n", delta.Attributes{"list": "checked", "indent": 1}).
+		Insert("x := 1", nil).Insert("\n", delta.Attributes{"code-block": true}).
+		Insert("quoted", nil).Insert("\n", delta.Attributes{"blockquote": true}).
+		Insert("Sub", nil).Insert("\n", delta.Attributes{"header": 3}).
+		InsertEmbed(delta.Embed{Key: "image", Value: img}, delta.Attributes{"alt": "a picture"}).
+		InsertEmbed(delta.Embed{Key: "image", Value: img}, delta.Attributes{"width": "15"}).
+		Insert("\n", nil)
+
+	var buf bytes.Buffer
+	if err := Export(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	images := 0
+	i := &Importer{Images: ImageSinkFunc(func(name string, img *model.Image) (string, error) {
+		images++
+		return img.DataURL(), nil
+	})}
+	got := importBytes(t, i, buf.Bytes())
+	// numbers come back as float64, like in the stored JSON
+	data, _ := json.Marshal(doc)
+	stored, _ := delta.FromJSON(data)
+	if !reflect.DeepEqual(got, stored) {
+		t.Errorf("import doesn't match the exported delta\ngot:      %v\nexpected: %v", got.Ops, stored.Ops)
+	}
+	if images != 2 {
+		t.Errorf("expected 2 images stored, got %d", images)
+	}
+}
+
+// docxFile builds a docx file with the given document body, styles and
+// numbering
+func docxFile(t *testing.T, body, styles, numbering string, media map[string][]byte) []byte {
+	var buf bytes.Buffer
+	z := zip.NewWriter(&buf)
+	parts := map[string]string{
+		"_rels/.rels": xmlHeader + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="` + relDocument + `" Target="/word/main.xml"/></Relationships>`,
+		"word/_rels/main.xml.rels": xmlHeader + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="` + relStyles + `" Target="s.xml"/>` +
+			`<Relationship Id="rId2" Type="` + relNumbering + `" Target="n.xml"/>` +
+			`<Relationship Id="rId3" Type="` + relHyperlink + `" Target="https://example.com/" TargetMode="External"/>` +
+			`<Relationship Id="rId4" Type="` + relImage + `" Target="media/a.png"/>` +
+			`<Relationship Id="rId5" Type="` + relHyperlink + `" Target="javascript:alert(1)" TargetMode="External"/>` +
+			`<Relationship Id="rId6" Type="` + relImage + `" Target="javascript:alert(1)" TargetMode="External"/>` +
+			`<Relationship Id="rId7" Type="` + relImage + `" Target="https://example.com/b.png" TargetMode="External"/></Relationships>`,
+		"word/main.xml": `<w:document xmlns:w="` + nsW + `" xmlns:r="` + nsR + `" xmlns:wp="` + nsWP + `" xmlns:a="` + nsA +
+			`"><w:body>` + body + `</w:body></w:document>`,
+		"word/s.xml": `<w:styles xmlns:w="` + nsW + `">` + styles + `</w:styles>`,
+		"word/n.xml": `<w:numbering xmlns:w="` + nsW + `">` + numbering + `</w:numbering>`,
+	}
+	for name, data := range media {
+		parts[name] = string(data)
+	}
+	for name, data := range parts {
+		f, _ := z.Create(name)
+		f.Write([]byte(data))
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestImport(t *testing.T) {
+	styles := `<w:style w:type="paragraph" w:styleId="T"><w:name w:val="Title"/></w:style>` +
+		`<w:style w:type="paragraph" w:styleId="Big"><w:name w:val="Big"/><w:pPr><w:outlineLvl w:val="1"/></w:pPr></w:style>` +
+		`<w:style w:type="paragraph" w:styleId="Bigger"><w:name w:val="Bigger"/><w:basedOn w:val="Big"/></w:style>` +
+		`<w:style w:type="paragraph" w:styleId="Bullets"><w:name w:val="Bullets"/><w:pPr><w:numPr><w:numId w:val="7"/></w:numPr></w:pPr></w:style>`
+	numbering := `<w:abstractNum w:abstractNumId="3"><w:lvl w:ilvl="0"><w:numFmt w:val="bullet"/></w:lvl>` +
+		`<w:lvl w:ilvl="1"><w:numFmt w:val="upperRoman"/></w:lvl></w:abstractNum>` +
+		`<w:num w:numId="7"><w:abstractNumId w:val="3"/></w:num>`
+	body := `<w:p><w:pPr><w:pStyle w:val="T"/></w:pPr><w:r><w:t>Title</w:t></w:r></w:p>` +
+		`<w:p><w:pPr><w:pStyle w:val="Bigger"/></w:pPr><w:r><w:t>Section</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:rPr><w:b/><w:i w:val="0"/><w:highlight w:val="yellow"/></w:rPr><w:t xml:space="preserve">a </w:t></w:r>` +
+		`<w:hyperlink r:id="rId3"><w:r><w:t>link</w:t></w:r></w:hyperlink>` +
+		`<w:r><w:tab/><w:t>b</w:t><w:br/><w:t>c</w:t></w:r>` +
+		`<w:r><w:fldChar w:fldCharType="begin"/></w:r><w:r><w:instrText> HYPERLINK "https://example.org/" </w:instrText></w:r>` +
+		`<w:r><w:fldChar w:fldCharType="separate"/></w:r><w:r><w:t>field</w:t></w:r><w:r><w:fldChar w:fldCharType="end"/></w:r>` +
+		`<w:del><w:r><w:delText>gone</w:delText></w:r></w:del><w:ins><w:r><w:t>!</w:t></w:r></w:ins></w:p>` +
+		`<w:p><w:pPr><w:pStyle w:val="Bullets"/></w:pPr><w:r><w:t>item</w:t></w:r></w:p>` +
+		`<w:p><w:pPr><w:numPr><w:ilvl w:val="1"/><w:numId w:val="7"/></w:numPr><w:jc w:val="both"/></w:pPr><w:r><w:t>sub</w:t></w:r></w:p>` +
+		`<w:p><w:pPr><w:pStyle w:val="Bullets"/><w:numPr><w:numId w:val="0"/></w:numPr></w:pPr><w:r><w:t>not a list</w:t></w:r></w:p>` +
+		`<w:tbl><w:tr><w:tc><w:p><w:r><w:t>cell</w:t></w:r></w:p></w:tc></w:tr></w:tbl>` +
+		`<w:p><w:r><w:drawing><wp:inline><wp:extent cx="95250" cy="95250"/><wp:docPr id="1" name="x"/>` +
+		`<a:graphic><a:graphicData><a:blip r:embed="rId4"/></a:graphicData></a:graphic></wp:inline></w:drawing></w:r>` +
+		`<w:r><w:drawing><a:blip r:embed="rId4"/></w:drawing></w:r></w:p>` +
+		`<w:sectPr/>`
+	data := docxFile(t, body, styles, numbering, map[string][]byte{"word/media/a.png": testPNG(10, 10)})
+
+	var names []string
+	i := &Importer{Images: ImageSinkFunc(func(name string, img *model.Image) (string, error) {
+		names = append(names, name+" "+img.ContentType)
+		return "https://cdn.example.com/" + name, nil
+	})}
+	got := importBytes(t, i, data)
+	expected := delta.New(nil).
+		Insert("Title", nil).Insert("\n", delta.Attributes{"header": 1.0}).
+		Insert("Section", nil).Insert("\n", delta.Attributes{"header": 2.0}).
+		Insert("a ", delta.Attributes{"bold": true, "background": "#ffff00"}).
+		Insert("link", delta.Attributes{"link": "https://example.com/"}).
+		Insert("\tb\nc", nil).
+		Insert("field", delta.Attributes{"link": "https://example.org/"}).
+		Insert("!\n", nil).
+		Insert("item", nil).Insert("\n", delta.Attributes{"list": "bullet"}).
+		Insert("sub", nil).Insert("\n", delta.Attributes{"list": "ordered", "indent": 1.0, "align": "justify"}).
+		Insert("not a list\ncell\n", nil).
+		InsertEmbed(delta.Embed{Key: "image", Value: "https://cdn.example.com/media/a.png"}, nil).
+		InsertEmbed(delta.Embed{Key: "image", Value: "https://cdn.example.com/media/a.png"}, nil).
+		Insert("\n", nil)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected import\ngot:      %v\nexpected: %v", got.Ops, expected.Ops)
+	}
+	if !reflect.DeepEqual(names, []string{"media/a.png image/png"}) {
+		t.Errorf("unexpected images stored %v", names)
+	}
+}
+
+func TestImportUnsafe(t *testing.T) {
+	body := `<w:p><w:hyperlink r:id="rId5"><w:r><w:t>a</w:t></w:r></w:hyperlink>` +
+		`<w:fldSimple w:instr=" HYPERLINK &quot;javascript:alert(1)&quot; "><w:r><w:t>b</w:t></w:r></w:fldSimple>` +
+		`<w:r><w:fldChar w:fldCharType="begin"/></w:r><w:r><w:instrText> HYPERLINK " JaVa&#9;script:alert(1)" </w:instrText></w:r>` +
+		`<w:r><w:fldChar w:fldCharType="separate"/></w:r><w:r><w:t>c</w:t></w:r><w:r><w:fldChar w:fldCharType="end"/></w:r>` +
+		`<w:r><w:drawing><a:blip r:link="rId6"/></w:drawing><w:drawing><a:blip r:link="rId7"/></w:drawing></w:r>` +
+		`<w:r><w:t>d</w:t><w:br w:type="page"/><w:br w:type="column"/><w:t>e</w:t><w:br w:type="textWrapping"/><w:t>f</w:t></w:r></w:p>`
+	got := importBytes(t, NewImporter(), docxFile(t, body, "", "", nil))
+	expected := delta.New(nil).
+		Insert("abc", nil).
+		InsertEmbed(delta.Embed{Key: "image", Value: "https://example.com/b.png"}, nil).
+		Insert("de\nf\n", nil)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected import\ngot:      %v\nexpected: %v", got.Ops, expected.Ops)
+	}
+}
+
+func TestImportInvalid(t *testing.T) {
+	if _, err := Import(bytes.NewReader([]byte("nope")), 4); err == nil {
+		t.Errorf("expected an error for a file that is not a zip")
+	}
+	var buf bytes.Buffer
+	z := zip.NewWriter(&buf)
+	f, _ := z.Create("hello.txt")
+	f.Write([]byte("hello"))
+	z.Close()
+	if _, err := Import(bytes.NewReader(buf.Bytes()), int64(buf.Len())); err != ErrInvalidDocx {
+		t.Errorf("expected ErrInvalidDocx, got %v", err)
+	}
+	empty := docxFile(t, "", "", "", nil)
+	got := importBytes(t, NewImporter(), empty)
+	if !reflect.DeepEqual(got, delta.New(nil).Insert("\n", nil)) {
+		t.Errorf("expected an empty document, got %v", got.Ops)
+	}
+}
@@ -0,0 +1,423 @@
+package draftjs
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+	"github.com/fmpwizard/go-quilljs-delta/model"
+)
+
+// headers are the block types of the header levels
+var headers = []string{"header-one", "header-two", "header-three", "header-four", "header-five", "header-six"}
+
+// styles maps the inline styles of Draft.js, and the ones of the usual
+// plugins, to quill formats
+var styles = map[string]struct {
+	format string
+	value  interface{}
+}{
+	"BOLD":          {"bold", true},
+	"ITALIC":        {"italic", true},
+	"UNDERLINE":     {"underline", true},
+	"STRIKETHROUGH": {"strike", true},
+	"CODE":          {"code", true},
+	"SUPERSCRIPT":   {"script", "super"},
+	"SUBSCRIPT":     {"script", "sub"},
+}
+
+// styleOrder is the order inline styles are written in
+var styleOrder = []string{"BOLD", "ITALIC", "UNDERLINE", "STRIKETHROUGH", "CODE", "SUPERSCRIPT", "SUBSCRIPT"}
+
+// imageData are the formats of image embeds kept in the data of image
+// entities
+var imageData = []string{"alt", "width", "height"}
+
+// ToDelta returns the delta of raw. Soft newlines, in the text of blocks,
+// end the line and the text after them gets a line of its own with the same
+// formats. Atomic blocks become image and video embeds, and styles and
+// entities it doesn't know about are left out
+func ToDelta(raw *RawContent) (*delta.Delta, error) {
+	if raw == nil {
+		return nil, ErrNoContent
+	}
+	d := delta.New(nil)
+	for _, b := range raw.Blocks {
+		if b == nil {
+			continue
+		}
+		if b.Type == "atomic" {
+			atomic(d, b, raw.EntityMap)
+			continue
+		}
+		line := lineFormats(b)
+		runes := []rune(b.Text)
+		formats, embeds := inlineFormats(b, runes, raw.EntityMap)
+		for i := 0; i < len(runes); {
+			if embed, ok := embeds[i]; ok {
+				d.InsertEmbed(embed.Embed, embed.attrs)
+				i += embed.length
+				continue
+			}
+			if runes[i] == '\n' {
+				d.Insert("\n", line)
+				i++
+				continue
+			}
+			j := i + 1
+			for j < len(runes) && runes[j] != '\n' && !hasEmbed(embeds, j) && formats[j].Equal(formats[i]) {
+				j++
+			}
+			d.Insert(string(runes[i:j]), formats[i])
+			i = j
+		}
+		d.Insert("\n", line)
+	}
+	if len(d.Ops) == 0 {
+		d.Insert("\n", nil)
+	}
+	return d, nil
+}
+
+// inlineEmbed is an image entity inside the text of a block, it takes the
+// place of length runes
+type inlineEmbed struct {
+	delta.Embed
+	attrs  delta.Attributes
+	length int
+}
+
+func hasEmbed(embeds map[int]inlineEmbed, i int) bool {
+	_, ok := embeds[i]
+	return ok
+}
+
+// lineFormats returns the block formats of b
+func lineFormats(b *Block) delta.Attributes {
+	var attrs delta.Attributes
+	for i, h := range headers {
+		if b.Type == h {
+			return delta.Attributes{"header": i + 1}
+		}
+	}
+	switch b.Type {
+	case "unordered-list-item":
+		attrs = delta.Attributes{"list": "bullet"}
+	case "ordered-list-item":
+		attrs = delta.Attributes{"list": "ordered"}
+	case "checkable-list-item":
+		attrs = delta.Attributes{"list": "unchecked"}
+		if checked, _ := delta.Attributes(b.Data).Bool("checked"); checked {
+			attrs["list"] = "checked"
+		}
+	case "blockquote":
+		return delta.Attributes{"blockquote": true}
+	case "code-block":
+		var lang interface{} = true
+		if s, _ := delta.Attributes(b.Data).String("language"); s != "" {
+			lang = s
+		} else if s, _ := delta.Attributes(b.Data).String("syntax"); s != "" {
+			lang = s
+		}
+		return delta.Attributes{"code-block": lang}
+	default:
+		return nil
+	}
+	if b.Depth > 0 {
+		attrs["indent"] = b.Depth
+	}
+	return attrs
+}
+
+// inlineFormats returns the formats of each rune of a block, and the image
+// entities in its text, by the index of their first rune
+func inlineFormats(b *Block, runes []rune, entities map[string]*Entity) ([]delta.Attributes, map[int]inlineEmbed) {
+	// starts are the UTF-16 offsets where each rune starts
+	starts := make([]int, len(runes)+1)
+	for i, r := range runes {
+		starts[i+1] = starts[i] + utf16Len(r)
+	}
+	span := func(offset, length int) (int, int) {
+		from, to := len(runes), len(runes)
+		for i := len(runes) - 1; i >= 0; i-- {
+			if starts[i] >= offset+length {
+				to = i
+			}
+			if starts[i] >= offset {
+				from = i
+			}
+		}
+		return from, to
+	}
+
+	formats := make([]delta.Attributes, len(runes))
+	for _, r := range b.InlineStyleRanges {
+		style, ok := styles[r.Style]
+		if !ok {
+			continue
+		}
+		from, to := span(r.Offset, r.Length)
+		for i := from; i < to; i++ {
+			formats[i] = formats[i].With(style.format, style.value)
+		}
+	}
+	embeds := make(map[int]inlineEmbed)
+	for _, r := range b.EntityRanges {
+		e := entities[strconv.Itoa(r.Key)]
+		if e == nil {
+			continue
+		}
+		from, to := span(r.Offset, r.Length)
+		switch e.Type {
+		case "LINK":
+			if url := entityURL(e, "url", "href"); url != "" {
+				for i := from; i < to; i++ {
+					formats[i] = formats[i].With("link", url)
+				}
+			}
+		case "IMAGE":
+			if src := entityURL(e, "src", "url"); src != "" && from < to {
+				embeds[from] = inlineEmbed{Embed: delta.Embed{Key: "image", Value: src}, attrs: imageAttributes(e), length: to - from}
+			}
+		}
+	}
+	return formats, embeds
+}
+
+// atomic adds the embed of an atomic block
+func atomic(d *delta.Delta, b *Block, entities map[string]*Entity) {
+	for _, r := range b.EntityRanges {
+		e := entities[strconv.Itoa(r.Key)]
+		if e == nil {
+			continue
+		}
+		switch e.Type {
+		case "IMAGE":
+			if src := entityURL(e, "src", "url"); src != "" {
+				d.InsertEmbed(delta.Embed{Key: "image", Value: src}, imageAttributes(e))
+				d.Insert("\n", nil)
+			}
+		case "VIDEO":
+			if src := entityURL(e, "src", "url"); src != "" {
+				d.InsertEmbed(delta.Embed{Key: "video", Value: src}, nil)
+			}
+		}
+		return
+	}
+}
+
+// entityURL returns the first of keys set in the data of e
+func entityURL(e *Entity, keys ...string) string {
+	for _, k := range keys {
+		if s, _ := e.Data[k].(string); s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+func imageAttributes(e *Entity) delta.Attributes {
+	var attrs delta.Attributes
+	for _, k := range imageData {
+		switch v := e.Data[k].(type) {
+		case string:
+			if v != "" {
+				attrs = attrs.With(k, v)
+			}
+		case float64:
+			attrs = attrs.With(k, strconv.FormatFloat(v, 'f', -1, 64))
+		}
+	}
+	return attrs
+}
+
+// utf16Len returns how many UTF-16 code units r takes
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+// utf16String returns how many UTF-16 code units s takes
+func utf16String(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16Len(r)
+	}
+	return n
+}
+
+// FromDelta returns the raw content of d, an insert only delta. Images
+// become atomic blocks of their own, splitting the line they are in, and
+// formats Draft.js has no style for, like colors or alignment, are left
+// out
+func FromDelta(d *delta.Delta) (*RawContent, error) {
+	parsed, err := model.Parse(d)
+	if err != nil {
+		return nil, err
+	}
+	ex := &exporter{raw: &RawContent{Blocks: []*Block{}, EntityMap: make(map[string]*Entity)}}
+	for _, b := range parsed.Blocks {
+		ex.block(b)
+	}
+	return ex.raw, nil
+}
+
+// exporter holds the state of one FromDelta call
+type exporter struct {
+	raw *RawContent
+}
+
+func (ex *exporter) block(b model.Block) {
+	switch b := b.(type) {
+	case *model.Paragraph:
+		ex.line(b.Runs, "unstyled", 0, nil)
+	case *model.Heading:
+		level := b.Level
+		if level > len(headers) {
+			level = len(headers)
+		}
+		ex.line(b.Runs, headers[level-1], 0, nil)
+	case *model.List:
+		ex.list(b)
+	case *model.Blockquote:
+		for _, l := range b.Lines {
+			ex.line(l.Runs, "blockquote", 0, nil)
+		}
+	case *model.CodeBlock:
+		var data map[string]interface{}
+		if b.Language != "" {
+			data = map[string]interface{}{"language": b.Language}
+		}
+		for _, l := range b.Lines {
+			ex.line([]model.Run{{Text: l.Text()}}, "code-block", 0, data)
+		}
+	case *model.EmbedBlock:
+		if src, _ := b.Embed.Value.(string); src != "" && b.Embed.Key == "video" {
+			ex.atomic("VIDEO", map[string]interface{}{"src": src})
+		}
+	}
+}
+
+func (ex *exporter) list(l *model.List) {
+	for _, item := range l.Items {
+		switch l.Type {
+		case "ordered":
+			ex.line(item.Runs, "ordered-list-item", l.Indent, nil)
+		case "checked":
+			ex.line(item.Runs, "checkable-list-item", l.Indent, map[string]interface{}{"checked": item.Checked})
+		default:
+			ex.line(item.Runs, "unordered-list-item", l.Indent, nil)
+		}
+		for _, child := range item.Children {
+			ex.list(child)
+		}
+	}
+}
+
+func (ex *exporter) newBlock(blockType string, depth int, data map[string]interface{}) *Block {
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+	b := &Block{
+		Key:               key(len(ex.raw.Blocks)),
+		Type:              blockType,
+		Depth:             depth,
+		InlineStyleRanges: []StyleRange{},
+		EntityRanges:      []EntityRange{},
+		Data:              data,
+	}
+	ex.raw.Blocks = append(ex.raw.Blocks, b)
+	return b
+}
+
+// key returns a block key, five characters like the ones of Draft.js
+func key(n int) string {
+	s := strconv.FormatInt(int64(n), 36)
+	if len(s) < 5 {
+		s = strings.Repeat("0", 5-len(s)) + s
+	}
+	return s
+}
+
+func (ex *exporter) entity(entityType, mutability string, data map[string]interface{}) int {
+	n := len(ex.raw.EntityMap)
+	ex.raw.EntityMap[strconv.Itoa(n)] = &Entity{Type: entityType, Mutability: mutability, Data: data}
+	return n
+}
+
+func (ex *exporter) atomic(entityType string, data map[string]interface{}) {
+	b := ex.newBlock("atomic", 0, nil)
+	b.Text = " "
+	b.EntityRanges = append(b.EntityRanges, EntityRange{Offset: 0, Length: 1, Key: ex.entity(entityType, "IMMUTABLE", data)})
+}
+
+// line writes the runs of a line as a block, and its images as atomic
+// blocks after the text before them
+func (ex *exporter) line(runs []model.Run, blockType string, depth int, data map[string]interface{}) {
+	var b *Block
+	var text strings.Builder
+	offset := 0
+	written := false
+	// last is the index of the last range of each style
+	last := make(map[string]int)
+	lastLink := ""
+	flush := func() {
+		if b != nil {
+			b.Text = text.String()
+		}
+		b = nil
+		text.Reset()
+		offset = 0
+		last = make(map[string]int)
+		lastLink = ""
+	}
+	for _, r := range runs {
+		if r.Embed != nil {
+			if src, _ := r.Embed.Value.(string); r.Embed.Key == "image" && src != "" {
+				flush()
+				data := map[string]interface{}{"src": src}
+				for _, k := range imageData {
+					if v, ok := r.Attributes.String(k); ok {
+						data[k] = v
+					}
+				}
+				ex.atomic("IMAGE", data)
+				written = true
+			}
+			continue
+		}
+		if b == nil {
+			b = ex.newBlock(blockType, depth, data)
+			written = true
+		}
+		length := utf16String(r.Text)
+		for _, name := range styleOrder {
+			style := styles[name]
+			if v, ok := r.Attributes[style.format]; !ok || v != style.value {
+				continue
+			}
+			if i, ok := last[name]; ok && b.InlineStyleRanges[i].Offset+b.InlineStyleRanges[i].Length == offset {
+				b.InlineStyleRanges[i].Length += length
+				continue
+			}
+			last[name] = len(b.InlineStyleRanges)
+			b.InlineStyleRanges = append(b.InlineStyleRanges, StyleRange{Offset: offset, Length: length, Style: name})
+		}
+		link, _ := r.Attributes.String("link")
+		if link != "" && link == lastLink {
+			b.EntityRanges[len(b.EntityRanges)-1].Length += length
+		} else if link != "" {
+			n := ex.entity("LINK", "MUTABLE", map[string]interface{}{"url": link})
+			b.EntityRanges = append(b.EntityRanges, EntityRange{Offset: offset, Length: length, Key: n})
+		}
+		lastLink = link
+		text.WriteString(r.Text)
+		offset += length
+	}
+	if b == nil && !written {
+		ex.newBlock(blockType, depth, data)
+	}
+	flush()
+}
@@ -0,0 +1,118 @@
+package draftjs
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+)
+
+func TestRoundTrip(t *testing.T) {
+	doc := delta.New(nil).
+		Insert("Title", nil).Insert("\n", delta.Attributes{"header": 1}).
+		Insert("Some ", nil).Insert("bold", delta.Attributes{"bold": true}).
+		Insert("😀 both", delta.Attributes{"bold": true, "italic": true}).
+		Insert(" ", nil).Insert("linked", delta.Attributes{"link": "https://example.com/", "underline": true}).
+		Insert(" too", delta.Attributes{"link": "https://example.com/"}).
+		Insert(" x", nil).Insert("2", delta.Attributes{"script": "super"}).
+		Insert(" ", nil).Insert("gone", delta.Attributes{"strike": true, "code": true}).
+		Insert("\n\n", nil).
+		Insert("one", nil).Insert("\n", delta.Attributes{"list": "ordered"}).
+		Insert("nested", nil).Insert("\n", delta.Attributes{"list": "bullet", "indent": 1}).
+		Insert("two", nil).Insert("\n", delta.Attributes{"list": "ordered"}).
+		Insert("todo", nil).Insert("\n", delta.Attributes{"list": "unchecked"}).
+		Insert("done", nil).Insert("\n", delta.Attributes{"list": "checked", "indent": 2}).
+		Insert("quoted", nil).Insert("\n", delta.Attributes{"blockquote": true}).
+		Insert("x := 1", nil).Insert("\n", delta.Attributes{"code-block": "go"}).
+		Insert("y", nil).Insert("\n", delta.Attributes{"code-block": true}).
+		InsertEmbed(delta.Embed{Key: "image", Value: "https://example.com/a.png"}, delta.Attributes{"alt": "a", "width": "20"}).
+		Insert("\n", nil).
+		InsertEmbed(delta.Embed{Key: "video", Value: "https://example.com/v"}, nil).
+		Insert("end\n", nil)
+
+	raw, err := FromDelta(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(raw)
+	parsed, err := FromJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ToDelta(parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, doc) {
+		t.Errorf("round trip doesn't match\ngot:      %v\nexpected: %v", got.Ops, doc.Ops)
+	}
+}
+
+func TestFromDelta(t *testing.T) {
+	doc := delta.New(nil).
+		Insert("a😀", delta.Attributes{"bold": true, "color": "#ff0000"}).
+		Insert("b", delta.Attributes{"bold": true, "link": "https://example.com/"}).
+		InsertEmbed(delta.Embed{Key: "image", Value: "a.png"}, nil).
+		Insert("c", nil).
+		Insert("\n", delta.Attributes{"align": "center"})
+	raw, err := FromDelta(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(raw)
+	expected := `{"blocks":[` +
+		`{"key":"00000","text":"a😀b","type":"unstyled","depth":0,"inlineStyleRanges":[{"offset":0,"length":4,"style":"BOLD"}],` +
+		`"entityRanges":[{"offset":3,"length":1,"key":0}],"data":{}},` +
+		`{"key":"00001","text":" ","type":"atomic","depth":0,"inlineStyleRanges":[],"entityRanges":[{"offset":0,"length":1,"key":1}],"data":{}},` +
+		`{"key":"00002","text":"c","type":"unstyled","depth":0,"inlineStyleRanges":[],"entityRanges":[],"data":{}}],` +
+		`"entityMap":{"0":{"type":"LINK","mutability":"MUTABLE","data":{"url":"https://example.com/"}},` +
+		`"1":{"type":"IMAGE","mutability":"IMMUTABLE","data":{"src":"a.png"}}}}`
+	if string(data) != expected {
+		t.Errorf("expected %s\ngot      %s", expected, data)
+	}
+}
+
+func TestToDelta(t *testing.T) {
+	raw, err := FromJSON([]byte(`{"blocks":[
+		{"key":"a","text":"😀 bold\nnext","type":"unordered-list-item","depth":1,
+			"inlineStyleRanges":[{"offset":3,"length":4,"style":"BOLD"},{"offset":0,"length":2,"style":"HIGHLIGHT"}],
+			"entityRanges":[{"offset":8,"length":4,"key":0},{"offset":0,"length":2,"key":3}]},
+		{"key":"b","text":"pic here","type":"unstyled","entityRanges":[{"offset":0,"length":3,"key":1}]},
+		{"key":"c","text":"f(x)\n  y","type":"code-block","data":{"syntax":"js"}},
+		{"key":"d","text":" ","type":"atomic","entityRanges":[{"offset":0,"length":1,"key":2}]},
+		{"key":"e","text":"plain","type":"mystery"}
+	],"entityMap":{
+		"0":{"type":"LINK","mutability":"MUTABLE","data":{"href":"https://example.com/"}},
+		"1":{"type":"IMAGE","mutability":"IMMUTABLE","data":{"src":"a.png","width":30}},
+		"2":{"type":"IMAGE","mutability":"IMMUTABLE","data":{"url":"b.png","alt":"b"}},
+		"3":{"type":"MENTION","mutability":"SEGMENTED","data":{"name":"x"}}
+	}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ToDelta(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := delta.Attributes{"list": "bullet", "indent": 1}
+	code := delta.Attributes{"code-block": "js"}
+	expected := delta.New(nil).
+		Insert("😀 ", nil).Insert("bold", delta.Attributes{"bold": true}).Insert("\n", list).
+		Insert("next", delta.Attributes{"link": "https://example.com/"}).Insert("\n", list).
+		InsertEmbed(delta.Embed{Key: "image", Value: "a.png"}, delta.Attributes{"width": "30"}).
+		Insert(" here\n", nil).
+		Insert("f(x)", nil).Insert("\n", code).Insert("  y", nil).Insert("\n", code).
+		InsertEmbed(delta.Embed{Key: "image", Value: "b.png"}, delta.Attributes{"alt": "b"}).
+		Insert("\nplain\n", nil)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected delta\ngot:      %v\nexpected: %v", got.Ops, expected.Ops)
+	}
+
+	if _, err := ToDelta(nil); err != ErrNoContent {
+		t.Errorf("expected ErrNoContent, got %v", err)
+	}
+	if got, _ := ToDelta(&RawContent{}); !reflect.DeepEqual(got, delta.New(nil).Insert("\n", nil)) {
+		t.Errorf("expected an empty document, got %v", got.Ops)
+	}
+}
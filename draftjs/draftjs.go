@@ -0,0 +1,60 @@
+// Package draftjs converts between Quill documents, insert only deltas, and
+// the raw content state of Draft.js editors, what convertToRaw returns and
+// convertFromRaw takes.
+package draftjs
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// ErrNoContent is returned when converting a nil RawContent
+var ErrNoContent = errors.New("draftjs: no content")
+
+// RawContent is the raw content state of an editor
+type RawContent struct {
+	Blocks    []*Block           `json:"blocks"`
+	EntityMap map[string]*Entity `json:"entityMap"`
+}
+
+// Block is a line of text. The offsets and lengths of its ranges count
+// UTF-16 code units, like the length of JavaScript strings
+type Block struct {
+	Key               string                 `json:"key"`
+	Text              string                 `json:"text"`
+	Type              string                 `json:"type"`
+	Depth             int                    `json:"depth"`
+	InlineStyleRanges []StyleRange           `json:"inlineStyleRanges"`
+	EntityRanges      []EntityRange          `json:"entityRanges"`
+	Data              map[string]interface{} `json:"data"`
+}
+
+// StyleRange is text with an inline style, like BOLD
+type StyleRange struct {
+	Offset int    `json:"offset"`
+	Length int    `json:"length"`
+	Style  string `json:"style"`
+}
+
+// EntityRange is text that belongs to the entity Key of the entity map
+type EntityRange struct {
+	Offset int `json:"offset"`
+	Length int `json:"length"`
+	Key    int `json:"key"`
+}
+
+// Entity is a link, an image or any other data attached to text
+type Entity struct {
+	Type       string                 `json:"type"`
+	Mutability string                 `json:"mutability"`
+	Data       map[string]interface{} `json:"data"`
+}
+
+// FromJSON parses the JSON of a raw content state
+func FromJSON(in []byte) (*RawContent, error) {
+	var raw RawContent
+	if err := json.Unmarshal(in, &raw); err != nil {
+		return nil, err
+	}
+	return &raw, nil
+}
@@ -0,0 +1,23 @@
+package draftjs
+
+import "testing"
+
+func TestFromJSON(t *testing.T) {
+	raw, err := FromJSON([]byte(`{"blocks":[{"key":"a","text":"hi","type":"unstyled","depth":0,` +
+		`"inlineStyleRanges":[{"offset":0,"length":2,"style":"BOLD"}],"entityRanges":[],"data":{}}],"entityMap":{}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(raw.Blocks) != 1 || raw.Blocks[0].Text != "hi" || raw.Blocks[0].InlineStyleRanges[0].Style != "BOLD" {
+		t.Errorf("unexpected content %+v", raw.Blocks)
+	}
+	if _, err := FromJSON([]byte(`{"blocks":`)); err == nil {
+		t.Errorf("expected an error for invalid JSON")
+	}
+}
+
+func TestUTF16(t *testing.T) {
+	if n := utf16String("a😀é"); n != 4 {
+		t.Errorf("expected 4 code units, got %d", n)
+	}
+}
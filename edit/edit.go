@@ -0,0 +1,249 @@
+// Package edit builds the change deltas for common editing commands, the
+// same way quill's editor API does. Every function takes the current
+// document, an insert only Delta, and returns the change to Compose into
+// it, or to send to the clients. The document is never modified.
+//
+// Like quill, the final newline of a document is never deleted and nothing
+// is inserted after it, indexes past it are moved before it.
+package edit
+
+import (
+	"strings"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+)
+
+// Editor builds the change deltas of the commands that depend on what the
+// formats are. Use NewEditor to get quill's formats
+type Editor struct {
+	// Schema tells inline formats from block formats. Formats it doesn't
+	// know about, or all of them when it is nil, are accepted by both
+	// FormatText and FormatLine
+	Schema *delta.Schema
+}
+
+// NewEditor returns an Editor with delta.DefaultSchema()
+func NewEditor() *Editor {
+	return &Editor{Schema: delta.DefaultSchema()}
+}
+
+// InsertText inserts text at index with the given formats. Windows and old
+// Mac line endings are turned into \n
+func InsertText(doc *delta.Delta, index int, text string, formats delta.Attributes) *delta.Delta {
+	text = strings.Replace(text, "\r\n", "\n", -1)
+	text = strings.Replace(text, "\r", "\n", -1)
+	return delta.New(nil).Retain(clampIndex(doc, index), nil).Insert(text, formats.Clone()).Chop()
+}
+
+// InsertEmbed inserts embed at index with the given formats
+func InsertEmbed(doc *delta.Delta, index int, embed delta.Embed, formats delta.Attributes) *delta.Delta {
+	return delta.New(nil).Retain(clampIndex(doc, index), nil).InsertEmbed(embed, formats.Clone()).Chop()
+}
+
+// DeleteRange deletes length characters starting at index
+func DeleteRange(doc *delta.Delta, index, length int) *delta.Delta {
+	index, length = clampRange(doc, index, length)
+	return delta.New(nil).Retain(index, nil).Delete(length).Chop()
+}
+
+// FormatText formats text with NewEditor()
+func FormatText(doc *delta.Delta, index, length int, formats delta.Attributes) *delta.Delta {
+	return NewEditor().FormatText(doc, index, length, formats)
+}
+
+// FormatText applies inline formats to the characters between index and
+// index+length. Block formats are ignored, use FormatLine for those, and
+// newlines are skipped since they only carry block formats. Use a nil value
+// to remove a format
+func (e *Editor) FormatText(doc *delta.Delta, index, length int, formats delta.Attributes) *delta.Delta {
+	index, length = clampRange(doc, index, length)
+	formats = e.only(formats, delta.ScopeBlock)
+	change := delta.New(nil).Retain(index, nil)
+	if formats == nil {
+		return change.Chop()
+	}
+	for _, op := range doc.Slice(index, index+length).Ops {
+		if op.InsertEmbed != nil {
+			change.Retain(1, formats)
+			continue
+		}
+		eachRun(op.Insert, func(n int, newline bool) {
+			if newline {
+				change.Retain(n, nil)
+			} else {
+				change.Retain(n, formats)
+			}
+		})
+	}
+	return change.Chop()
+}
+
+// FormatLine formats lines with NewEditor()
+func FormatLine(doc *delta.Delta, index, length int, formats delta.Attributes) *delta.Delta {
+	return NewEditor().FormatLine(doc, index, length, formats)
+}
+
+// FormatLine applies block formats to every line touched by the range
+// between index and index+length, even if length is 0. Setting a format
+// removes the ones that are exclusive with it, like quill does when you turn
+// a list item into a header
+func (e *Editor) FormatLine(doc *delta.Delta, index, length int, formats delta.Attributes) *delta.Delta {
+	index, length = clampRange(doc, index, length)
+	formats = e.only(formats, delta.ScopeInline)
+	change := delta.New(nil)
+	if formats == nil {
+		return change
+	}
+	pos := 0
+	for _, nl := range linesIn(doc, index, length) {
+		attrs := formats
+		for k, v := range formats {
+			if v == nil {
+				continue
+			}
+			f, found := e.lookup(k)
+			if !found {
+				continue
+			}
+			for _, e := range f.Exclusive {
+				if nl.attrs.Has(e) && !formats.Has(e) {
+					attrs = attrs.With(e, nil)
+				}
+			}
+		}
+		change.Retain(nl.index-pos, nil).Retain(1, attrs)
+		pos = nl.index + 1
+	}
+	return change.Chop()
+}
+
+// RemoveFormat removes every inline format between index and index+length
+// and the block formats of the lines the range touches
+func RemoveFormat(doc *delta.Delta, index, length int) *delta.Delta {
+	index, length = clampRange(doc, index, length)
+	change := delta.New(nil).Retain(index, nil)
+	for _, op := range doc.Slice(index, index+length).Ops {
+		change.Retain(op.Length(), removeAll(op.Attributes))
+	}
+	// the line where the range ends loses its block formats too
+	lines := linesIn(doc, index, length)
+	if len(lines) > 0 {
+		last := lines[len(lines)-1]
+		if last.index >= index+length {
+			change.Retain(last.index-index-length, nil).Retain(1, removeAll(last.attrs))
+		}
+	}
+	return change.Chop()
+}
+
+type newline struct {
+	index int
+	attrs delta.Attributes
+}
+
+// linesIn returns the newlines of the lines touched by the range. A line is
+// touched when it shares a character with the range, or holds the cursor
+// when length is 0
+func linesIn(doc *delta.Delta, index, length int) []newline {
+	end := index + length
+	if length == 0 {
+		end = index + 1
+	}
+	var ret []newline
+	pos := 0
+	prev := -1
+	for _, op := range doc.Ops {
+		if op.Insert == nil {
+			pos += op.Length()
+			continue
+		}
+		for _, r := range op.Insert {
+			if r == '\n' {
+				// the line goes from prev+1 to pos
+				if pos >= index && prev < end-1 {
+					ret = append(ret, newline{index: pos, attrs: op.Attributes})
+				}
+				if pos >= end-1 {
+					return ret
+				}
+				prev = pos
+			}
+			pos++
+		}
+	}
+	return ret
+}
+
+// eachRun calls fn for every run of newlines or other characters in text
+func eachRun(text []rune, fn func(n int, newline bool)) {
+	start := 0
+	for start < len(text) {
+		nl := text[start] == '\n'
+		end := start + 1
+		for end < len(text) && (text[end] == '\n') == nl {
+			end++
+		}
+		fn(end-start, nl)
+		start = end
+	}
+}
+
+// only returns the formats that are not of the excluded scope
+func (e *Editor) only(formats delta.Attributes, excluded delta.Scope) delta.Attributes {
+	ret := formats
+	for k := range formats {
+		if f, found := e.lookup(k); found && f.Scope == excluded {
+			ret = ret.Without(k)
+		}
+	}
+	return ret.Clone()
+}
+
+func (e *Editor) lookup(name string) (delta.Format, bool) {
+	if e.Schema == nil {
+		return delta.Format{}, false
+	}
+	return e.Schema.Lookup(name)
+}
+
+func removeAll(attrs delta.Attributes) delta.Attributes {
+	var ret delta.Attributes
+	for k := range attrs {
+		ret = ret.With(k, nil)
+	}
+	return ret
+}
+
+// maxIndex is the last index we can insert at, before the final newline
+func maxIndex(doc *delta.Delta) int {
+	length := doc.Length()
+	if length == 0 {
+		return 0
+	}
+	last := doc.Slice(length-1, length)
+	if op := last.Ops[0]; op.Insert != nil && op.Insert[0] == '\n' {
+		return length - 1
+	}
+	return length
+}
+
+func clampIndex(doc *delta.Delta, index int) int {
+	if index < 0 {
+		return 0
+	}
+	if max := maxIndex(doc); index > max {
+		return max
+	}
+	return index
+}
+
+func clampRange(doc *delta.Delta, index, length int) (int, int) {
+	index = clampIndex(doc, index)
+	if length < 0 {
+		length = 0
+	}
+	if max := maxIndex(doc); index+length > max {
+		length = max - index
+	}
+	return index, length
+}
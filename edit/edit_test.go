@@ -0,0 +1,149 @@
+package edit
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+)
+
+func document() *delta.Delta {
+	return delta.New(nil).
+		Insert("Title", nil).
+		Insert("\n", delta.Attributes{"header": 1}).
+		Insert("Hello ", nil).
+		Insert("World", delta.Attributes{"bold": true}).
+		Insert("\n", delta.Attributes{"list": "bullet"}).
+		Insert("Last line\n", nil)
+}
+
+func check(t *testing.T, name string, change, expectedChange, expectedDoc *delta.Delta) {
+	t.Helper()
+	if !reflect.DeepEqual(change.Ops, expectedChange.Ops) {
+		a, _ := json.Marshal(change)
+		b, _ := json.Marshal(expectedChange)
+		t.Errorf("%s: unexpected change\ngot:      %s\nexpected: %s", name, a, b)
+	}
+	got := document().Compose(*change)
+	if !reflect.DeepEqual(got.Ops, expectedDoc.Ops) {
+		a, _ := json.Marshal(got)
+		b, _ := json.Marshal(expectedDoc)
+		t.Errorf("%s: unexpected document\ngot:      %s\nexpected: %s", name, a, b)
+	}
+}
+
+func TestInsertText(t *testing.T) {
+	check(t, "insert", InsertText(document(), 6, "Oh\r\n", delta.Attributes{"italic": true}),
+		delta.New(nil).Retain(6, nil).Insert("Oh\n", delta.Attributes{"italic": true}),
+		delta.New(nil).
+			Insert("Title", nil).
+			Insert("\n", delta.Attributes{"header": 1}).
+			Insert("Oh\n", delta.Attributes{"italic": true}).
+			Insert("Hello ", nil).
+			Insert("World", delta.Attributes{"bold": true}).
+			Insert("\n", delta.Attributes{"list": "bullet"}).
+			Insert("Last line\n", nil))
+	// past the end goes before the final newline
+	change := InsertText(document(), 100, "!", nil)
+	if l := document().Length(); *change.Ops[0].Retain != l-1 {
+		t.Errorf("expected to insert before the last newline, got %+v", change.Ops)
+	}
+}
+
+func TestInsertEmbed(t *testing.T) {
+	change := InsertEmbed(document(), 0, delta.Embed{Key: "image", Value: "a.png"}, delta.Attributes{"width": "10"})
+	expected := delta.New(nil).InsertEmbed(delta.Embed{Key: "image", Value: "a.png"}, delta.Attributes{"width": "10"})
+	if !reflect.DeepEqual(change.Ops, expected.Ops) {
+		t.Errorf("unexpected change %+v", change.Ops)
+	}
+}
+
+func TestDeleteRange(t *testing.T) {
+	check(t, "delete", DeleteRange(document(), 12, 6),
+		delta.New(nil).Retain(12, nil).Delete(6),
+		delta.New(nil).
+			Insert("Title", nil).
+			Insert("\n", delta.Attributes{"header": 1}).
+			Insert("Hello Last line\n", nil))
+	change := DeleteRange(document(), 18, 100)
+	if *change.Ops[1].Delete != 9 {
+		t.Errorf("expected to keep the last newline, got %+v", change.Ops)
+	}
+}
+
+func TestFormatText(t *testing.T) {
+	check(t, "format", FormatText(document(), 3, 6, delta.Attributes{"bold": true, "header": 2}),
+		delta.New(nil).Retain(3, nil).Retain(2, delta.Attributes{"bold": true}).Retain(1, nil).
+			Retain(3, delta.Attributes{"bold": true}),
+		delta.New(nil).
+			Insert("Tit", nil).
+			Insert("le", delta.Attributes{"bold": true}).
+			Insert("\n", delta.Attributes{"header": 1}).
+			Insert("Hel", delta.Attributes{"bold": true}).
+			Insert("lo ", nil).
+			Insert("World", delta.Attributes{"bold": true}).
+			Insert("\n", delta.Attributes{"list": "bullet"}).
+			Insert("Last line\n", nil))
+	if change := FormatText(document(), 0, 3, delta.Attributes{"list": "ordered"}); len(change.Ops) != 0 {
+		t.Errorf("block formats should be ignored, got %+v", change.Ops)
+	}
+}
+
+func TestFormatLine(t *testing.T) {
+	// from the middle of the title to the middle of the list item
+	check(t, "format line", FormatLine(document(), 2, 6, delta.Attributes{"align": "center", "bold": true}),
+		delta.New(nil).Retain(5, nil).Retain(1, delta.Attributes{"align": "center"}).
+			Retain(11, nil).Retain(1, delta.Attributes{"align": "center"}),
+		delta.New(nil).
+			Insert("Title", nil).
+			Insert("\n", delta.Attributes{"header": 1, "align": "center"}).
+			Insert("Hello ", nil).
+			Insert("World", delta.Attributes{"bold": true}).
+			Insert("\n", delta.Attributes{"list": "bullet", "align": "center"}).
+			Insert("Last line\n", nil))
+	// a cursor, and header replaces list
+	check(t, "exclusive", FormatLine(document(), 8, 0, delta.Attributes{"header": 2}),
+		delta.New(nil).Retain(17, nil).Retain(1, delta.Attributes{"header": 2, "list": nil}),
+		delta.New(nil).
+			Insert("Title", nil).
+			Insert("\n", delta.Attributes{"header": 1}).
+			Insert("Hello ", nil).
+			Insert("World", delta.Attributes{"bold": true}).
+			Insert("\n", delta.Attributes{"header": 2}).
+			Insert("Last line\n", nil))
+	// ending right after a newline doesn't touch the next line
+	change := FormatLine(document(), 0, 6, delta.Attributes{"align": "right"})
+	if len(change.Ops) != 2 {
+		t.Errorf("expected only the title to change, got %+v", change.Ops)
+	}
+}
+
+func TestEditorSchema(t *testing.T) {
+	// without a schema every format is applied, to text and lines alike
+	e := &Editor{}
+	check(t, "no schema", e.FormatText(document(), 0, 2, delta.Attributes{"header": 2}),
+		delta.New(nil).Retain(2, delta.Attributes{"header": 2}),
+		delta.New(nil).
+			Insert("Ti", delta.Attributes{"header": 2}).
+			Insert("tle", nil).
+			Insert("\n", delta.Attributes{"header": 1}).
+			Insert("Hello ", nil).
+			Insert("World", delta.Attributes{"bold": true}).
+			Insert("\n", delta.Attributes{"list": "bullet"}).
+			Insert("Last line\n", nil))
+	if change := e.FormatLine(document(), 8, 0, delta.Attributes{"header": 2}); len(change.Ops) != 2 || change.Ops[1].Attributes.Has("list") {
+		t.Errorf("expected no exclusive formats without a schema, got %+v", change.Ops)
+	}
+}
+
+func TestRemoveFormat(t *testing.T) {
+	check(t, "remove", RemoveFormat(document(), 3, 10),
+		delta.New(nil).Retain(3, nil).Retain(2, nil).Retain(1, delta.Attributes{"header": nil}).
+			Retain(6, nil).Retain(1, delta.Attributes{"bold": nil}).
+			Retain(4, nil).Retain(1, delta.Attributes{"list": nil}),
+		delta.New(nil).
+			Insert("Title\nHello W", nil).
+			Insert("orld", delta.Attributes{"bold": true}).
+			Insert("\nLast line\n", nil))
+}
@@ -0,0 +1,235 @@
+package edit
+
+import (
+	"regexp"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+)
+
+// objectReplacement stands for embeds in the text, so indexes match the
+// document. It is never searched, embeds split the document like formats do
+const objectReplacement = '\uFFFC'
+
+// Query describes what Find and Replace look for
+type Query struct {
+	// Pattern is the text to find, or a regular expression (RE2 syntax)
+	// when Regexp is true
+	Pattern string
+	Regexp  bool
+	// IgnoreCase uses Unicode case folding
+	IgnoreCase bool
+	// Formats restricts matches to text that has all these formats. A nil
+	// value means the text must not have that format. Text with other
+	// formats splits the document, a match never crosses it
+	Formats delta.Attributes
+}
+
+// Match is a piece of the document that matched a Query
+type Match struct {
+	Index  int
+	Length int
+	Text   string
+	// submatches holds byte offsets inside the searched segment, needed to
+	// expand $1 style references in replacements
+	segment    string
+	submatches []int
+}
+
+// span is a run of newlines or other characters of an op of the document,
+// with its position
+type span struct {
+	start, end int
+	attrs      delta.Attributes
+	// skip is true for the text that is never searched: embeds, newlines
+	// with block formats and the final newline
+	skip bool
+}
+
+// Find returns all the non overlapping matches of q in doc, in order.
+// Matches can cross ops with different formats, but never an embed or a
+// newline with block formats, and the final newline is never matched, so a
+// replacement can't delete images, the formats of a line or the end of the
+// document
+func Find(doc *delta.Delta, q Query) ([]Match, error) {
+	re, err := q.compile()
+	if err != nil {
+		return nil, err
+	}
+	text, spans := flatten(doc)
+	var ret []Match
+	for _, seg := range segments(spans, q.Formats) {
+		s := string(text[seg.start:seg.end])
+		// byte offset to rune offset inside the segment
+		runeAt := make([]int, len(s)+1)
+		r := 0
+		for i := range s {
+			runeAt[i] = r
+			r++
+		}
+		runeAt[len(s)] = r
+		for _, loc := range re.FindAllStringSubmatchIndex(s, -1) {
+			if loc[0] == loc[1] {
+				continue
+			}
+			start := seg.start + runeAt[loc[0]]
+			end := seg.start + runeAt[loc[1]]
+			ret = append(ret, Match{
+				Index:      start,
+				Length:     end - start,
+				Text:       s[loc[0]:loc[1]],
+				segment:    s,
+				submatches: loc,
+			})
+		}
+	}
+	return ret, nil
+}
+
+// Replace replaces every match of q with replacement and returns the change
+// delta, ready to Compose into doc, and the matches. With Regexp queries,
+// replacement can use $1 or ${name} to refer to submatches.
+//
+// The replacement text takes the formats of the first character it
+// replaces. When the whole match has the same formats, only the characters
+// that actually change are deleted and inserted, so replacing "colour" with
+// "color" deletes one character
+func Replace(doc *delta.Delta, q Query, replacement string) (*delta.Delta, []Match, error) {
+	matches, err := Find(doc, q)
+	if err != nil || len(matches) == 0 {
+		return delta.New(nil), matches, err
+	}
+	re, _ := q.compile()
+	_, spans := flatten(doc)
+	change := delta.New(nil)
+	pos := 0
+	for _, m := range matches {
+		repl := replacement
+		if q.Regexp {
+			repl = string(re.ExpandString(nil, replacement, m.segment, m.submatches))
+		}
+		old := []rune(m.Text)
+		newText := []rune(repl)
+		attrs := attributesAt(spans, m.Index)
+		if len(old) > 0 && old[0] == '\n' {
+			// newlines only carry block formats
+			attrs = nil
+		}
+		start, oldEnd, newEnd := 0, len(old), len(newText)
+		if uniform(spans, m.Index, m.Length) {
+			for start < oldEnd && start < newEnd && old[start] == newText[start] {
+				start++
+			}
+			for oldEnd > start && newEnd > start && old[oldEnd-1] == newText[newEnd-1] {
+				oldEnd--
+				newEnd--
+			}
+		}
+		change.Retain(m.Index+start-pos, nil).
+			Insert(string(newText[start:newEnd]), attrs.Clone()).
+			Delete(oldEnd - start)
+		pos = m.Index + oldEnd
+	}
+	return change.Chop(), matches, nil
+}
+
+func (q Query) compile() (*regexp.Regexp, error) {
+	pattern := q.Pattern
+	if !q.Regexp {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if q.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+// flatten returns the text of the document, with embeds replaced by U+FFFC,
+// and its spans
+func flatten(doc *delta.Delta) ([]rune, []span) {
+	var text []rune
+	var spans []span
+	for _, op := range doc.Ops {
+		if op.InsertEmbed != nil {
+			text = append(text, objectReplacement)
+			spans = append(spans, span{start: len(text) - 1, end: len(text), attrs: op.Attributes, skip: true})
+			continue
+		}
+		i := 0
+		eachRun(op.Insert, func(n int, newline bool) {
+			start := len(text)
+			for _, r := range op.Insert[i : i+n] {
+				if !utf8.ValidRune(r) {
+					r = utf8.RuneError
+				}
+				text = append(text, r)
+			}
+			i += n
+			spans = append(spans, span{start: start, end: len(text), attrs: op.Attributes, skip: newline && len(op.Attributes) > 0})
+		})
+	}
+	if n := len(spans); n > 0 && !spans[n-1].skip && text[len(text)-1] == '\n' {
+		// the final newline gets a span of its own
+		last := spans[n-1]
+		spans[n-1].end--
+		if spans[n-1].start == spans[n-1].end {
+			spans = spans[:n-1]
+		}
+		spans = append(spans, span{start: last.end - 1, end: last.end, attrs: last.attrs, skip: true})
+	}
+	return text, spans
+}
+
+// segments returns the ranges of text that have formats, merging spans next
+// to each other. Skipped spans are left out
+func segments(spans []span, formats delta.Attributes) []span {
+	var ret []span
+	for _, s := range spans {
+		if s.skip || !hasFormats(s.attrs, formats) {
+			continue
+		}
+		if n := len(ret); n > 0 && ret[n-1].end == s.start {
+			ret[n-1].end = s.end
+			continue
+		}
+		ret = append(ret, span{start: s.start, end: s.end})
+	}
+	return ret
+}
+
+func hasFormats(attrs, formats delta.Attributes) bool {
+	for k, v := range formats {
+		if v == nil {
+			if attrs.Has(k) {
+				return false
+			}
+		} else if !(delta.Attributes{k: v}).Equal(delta.Attributes{k: attrs[k]}) {
+			return false
+		}
+	}
+	return true
+}
+
+func spanAt(spans []span, index int) int {
+	return sort.Search(len(spans), func(i int) bool { return spans[i].end > index })
+}
+
+func attributesAt(spans []span, index int) delta.Attributes {
+	if i := spanAt(spans, index); i < len(spans) {
+		return spans[i].attrs
+	}
+	return nil
+}
+
+// uniform tells you if all the characters in the range have the same formats
+func uniform(spans []span, index, length int) bool {
+	i := spanAt(spans, index)
+	first := spans[i].attrs
+	for ; i < len(spans) && spans[i].start < index+length; i++ {
+		if !spans[i].attrs.Equal(first) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,146 @@
+package edit
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+)
+
+func replaceDocument() *delta.Delta {
+	return delta.New(nil).
+		Insert("The Acme", nil).
+		Insert("Phone", delta.Attributes{"bold": true}).
+		Insert(" and the acmephone", nil).
+		InsertEmbed(delta.Embed{Key: "image", Value: "a.png"}, nil).
+		Insert(" colour ", nil).
+		Insert("AcmePhone", delta.Attributes{"bold": true}).
+		Insert("\n", nil)
+}
+
+func TestFindAcrossOps(t *testing.T) {
+	matches, err := Find(replaceDocument(), Query{Pattern: "acmephone", IgnoreCase: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexes := []int{}
+	for _, m := range matches {
+		indexes = append(indexes, m.Index)
+	}
+	if !reflect.DeepEqual(indexes, []int{4, 22, 40}) || matches[0].Text != "AcmePhone" || matches[0].Length != 9 {
+		t.Errorf("unexpected matches %+v", matches)
+	}
+}
+
+func TestFindFormats(t *testing.T) {
+	matches, _ := Find(replaceDocument(), Query{Pattern: "phone", IgnoreCase: true, Formats: delta.Attributes{"bold": true}})
+	if len(matches) != 2 || matches[0].Index != 8 || matches[1].Index != 44 {
+		t.Errorf("unexpected bold matches %+v", matches)
+	}
+	matches, _ = Find(replaceDocument(), Query{Pattern: "acmephone", IgnoreCase: true, Formats: delta.Attributes{"bold": nil}})
+	if len(matches) != 1 || matches[0].Index != 22 {
+		t.Errorf("unexpected not bold matches %+v", matches)
+	}
+}
+
+func TestReplaceKeepsFirstFormat(t *testing.T) {
+	doc := replaceDocument()
+	change, matches, err := Replace(doc, Query{Pattern: `acme\s*phone`, Regexp: true, IgnoreCase: true}, "Widget")
+	if err != nil || len(matches) != 3 {
+		t.Fatalf("unexpected matches %+v, %v", matches, err)
+	}
+	got := doc.Compose(*change)
+	expected := delta.New(nil).
+		Insert("The Widget and the Widget", nil).
+		InsertEmbed(delta.Embed{Key: "image", Value: "a.png"}, nil).
+		Insert(" colour ", nil).
+		Insert("Widget", delta.Attributes{"bold": true}).
+		Insert("\n", nil)
+	if !reflect.DeepEqual(got.Ops, expected.Ops) {
+		a, _ := json.Marshal(got)
+		t.Errorf("unexpected document %s", a)
+	}
+}
+
+func TestReplaceMinimal(t *testing.T) {
+	doc := replaceDocument()
+	change, _, _ := Replace(doc, Query{Pattern: "colour"}, "color")
+	expected := delta.New(nil).Retain(37, nil).Delete(1)
+	if !reflect.DeepEqual(change.Ops, expected.Ops) {
+		a, _ := json.Marshal(change)
+		t.Errorf("expected a single delete, got %s", a)
+	}
+	change, _, _ = Replace(doc, Query{Pattern: "colour"}, "colour")
+	if len(change.Ops) != 0 {
+		t.Errorf("expected no change, got %+v", change.Ops)
+	}
+}
+
+func TestReplaceSubmatches(t *testing.T) {
+	doc := delta.New(nil).Insert("see http://example.com and http://quilljs.com\n", nil)
+	change, _, err := Replace(doc, Query{Pattern: `http://([a-z.]+)`, Regexp: true}, "https://$1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := doc.Compose(*change)
+	if s := string(got.Ops[0].Insert); s != "see https://example.com and https://quilljs.com\n" {
+		t.Errorf("unexpected text %q", s)
+	}
+	if len(change.Ops) != 4 {
+		t.Errorf("expected only the s to be inserted, got %+v", change.Ops)
+	}
+}
+
+func TestReplaceInvalidRegexp(t *testing.T) {
+	if _, _, err := Replace(replaceDocument(), Query{Pattern: "(", Regexp: true}, ""); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestReplaceKeepsEmbeds(t *testing.T) {
+	doc := replaceDocument()
+	for _, pattern := range []string{`phone\W colour`, `.+`, "\uFFFC", `\s+`} {
+		change, _, err := Replace(doc, Query{Pattern: pattern, Regexp: true, IgnoreCase: true}, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := doc.Compose(*change)
+		images := 0
+		for _, op := range got.Ops {
+			if op.InsertEmbed != nil {
+				images++
+			}
+		}
+		if images != 1 {
+			a, _ := json.Marshal(got)
+			t.Errorf("%s: expected the image to stay, got %s", pattern, a)
+		}
+	}
+}
+
+func TestReplaceKeepsLines(t *testing.T) {
+	doc := delta.New(nil).
+		Insert("Title", nil).
+		Insert("\n", delta.Attributes{"header": 1}).
+		Insert("Hello \n world ", nil).
+		Insert("bold", delta.Attributes{"bold": true}).
+		Insert("\n", delta.Attributes{"list": "bullet"}).
+		Insert("end\n", nil)
+	change, _, err := Replace(doc, Query{Pattern: `\s+`, Regexp: true}, " ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := doc.Compose(*change)
+	expected := delta.New(nil).
+		Insert("Title", nil).
+		Insert("\n", delta.Attributes{"header": 1}).
+		Insert("Hello world ", nil).
+		Insert("bold", delta.Attributes{"bold": true}).
+		Insert("\n", delta.Attributes{"list": "bullet"}).
+		Insert("end\n", nil)
+	if !reflect.DeepEqual(got.Ops, expected.Ops) {
+		a, _ := json.Marshal(got)
+		t.Errorf("unexpected document %s", a)
+	}
+}
@@ -0,0 +1,491 @@
+// Package latex exports Quill documents, insert only deltas, as LaTeX
+// source. Headers become sectioning commands, lists itemize and enumerate
+// environments, code blocks verbatim, and formula embeds inline math.
+package latex
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+	"github.com/fmpwizard/go-quilljs-delta/model"
+)
+
+// maxListDepth is how deep LaTeX lets lists nest, deeper items are written
+// at that depth
+const maxListDepth = 4
+
+// sections are the sectioning commands of the header levels
+var sections = []string{`\section`, `\subsection`, `\subsubsection`, `\paragraph`, `\subparagraph`, `\subparagraph`}
+
+// sizes are the font size declarations of quill's size format
+var sizes = map[string]string{"small": `\small`, "large": `\large`, "huge": `\huge`}
+
+// fonts are the font family commands of quill's font format
+var fonts = map[string]string{"serif": `\textrm`, "monospace": `\texttt`, "sans-serif": `\textsf`}
+
+// environments are the environments of quill's align format
+var environments = map[string]string{"center": "center", "right": "flushright"}
+
+const preamble = `\documentclass{article}
+\usepackage[utf8]{inputenc}
+\usepackage[T1]{fontenc}
+\usepackage{amssymb}
+\usepackage{graphicx}
+\usepackage{xcolor}
+\usepackage[normalem]{ulem}
+\usepackage{hyperref}
+
+\begin{document}
+
+`
+
+// Exporter writes documents as LaTeX
+type Exporter struct {
+	// Standalone wraps the body in a document that loads the packages the
+	// body needs: graphicx, xcolor, ulem, hyperref and amssymb
+	Standalone bool
+	// ImagePath returns the file \includegraphics gets for the value of an
+	// image embed. Images it returns "" for are written as their alt text.
+	// When nil the value of the embed is used for files, images with a URL
+	// are written as a \url to them, and as their alt text when the URL
+	// isn't safe or is a data: URL
+	ImagePath func(src string) string
+}
+
+// NewExporter returns an Exporter for standalone documents
+func NewExporter() *Exporter {
+	return &Exporter{Standalone: true}
+}
+
+// Export writes doc as a standalone LaTeX document to w
+func Export(w io.Writer, doc *delta.Delta) error {
+	return NewExporter().Export(w, doc)
+}
+
+// Export writes doc, an insert only delta, as LaTeX to w
+func (e *Exporter) Export(w io.Writer, doc *delta.Delta) error {
+	parsed, err := model.Parse(doc)
+	if err != nil {
+		return err
+	}
+	x := &exporter{Exporter: e}
+	if e.Standalone {
+		x.b.WriteString(preamble)
+	}
+	for _, b := range parsed.Blocks {
+		x.block(b)
+	}
+	if e.Standalone {
+		x.b.WriteString(`\end{document}` + "\n")
+	}
+	_, err = w.Write(x.b.Bytes())
+	return err
+}
+
+// exporter holds the state of one Export call
+type exporter struct {
+	*Exporter
+	b bytes.Buffer
+}
+
+func (x *exporter) block(b model.Block) {
+	switch b := b.(type) {
+	case *model.Paragraph:
+		if len(b.Runs) > 0 {
+			x.paragraph(b.Line)
+		}
+	case *model.Heading:
+		level := b.Level
+		if level > len(sections) {
+			level = len(sections)
+		}
+		x.b.WriteString(sections[level-1] + "{")
+		x.runs(b.Runs)
+		x.b.WriteString("}\n\n")
+	case *model.List:
+		x.list(b, 0)
+		x.b.WriteString("\n")
+	case *model.Blockquote:
+		x.b.WriteString("\\begin{quote}\n")
+		for i, l := range b.Lines {
+			if i > 0 {
+				x.b.WriteString("\n\n")
+			}
+			x.runs(l.Runs)
+		}
+		x.b.WriteString("\n\\end{quote}\n\n")
+	case *model.CodeBlock:
+		x.b.WriteString("\\begin{verbatim}\n")
+		for _, l := range b.Lines {
+			// verbatim ends at the first \end{verbatim}, whatever the code
+			x.b.WriteString(strings.Replace(l.Text(), `\end{verbatim}`, `\end {verbatim}`, -1))
+			x.b.WriteString("\n")
+		}
+		x.b.WriteString("\\end{verbatim}\n\n")
+	case *model.EmbedBlock:
+		src, _ := b.Embed.Value.(string)
+		if b.Embed.Key == "video" && src != "" {
+			if model.Link(delta.Attributes{"link": src}) != "" {
+				fmt.Fprintf(&x.b, "\\url{%s}\n\n", escapeURL(src))
+			} else {
+				x.b.WriteString(Escape(src) + "\n\n")
+			}
+		} else {
+			x.run(model.Run{Embed: &b.Embed, Attributes: b.Attributes})
+			x.b.WriteString("\n\n")
+		}
+	}
+}
+
+// paragraph writes a line, in an environment for its alignment
+func (x *exporter) paragraph(l model.Line) {
+	align, _ := l.Attributes.String("align")
+	env := environments[align]
+	if env != "" {
+		fmt.Fprintf(&x.b, "\\begin{%s}\n", env)
+	}
+	x.runs(l.Runs)
+	if env != "" {
+		fmt.Fprintf(&x.b, "\n\\end{%s}", env)
+	}
+	x.b.WriteString("\n\n")
+}
+
+// list writes l as an environment nested depth lists deep. Lists that skip
+// indent levels get empty items around them, and lists deeper than LaTeX
+// allows are written inside their parent
+func (x *exporter) list(l *model.List, depth int) {
+	env := "itemize"
+	if l.Type == "ordered" {
+		env = "enumerate"
+	}
+	level := l.Indent + 1
+	if level > maxListDepth {
+		level = maxListDepth
+	}
+	nested := level > depth
+	if nested {
+		for i := depth + 1; i < level; i++ {
+			x.b.WriteString("\\begin{itemize}\n\\item[]\n")
+		}
+		fmt.Fprintf(&x.b, "\\begin{%s}\n", env)
+	}
+	for _, item := range l.Items {
+		x.b.WriteString(`\item`)
+		if l.Type == "checked" {
+			if item.Checked {
+				x.b.WriteString(`[$\boxtimes$]`)
+			} else {
+				x.b.WriteString(`[$\square$]`)
+			}
+		}
+		x.b.WriteString(" ")
+		x.runs(item.Runs)
+		x.b.WriteString("\n")
+		for _, child := range item.Children {
+			if nested {
+				x.list(child, level)
+			} else {
+				x.list(child, depth)
+			}
+		}
+	}
+	if nested {
+		fmt.Fprintf(&x.b, "\\end{%s}\n", env)
+		for i := depth + 1; i < level; i++ {
+			x.b.WriteString("\\end{itemize}\n")
+		}
+	}
+}
+
+func (x *exporter) runs(runs []model.Run) {
+	for _, r := range runs {
+		x.run(r)
+	}
+}
+
+// run writes a run inside the commands for its formats
+func (x *exporter) run(r model.Run) {
+	attrs := r.Attributes
+	var closing []string
+	wrap := func(open string) {
+		x.b.WriteString(open + "{")
+		closing = append(closing, "}")
+	}
+	if link := model.Link(attrs); link != "" {
+		wrap(`\href{` + escapeURL(link) + "}")
+	}
+	if size, _ := attrs.String("size"); sizes[size] != "" {
+		// a group, so the size declaration ends with the run
+		x.b.WriteString("{" + sizes[size] + " ")
+		closing = append(closing, "}")
+	}
+	if color, ok := attrs.String("color"); ok {
+		if c, ok := delta.NormalizeColor(color); ok {
+			wrap(`\textcolor[HTML]{` + strings.ToUpper(c[1:]) + "}")
+		}
+	}
+	if background, ok := attrs.String("background"); ok {
+		if c, ok := delta.NormalizeColor(background); ok {
+			wrap(`\colorbox[HTML]{` + strings.ToUpper(c[1:]) + "}")
+		}
+	}
+	if font, _ := attrs.String("font"); fonts[font] != "" {
+		wrap(fonts[font])
+	}
+	for _, f := range []struct{ format, command string }{
+		{"bold", `\textbf`}, {"italic", `\emph`}, {"underline", `\underline`},
+		{"strike", `\sout`}, {"code", `\texttt`},
+	} {
+		if v, _ := attrs.Bool(f.format); v {
+			wrap(f.command)
+		}
+	}
+	switch script, _ := attrs.String("script"); script {
+	case "super":
+		wrap(`\textsuperscript`)
+	case "sub":
+		wrap(`\textsubscript`)
+	}
+
+	switch {
+	case r.Embed == nil:
+		x.b.WriteString(Escape(r.Text))
+	case r.Embed.Key == "formula":
+		formula, _ := r.Embed.Value.(string)
+		if safeFormula(formula) {
+			x.b.WriteString(`\(` + formula + `\)`)
+		} else {
+			x.b.WriteString(Escape(formula))
+		}
+	case r.Embed.Key == "image":
+		x.image(r.Embed.Value, attrs)
+	}
+	for i := len(closing) - 1; i >= 0; i-- {
+		x.b.WriteString(closing[i])
+	}
+}
+
+func (x *exporter) image(value interface{}, attrs delta.Attributes) {
+	src, _ := value.(string)
+	path := src
+	if x.ImagePath != nil {
+		path = x.ImagePath(src)
+	} else if hasScheme(src) {
+		// \includegraphics only reads files, link to the image instead
+		if model.Link(delta.Attributes{"link": src}) != "" {
+			fmt.Fprintf(&x.b, "\\url{%s}", escapeURL(src))
+			return
+		}
+		path = ""
+	}
+	if path == "" || !safePath(path) {
+		alt, _ := attrs.String("alt")
+		x.b.WriteString(Escape(alt))
+		return
+	}
+	x.b.WriteString(`\includegraphics`)
+	if w, ok := attrs["width"]; ok {
+		s := strings.TrimSuffix(strings.TrimSpace(fmt.Sprint(w)), "px")
+		if px, err := strconv.ParseFloat(s, 64); err == nil && px > 0 {
+			// css pixels are 0.75 of a big point
+			fmt.Fprintf(&x.b, "[width=%sbp]", strconv.FormatFloat(px*0.75, 'f', -1, 64))
+		}
+	}
+	fmt.Fprintf(&x.b, "{%s}", path)
+}
+
+// hasScheme reports whether src is a URL with a scheme, like http: or data:,
+// rather than the path of a file
+func hasScheme(src string) bool {
+	colon := strings.IndexByte(src, ':')
+	return colon > 0 && strings.IndexAny(src[:colon], "/?#\\") < 0
+}
+
+// safePath reports whether path can go in the argument of \includegraphics
+// as it is, without characters that would end the argument or run commands
+func safePath(path string) bool {
+	for _, r := range path {
+		if r <= ' ' || r == 0x7f || strings.ContainsRune(`{}\%#$^~&`, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// mathCommands are the control words a formula can use: the symbols,
+// functions, accents and fonts of math mode. Anything else, like the
+// commands that read files or redefine other commands, makes the formula
+// unsafe
+var mathCommands = wordSet(`
+	alpha beta gamma delta epsilon varepsilon zeta eta theta vartheta iota
+	kappa lambda mu nu xi pi varpi rho varrho sigma varsigma tau upsilon phi
+	varphi chi psi omega Gamma Delta Theta Lambda Xi Pi Sigma Upsilon Phi Psi
+	Omega
+	frac dfrac tfrac sqrt binom over choose left right middle big Big bigg
+	Bigg bigl bigr Bigl Bigr overline underline overbrace underbrace hat
+	widehat tilde widetilde bar vec dot ddot acute grave breve check
+	mathring
+	mathrm mathbf mathit mathsf mathtt mathcal mathbb mathfrak text textrm
+	textbf textit operatorname boldsymbol displaystyle textstyle
+	scriptstyle limits nolimits substack hline quad qquad
+	sin cos tan cot sec csc arcsin arccos arctan sinh cosh tanh coth log ln
+	lg exp lim liminf limsup sup inf max min arg det dim gcd hom ker deg Pr
+	bmod pmod mod
+	sum prod coprod int iint iiint oint bigcup bigcap bigoplus bigotimes
+	bigvee bigwedge
+	le leq ge geq ne neq approx equiv sim simeq cong propto ll gg subset
+	subseteq supset supseteq in notin ni mid parallel perp models vdash
+	pm mp times div cdot ast star circ bullet cap cup setminus wedge vee
+	oplus ominus otimes oslash odot land lor neg lnot
+	to gets leftarrow rightarrow leftrightarrow Leftarrow Rightarrow
+	Leftrightarrow longleftarrow longrightarrow Longleftarrow Longrightarrow
+	mapsto implies iff uparrow downarrow
+	infty partial nabla forall exists nexists emptyset varnothing angle
+	triangle prime ldots cdots vdots ddots dots hbar ell Re Im aleph
+	therefore because langle rangle lfloor rfloor lceil rceil lbrace rbrace
+	vert Vert backslash square boxtimes checkmark
+`)
+
+// mathEnvironments are the environments a formula can begin and end
+var mathEnvironments = wordSet(`matrix pmatrix bmatrix Bmatrix vmatrix Vmatrix smallmatrix cases array aligned gathered split`)
+
+const (
+	// mathSymbols are the characters that can follow a backslash, like \{
+	mathSymbols = `{}|,;:! \&_%#`
+	// mathCharacters are the characters, besides ASCII letters, digits and
+	// braces, a formula can use as they are
+	mathCharacters = " \t+-*/=<>()[]|,.;:!'?_^&~"
+)
+
+func wordSet(words string) map[string]bool {
+	ret := make(map[string]bool)
+	for _, w := range strings.Fields(words) {
+		ret[w] = true
+	}
+	return ret
+}
+
+// safeFormula reports whether formula can be written as inline math. Only
+// ASCII letters, digits, mathCharacters and the control words of
+// mathCommands are allowed, so a formula can't use % to comment out the
+// end of the math, # or ^^ to hide characters, or commands that reach
+// files, the shell or the definitions of other commands. Its braces and
+// environments must be balanced
+func safeFormula(formula string) bool {
+	if strings.Contains(formula, "^^") {
+		return false
+	}
+	depth := 0
+	var envs []string
+	for i := 0; i < len(formula); i++ {
+		c := formula[i]
+		switch {
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth < 0 {
+				return false
+			}
+		case c == '\\':
+			j := i + 1
+			for j < len(formula) && isLetter(formula[j]) {
+				j++
+			}
+			switch name := formula[i+1 : j]; name {
+			case "":
+				if j == len(formula) || strings.IndexByte(mathSymbols, formula[j]) < 0 {
+					return false
+				}
+				j++
+			case "begin", "end":
+				env, n := environment(formula[j:])
+				if !mathEnvironments[env] {
+					return false
+				}
+				if name == "begin" {
+					envs = append(envs, env)
+				} else if len(envs) == 0 || envs[len(envs)-1] != env {
+					return false
+				} else {
+					envs = envs[:len(envs)-1]
+				}
+				j += n
+			default:
+				if !mathCommands[name] {
+					return false
+				}
+			}
+			i = j - 1
+		case isLetter(c) || c >= '0' && c <= '9' || strings.IndexByte(mathCharacters, c) >= 0:
+		default:
+			return false
+		}
+	}
+	return depth == 0 && len(envs) == 0
+}
+
+// environment reads the {name} argument of \begin or \end at the start of
+// s, it returns the name and the length of the argument
+func environment(s string) (string, int) {
+	i := 0
+	for i < len(s) && s[i] == ' ' {
+		i++
+	}
+	if i == len(s) || s[i] != '{' {
+		return "", 0
+	}
+	end := strings.IndexByte(s[i:], '}')
+	if end < 0 {
+		return "", 0
+	}
+	return s[i+1 : i+end], i + end + 1
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// Escape returns text with the characters LaTeX gives a meaning to
+// escaped. Tabs are written as spaces
+func Escape(text string) string {
+	var b bytes.Buffer
+	for _, r := range text {
+		switch r {
+		case '\\':
+			b.WriteString(`\textbackslash{}`)
+		case '{', '}', '$', '&', '#', '_', '%':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case '^':
+			b.WriteString(`\textasciicircum{}`)
+		case '~':
+			b.WriteString(`\textasciitilde{}`)
+		case '\t':
+			b.WriteRune(' ')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// escapeURL makes a URL safe for \href and \url: characters that would
+// unbalance the braces are percent encoded, and % and # are escaped
+func escapeURL(u string) string {
+	var b bytes.Buffer
+	for _, r := range u {
+		switch r {
+		case '\\', '{', '}', ' ', '^':
+			fmt.Fprintf(&b, "%%%02X", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return strings.NewReplacer("%", `\%`, "#", `\#`).Replace(b.String())
+}
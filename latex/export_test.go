@@ -0,0 +1,189 @@
+package latex
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+	"github.com/fmpwizard/go-quilljs-delta/model"
+)
+
+func export(t *testing.T, e *Exporter, doc *delta.Delta) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := e.Export(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestEscape(t *testing.T) {
+	got := Escape(`50% of $x_1 & {y} #2 ~ ^ \ 	end`)
+	expected := `50\% of \$x\_1 \& \{y\} \#2 \textasciitilde{} \textasciicircum{} \textbackslash{}  end`
+	if got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+}
+
+func TestExport(t *testing.T) {
+	doc := delta.New(nil).
+		Insert("Title & more", nil).Insert("\n", delta.Attributes{"header": 1}).
+		Insert("Sub", nil).Insert("\n", delta.Attributes{"header": 2}).
+		Insert("Some ", nil).Insert("bold", delta.Attributes{"bold": true}).
+		Insert(" ", nil).Insert("it", delta.Attributes{"italic": true, "color": "red"}).
+		Insert(" ", nil).Insert("link", delta.Attributes{"link": "https://example.com/?a=1 #top"}).
+		Insert(" x", nil).Insert("2", delta.Attributes{"script": "super"}).
+		Insert(" ", nil).Insert("big", delta.Attributes{"size": "large"}).
+		Insert("\n", delta.Attributes{"align": "center"}).
+		Insert("\n", nil).
+		Insert("energy ", nil).InsertEmbed(delta.Embed{Key: "formula", Value: "e=mc^2"}, nil).
+		Insert("\n", nil).
+		Insert("one", nil).Insert("\n", delta.Attributes{"list": "ordered"}).
+		Insert("deep", nil).Insert("\n", delta.Attributes{"list": "bullet", "indent": 2}).
+		Insert("two", nil).Insert("\n", delta.Attributes{"list": "ordered"}).
+		Insert("todo", nil).Insert("\n", delta.Attributes{"list": "unchecked"}).
+		Insert("done", nil).Insert("\n", delta.Attributes{"list": "checked"}).
+		Insert("quoted", nil).Insert("\n", delta.Attributes{"blockquote": true}).
+		Insert(`x := "\end{verbatim}"`, nil).Insert("\n", delta.Attributes{"code-block": true}).
+		InsertEmbed(delta.Embed{Key: "video", Value: "https://example.com/v"}, nil).
+		Insert("\n", nil)
+
+	got := export(t, &Exporter{}, doc)
+	if strings.Contains(got, `\documentclass`) {
+		t.Errorf("unexpected preamble\n%s", got)
+	}
+	for _, s := range []string{
+		`\section{Title \& more}`,
+		`\subsection{Sub}`,
+		"\\begin{center}\nSome \\textbf{bold} \\textcolor[HTML]{FF0000}{\\emph{it}} " +
+			`\href{https://example.com/?a=1\%20\#top}{link} x\textsuperscript{2} {\large big}` +
+			"\n\\end{center}",
+		`energy \(e=mc^2\)`,
+		"\\begin{enumerate}\n\\item one\n\\begin{itemize}\n\\item[]\n\\begin{itemize}\n\\item deep\n" +
+			"\\end{itemize}\n\\end{itemize}\n\\item two\n\\end{enumerate}",
+		"\\begin{itemize}\n\\item[$\\square$] todo\n\\item[$\\boxtimes$] done\n\\end{itemize}",
+		"\\begin{quote}\nquoted\n\\end{quote}",
+		"\\begin{verbatim}\nx := \"\\end {verbatim}\"\n\\end{verbatim}",
+		`\url{https://example.com/v}`,
+	} {
+		if !strings.Contains(got, s) {
+			t.Errorf("output is missing %s\n%s", s, got)
+		}
+	}
+	if strings.Contains(got, "\\end{center}\n\n\n") {
+		t.Errorf("empty paragraphs should be skipped\n%s", got)
+	}
+}
+
+func TestExportImages(t *testing.T) {
+	doc := delta.New(nil).
+		InsertEmbed(delta.Embed{Key: "image", Value: "pics/a.png"}, delta.Attributes{"width": "200"}).
+		Insert(" ", nil).
+		InsertEmbed(delta.Embed{Key: "image", Value: "data:image/png;base64,AAAA"}, delta.Attributes{"alt": "a_b"}).
+		Insert("\n", nil)
+
+	got := export(t, NewExporter(), doc)
+	if !strings.HasPrefix(got, `\documentclass{article}`) || !strings.HasSuffix(got, "\\end{document}\n") {
+		t.Errorf("expected a standalone document\n%s", got)
+	}
+	if !strings.Contains(got, `\includegraphics[width=150bp]{pics/a.png} a\_b`) {
+		t.Errorf("unexpected images\n%s", got)
+	}
+
+	e := &Exporter{ImagePath: func(src string) string {
+		if strings.HasPrefix(src, "data:") {
+			return "inline.png"
+		}
+		return ""
+	}}
+	got = export(t, e, doc)
+	if strings.Contains(got, "pics/a.png") || !strings.Contains(got, `\includegraphics{inline.png}`) {
+		t.Errorf("ImagePath not used\n%s", got)
+	}
+
+	if err := Export(ioutil.Discard, delta.New(nil).Delete(1)); err != model.ErrNotDocument {
+		t.Errorf("expected ErrNotDocument, got %v", err)
+	}
+}
+
+func TestExportUnsafe(t *testing.T) {
+	doc := delta.New(nil).
+		InsertEmbed(delta.Embed{Key: "image", Value: `a}\input{/etc/passwd`}, delta.Attributes{"alt": "pic"}).
+		InsertEmbed(delta.Embed{Key: "image", Value: "my pic.png"}, nil).
+		InsertEmbed(delta.Embed{Key: "formula", Value: `x$ \input{/etc/passwd} $`}, nil).
+		InsertEmbed(delta.Embed{Key: "formula", Value: `\immediate\write18{rm -rf /}`}, nil).
+		InsertEmbed(delta.Embed{Key: "formula", Value: `x}`}, nil).
+		InsertEmbed(delta.Embed{Key: "formula", Value: `^^5cinput`}, nil).
+		InsertEmbed(delta.Embed{Key: "formula", Value: `\end{document}`}, nil).
+		InsertEmbed(delta.Embed{Key: "formula", Value: `\frac{1}{2} \{x\} \\ \begin{matrix}a\end{matrix}`}, nil).
+		Insert("click", delta.Attributes{"link": "javascript:alert(1)"}).
+		Insert("\n", nil).
+		InsertEmbed(delta.Embed{Key: "video", Value: " JaVa\tscript:alert(1)"}, nil).
+		Insert("\n", nil)
+	got := export(t, &Exporter{}, doc)
+	for _, bad := range []string{`\input{`, `\write18`, `\includegraphics`, `\end{document}`, "$", `\href`, `\url`} {
+		if strings.Contains(strings.Replace(got, `\$`, "", -1), bad) {
+			t.Errorf("output has %s\n%s", bad, got)
+		}
+	}
+	if !strings.Contains(got, `\(\frac{1}{2} \{x\} \\ \begin{matrix}a\end{matrix}\)`) {
+		t.Errorf("expected the safe formula as inline math\n%s", got)
+	}
+	if !strings.HasPrefix(got, `pic`) {
+		t.Errorf("expected the alt text of the unsafe image\n%s", got)
+	}
+
+	e := &Exporter{ImagePath: func(string) string { return `x}\input{y` }}
+	if got := export(t, e, doc); strings.Contains(got, `\includegraphics`) {
+		t.Errorf("expected the path from ImagePath to be checked\n%s", got)
+	}
+}
+
+func TestSafeFormula(t *testing.T) {
+	tests := map[string]bool{
+		`e=mc^2`:                                    true,
+		`\sum_{i=1}^{n} \alpha_i \leq \sqrt{x}`:     true,
+		`\begin{pmatrix}a & b\\ c & d\end{pmatrix}`: true,
+		`\{x\} \, \% 100`:                           true,
+		`x % \)`:                                    false,
+		`\catcode`:                                  false,
+		`\def\x{y}`:                                 false,
+		`\csname input\endcsname`:                   false,
+		`#1`:                                        false,
+		`\begin{matrix}a`:                           false,
+		`\begin{matrix}a\end{pmatrix}`:              false,
+		`\begin{document}`:                          false,
+		"a\n\nb":                                    false,
+		"\u00e9":                                    false,
+		`x\`:                                        false,
+	}
+	for formula, expected := range tests {
+		if got := safeFormula(formula); got != expected {
+			t.Errorf("%q: expected %v, got %v", formula, expected, got)
+		}
+	}
+}
+
+func TestExportRemoteImages(t *testing.T) {
+	doc := delta.New(nil).
+		InsertEmbed(delta.Embed{Key: "image", Value: "https://example.com/a.png"}, nil).
+		Insert(" ", nil).
+		InsertEmbed(delta.Embed{Key: "image", Value: "javascript:alert(1)"}, delta.Attributes{"alt": "pic"}).
+		Insert("\n", nil)
+	got := export(t, &Exporter{}, doc)
+	if strings.Contains(got, `\includegraphics`) || !strings.Contains(got, `\url{https://example.com/a.png} pic`) {
+		t.Errorf("expected remote images as links\n%s", got)
+	}
+}
+
+func TestExportTransparentColors(t *testing.T) {
+	doc := delta.New(nil).
+		Insert("clear", delta.Attributes{"color": "transparent", "background": "transparent"}).
+		Insert("\n", nil)
+	got := export(t, &Exporter{}, doc)
+	if strings.Contains(got, `\textcolor`) || strings.Contains(got, `\colorbox`) || !strings.Contains(got, "clear") {
+		t.Errorf("expected transparent colors to be left out\n%s", got)
+	}
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strconv"
+	"strings"
+)
+
+// CodeFont is the font exporters use for code
+const CodeFont = "Courier New"
+
+// Fonts maps quill's font format to the fonts office applications have
+var Fonts = map[string]string{"serif": "Times New Roman", "monospace": CodeFont, "sans-serif": "Arial"}
+
+// sizes maps quill's size format to points
+var sizes = map[string]float64{"small": 9, "large": 16, "huge": 24}
+
+// FontName returns the font of quill's font format, font itself when it
+// isn't one of quill's names
+func FontName(font string) string {
+	if name, ok := Fonts[font]; ok {
+		return name
+	}
+	return font
+}
+
+// Points converts a quill size, a name or a css length in px or pt, to
+// points
+func Points(size string) (float64, bool) {
+	if pt, ok := sizes[size]; ok {
+		return pt, true
+	}
+	unit := 0.75 // px
+	if strings.HasSuffix(size, "pt") {
+		unit = 1
+	}
+	f, err := strconv.ParseFloat(strings.TrimRight(size, "ptx"), 64)
+	if err != nil || f <= 0 {
+		return 0, false
+	}
+	return f * unit, true
+}
+
+// HalfPoints converts a quill size to half points, the unit of font sizes
+// in Word and RTF
+func HalfPoints(size string) (int, bool) {
+	pt, ok := Points(size)
+	if !ok {
+		return 0, false
+	}
+	return int(pt*2 + 0.5), true
+}
+
+// EscapeXML returns s as XML text, also safe in attribute values
+func EscapeXML(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,41 @@
+package model
+
+import "testing"
+
+func TestPoints(t *testing.T) {
+	tests := []struct {
+		size       string
+		points     float64
+		halfPoints int
+		ok         bool
+	}{
+		{"small", 9, 18, true},
+		{"huge", 24, 48, true},
+		{"12pt", 12, 24, true},
+		{"13px", 9.75, 20, true},
+		{"0px", 0, 0, false},
+		{"big", 0, 0, false},
+	}
+	for _, test := range tests {
+		pt, ok := Points(test.size)
+		half, halfOK := HalfPoints(test.size)
+		if pt != test.points || half != test.halfPoints || ok != test.ok || halfOK != test.ok {
+			t.Errorf("%s: expected %v, %d, %v, got %v, %d, %v", test.size, test.points, test.halfPoints, test.ok, pt, half, ok)
+		}
+	}
+}
+
+func TestFontName(t *testing.T) {
+	if name := FontName("monospace"); name != CodeFont {
+		t.Errorf("expected %s, got %s", CodeFont, name)
+	}
+	if name := FontName("Comic Sans"); name != "Comic Sans" {
+		t.Errorf("expected the font itself, got %s", name)
+	}
+}
+
+func TestEscapeXML(t *testing.T) {
+	if s := EscapeXML(`a <b> & "c"`); s != "a &lt;b&gt; &amp; &#34;c&#34;" {
+		t.Errorf("unexpected escape %s", s)
+	}
+}
@@ -0,0 +1,143 @@
+package model
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"image"
+	// the formats Image.Size knows about
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+)
+
+// ErrInvalidDataURL is returned by DataURL for data: URLs it can't decode
+var ErrInvalidDataURL = errors.New("model: invalid data URL")
+
+// Image is the content of an image embed, for exporters that include the
+// images in their output
+type Image struct {
+	Data        []byte
+	ContentType string
+}
+
+// ImageResolver gets the image of an image embed, src is the value of the
+// embed. A nil Image with a nil error leaves the image out
+type ImageResolver interface {
+	ResolveImage(src string) (*Image, error)
+}
+
+// ImageResolverFunc lets you use a plain function as an ImageResolver
+type ImageResolverFunc func(src string) (*Image, error)
+
+// ResolveImage calls f(src)
+func (f ImageResolverFunc) ResolveImage(src string) (*Image, error) {
+	return f(src)
+}
+
+// DataURLs is the ImageResolver exporters use when they are given none, it
+// only knows about data: URLs
+var DataURLs ImageResolver = ImageResolverFunc(DataURL)
+
+// DataURL decodes src if it is a data: URL, for anything else it returns
+// nil and no error
+func DataURL(src string) (*Image, error) {
+	if !strings.HasPrefix(strings.ToLower(src), "data:") {
+		return nil, nil
+	}
+	comma := strings.IndexByte(src, ',')
+	if comma < 0 {
+		return nil, ErrInvalidDataURL
+	}
+	params := strings.Split(src[len("data:"):comma], ";")
+	payload := src[comma+1:]
+	var data []byte
+	var err error
+	if strings.EqualFold(params[len(params)-1], "base64") {
+		payload = strings.Map(func(r rune) rune {
+			if r == ' ' || r == '\n' || r == '\r' || r == '\t' {
+				return -1
+			}
+			return r
+		}, payload)
+		data, err = base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			data, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(payload, "="))
+		}
+	} else {
+		var s string
+		s, err = url.PathUnescape(payload)
+		data = []byte(s)
+	}
+	if err != nil {
+		return nil, ErrInvalidDataURL
+	}
+	img := &Image{Data: data, ContentType: strings.ToLower(strings.TrimSpace(params[0]))}
+	if img.ContentType == "" {
+		img.ContentType = http.DetectContentType(data)
+	}
+	return img, nil
+}
+
+// Size returns the size of the image in pixels, for the formats the image
+// package knows about
+func (img *Image) Size() (width, height int, ok bool) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(img.Data))
+	if err != nil {
+		return 0, 0, false
+	}
+	return config.Width, config.Height, true
+}
+
+// DataURL returns the image as a base64 data: URL
+func (img *Image) DataURL() string {
+	return "data:" + img.ContentType + ";base64," + base64.StdEncoding.EncodeToString(img.Data)
+}
+
+// DisplaySize returns the size to show the image at, in pixels: the width
+// and height formats of the embed, or the size of the image, keeping the
+// aspect ratio when only one is given, and no wider than maxWidth
+func (img *Image) DisplaySize(attrs delta.Attributes, maxWidth int) (int, int) {
+	width, height, ok := img.Size()
+	if !ok || width < 1 || height < 1 {
+		width, height = 300, 200
+	}
+	w, hasWidth := pixels(attrs["width"])
+	h, hasHeight := pixels(attrs["height"])
+	switch {
+	case hasWidth && hasHeight:
+		width, height = w, h
+	case hasWidth:
+		width, height = w, height*w/width
+	case hasHeight:
+		width, height = width*h/height, h
+	}
+	if width > maxWidth {
+		width, height = maxWidth, height*maxWidth/width
+	}
+	if height < 1 {
+		height = 1
+	}
+	return width, height
+}
+
+// pixels reads the width and height formats, numbers or strings like "200"
+// and "200px"
+func pixels(v interface{}) (int, bool) {
+	switch v := v.(type) {
+	case float64:
+		return int(v), v >= 1
+	case int:
+		return v, v >= 1
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(v), "px"), 64)
+		return int(f), err == nil && f >= 1
+	}
+	return 0, false
+}
@@ -0,0 +1,71 @@
+package model
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+)
+
+func testPNG(w, h int) []byte {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h)))
+	return buf.Bytes()
+}
+
+func TestDataURL(t *testing.T) {
+	data := testPNG(3, 2)
+	img, err := DataURL("data:image/png;base64," + base64.StdEncoding.EncodeToString(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.ContentType != "image/png" || !bytes.Equal(img.Data, data) {
+		t.Errorf("unexpected image %q %v", img.ContentType, img.Data)
+	}
+	if w, h, ok := img.Size(); !ok || w != 3 || h != 2 {
+		t.Errorf("unexpected size %d %d %v", w, h, ok)
+	}
+	if again, _ := DataURL(img.DataURL()); again.ContentType != "image/png" || !bytes.Equal(again.Data, data) {
+		t.Errorf("DataURL doesn't round trip")
+	}
+
+	img, err = DataURL("data:,a%20b")
+	if err != nil || string(img.Data) != "a b" || img.ContentType != "text/plain; charset=utf-8" {
+		t.Errorf("unexpected image %+v %v", img, err)
+	}
+	if img, err := DataURL("https://example.com/a.png"); img != nil || err != nil {
+		t.Errorf("expected nothing for an http URL, got %+v %v", img, err)
+	}
+	if _, err := DataURL("data:image/png;base64,!!!"); err != ErrInvalidDataURL {
+		t.Errorf("expected ErrInvalidDataURL, got %v", err)
+	}
+	if _, err := DataURL("data:image/png"); err != ErrInvalidDataURL {
+		t.Errorf("expected ErrInvalidDataURL, got %v", err)
+	}
+}
+
+func TestDisplaySize(t *testing.T) {
+	img := &Image{Data: testPNG(400, 200), ContentType: "image/png"}
+	tests := []struct {
+		attrs         delta.Attributes
+		width, height int
+	}{
+		{nil, 400, 200},
+		{delta.Attributes{"width": "100"}, 100, 50},
+		{delta.Attributes{"height": 100.0}, 200, 100},
+		{delta.Attributes{"width": "30px", "height": "40"}, 30, 40},
+		{delta.Attributes{"width": "1000"}, 600, 300},
+		{delta.Attributes{"width": "wide"}, 400, 200},
+	}
+	for _, test := range tests {
+		if w, h := img.DisplaySize(test.attrs, 600); w != test.width || h != test.height {
+			t.Errorf("%v: expected %dx%d, got %dx%d", test.attrs, test.width, test.height, w, h)
+		}
+	}
+	if w, h := (&Image{}).DisplaySize(nil, 600); w != 300 || h != 200 {
+		t.Errorf("expected the default size, got %dx%d", w, h)
+	}
+}
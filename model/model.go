@@ -0,0 +1,294 @@
+// Package model parses an insert only Delta into a tree of typed blocks, the
+// structure renderers and exporters work on: paragraphs, headings, nested
+// lists, blockquotes, code blocks and block embeds, each holding lines of
+// inline runs. Every line keeps the formats of its newline, so ToDelta gives
+// back the delta that was parsed.
+package model
+
+import (
+	"errors"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+)
+
+// ErrNotDocument is returned by Parse when the delta has retain or delete
+// ops, only documents (insert only deltas) can be parsed
+var ErrNotDocument = errors.New("model: delta is not a document")
+
+// BlockEmbeds are the embeds that take a whole line, like quill's video.
+// They are only parsed as an EmbedBlock when found at the start of a line,
+// everywhere else they are inline runs
+var BlockEmbeds = map[string]bool{"video": true}
+
+// Document is a parsed delta
+type Document struct {
+	Blocks []Block
+}
+
+// Block is one of *Paragraph, *Heading, *List, *Blockquote, *CodeBlock or
+// *EmbedBlock
+type Block interface {
+	appendTo(d *delta.Delta)
+}
+
+// Run is text, or an inline embed, with the same formats
+type Run struct {
+	Text       string
+	Embed      *delta.Embed
+	Attributes delta.Attributes
+}
+
+// Line is the runs of a line and the formats of the newline ending it
+type Line struct {
+	Runs       []Run
+	Attributes delta.Attributes
+	// NoNewline is set on the last line of a delta that doesn't end with a
+	// newline
+	NoNewline bool
+}
+
+// Text returns the text of the line, inline embeds are left out
+func (l Line) Text() string {
+	var ret []rune
+	for _, r := range l.Runs {
+		ret = append(ret, []rune(r.Text)...)
+	}
+	return string(ret)
+}
+
+// links checks the URLs of link formats
+var links = delta.NewSanitizer()
+
+// Link returns the link format of attrs, "" when there is none or its URL
+// isn't safe, see delta.Sanitizer. Exporters write unsafe links as text
+func Link(attrs delta.Attributes) string {
+	link, _ := attrs.String("link")
+	if link == "" || !links.ValidURL(link, false) {
+		return ""
+	}
+	return link
+}
+
+// Paragraph is a line with no header, list, blockquote or code-block format
+type Paragraph struct {
+	Line
+}
+
+// Heading is a line with a header format
+type Heading struct {
+	Level int
+	Line
+}
+
+// List is a run of list lines of the same type and indent. Type is the list
+// format, checked and unchecked items share a list of type "checked"
+type List struct {
+	Type   string
+	Indent int
+	Items  []*ListItem
+}
+
+// ListItem is a line of a list and the lists indented under it
+type ListItem struct {
+	Line
+	Checked  bool
+	Children []*List
+}
+
+// Blockquote is a run of lines with the blockquote format
+type Blockquote struct {
+	Lines []Line
+}
+
+// CodeBlock is a run of lines with the same code-block format. Language is
+// the value of the format when it's a string
+type CodeBlock struct {
+	Language string
+	Lines    []Line
+}
+
+// EmbedBlock is a block embed, see BlockEmbeds
+type EmbedBlock struct {
+	Embed      delta.Embed
+	Attributes delta.Attributes
+}
+
+// Parse builds the Document of d
+func Parse(d *delta.Delta) (*Document, error) {
+	p := parser{doc: &Document{}}
+	for _, op := range d.Ops {
+		switch {
+		case op.InsertEmbed != nil:
+			if BlockEmbeds[op.InsertEmbed.Key] && len(p.runs) == 0 {
+				p.add(&EmbedBlock{Embed: *op.InsertEmbed, Attributes: op.Attributes})
+				continue
+			}
+			p.runs = append(p.runs, Run{Embed: op.InsertEmbed, Attributes: op.Attributes})
+		case op.Insert != nil:
+			start := 0
+			for i, r := range op.Insert {
+				if r != '\n' {
+					continue
+				}
+				if i > start {
+					p.runs = append(p.runs, Run{Text: string(op.Insert[start:i]), Attributes: op.Attributes})
+				}
+				p.line(Line{Runs: p.runs, Attributes: op.Attributes})
+				p.runs = nil
+				start = i + 1
+			}
+			if start < len(op.Insert) {
+				p.runs = append(p.runs, Run{Text: string(op.Insert[start:]), Attributes: op.Attributes})
+			}
+		default:
+			return nil, ErrNotDocument
+		}
+	}
+	if len(p.runs) > 0 {
+		p.add(&Paragraph{Line: Line{Runs: p.runs, NoNewline: true}})
+	}
+	return p.doc, nil
+}
+
+type parser struct {
+	doc  *Document
+	runs []Run
+	// lists is the list being parsed and its parents, the outermost first
+	lists []*List
+}
+
+func (p *parser) add(b Block) {
+	p.doc.Blocks = append(p.doc.Blocks, b)
+	p.lists = nil
+}
+
+func (p *parser) last() Block {
+	if len(p.doc.Blocks) == 0 {
+		return nil
+	}
+	return p.doc.Blocks[len(p.doc.Blocks)-1]
+}
+
+func (p *parser) line(l Line) {
+	attrs := l.Attributes
+	if v, ok := attrs["code-block"]; ok && v != nil && v != false {
+		lang, _ := attrs.String("code-block")
+		if b, ok := p.last().(*CodeBlock); ok && sameValue(b.Lines[0].Attributes["code-block"], v) {
+			b.Lines = append(b.Lines, l)
+			p.lists = nil
+			return
+		}
+		p.add(&CodeBlock{Language: lang, Lines: []Line{l}})
+		return
+	}
+	if level, ok := attrs.Int("header"); ok && level > 0 {
+		p.add(&Heading{Level: level, Line: l})
+		return
+	}
+	if t, ok := attrs.String("list"); ok && t != "" {
+		p.listItem(l, t)
+		return
+	}
+	if quote, _ := attrs.Bool("blockquote"); quote {
+		if b, ok := p.last().(*Blockquote); ok {
+			b.Lines = append(b.Lines, l)
+			p.lists = nil
+			return
+		}
+		p.add(&Blockquote{Lines: []Line{l}})
+		return
+	}
+	p.add(&Paragraph{Line: l})
+}
+
+// listItem adds the line to the list at its indent, starting new lists as
+// needed
+func (p *parser) listItem(l Line, t string) {
+	item := &ListItem{Line: l, Checked: t == "checked"}
+	if t == "unchecked" {
+		t = "checked"
+	}
+	indent, _ := l.Attributes.Int("indent")
+	for len(p.lists) > 0 && p.lists[len(p.lists)-1].Indent > indent {
+		p.lists = p.lists[:len(p.lists)-1]
+	}
+	if len(p.lists) > 0 {
+		top := p.lists[len(p.lists)-1]
+		if top.Indent == indent && top.Type == t {
+			top.Items = append(top.Items, item)
+			return
+		}
+		if top.Indent == indent {
+			p.lists = p.lists[:len(p.lists)-1]
+		}
+	}
+	list := &List{Type: t, Indent: indent, Items: []*ListItem{item}}
+	if len(p.lists) == 0 {
+		p.add(list)
+	} else {
+		parent := p.lists[len(p.lists)-1].Items
+		last := parent[len(parent)-1]
+		last.Children = append(last.Children, list)
+	}
+	p.lists = append(p.lists, list)
+}
+
+func sameValue(a, b interface{}) bool {
+	return delta.Attributes{"v": a}.Equal(delta.Attributes{"v": b})
+}
+
+// ToDelta returns the delta of the document. For a normalized delta, like
+// the ones built with Insert or Compose, Parse(d).ToDelta() equals d
+func (doc *Document) ToDelta() *delta.Delta {
+	ret := delta.New(nil)
+	for _, b := range doc.Blocks {
+		b.appendTo(ret)
+	}
+	return ret
+}
+
+func (l *Line) appendTo(d *delta.Delta) {
+	for _, r := range l.Runs {
+		if r.Embed != nil {
+			d.InsertEmbed(*r.Embed, r.Attributes)
+		} else {
+			d.Insert(r.Text, r.Attributes)
+		}
+	}
+	if !l.NoNewline {
+		d.Insert("\n", l.Attributes)
+	}
+}
+
+func (b *Paragraph) appendTo(d *delta.Delta) {
+	b.Line.appendTo(d)
+}
+
+func (b *Heading) appendTo(d *delta.Delta) {
+	b.Line.appendTo(d)
+}
+
+func (b *List) appendTo(d *delta.Delta) {
+	for _, item := range b.Items {
+		item.Line.appendTo(d)
+		for _, child := range item.Children {
+			child.appendTo(d)
+		}
+	}
+}
+
+func (b *Blockquote) appendTo(d *delta.Delta) {
+	for i := range b.Lines {
+		b.Lines[i].appendTo(d)
+	}
+}
+
+func (b *CodeBlock) appendTo(d *delta.Delta) {
+	for i := range b.Lines {
+		b.Lines[i].appendTo(d)
+	}
+}
+
+func (b *EmbedBlock) appendTo(d *delta.Delta) {
+	d.InsertEmbed(b.Embed, b.Attributes)
+}
@@ -0,0 +1,154 @@
+package model
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+)
+
+func sampleDocument() *delta.Delta {
+	return delta.New(nil).
+		Insert("Title", nil).Insert("\n", delta.Attributes{"header": 1}).
+		Insert("Some ", nil).Insert("bold", delta.Attributes{"bold": true}).Insert(" text\n", nil).
+		Insert("one", nil).Insert("\n", delta.Attributes{"list": "ordered"}).
+		Insert("one.a", nil).Insert("\n", delta.Attributes{"list": "bullet", "indent": 1}).
+		Insert("one.b", nil).Insert("\n", delta.Attributes{"list": "bullet", "indent": 1}).
+		Insert("one.b.i", nil).Insert("\n", delta.Attributes{"list": "ordered", "indent": 2}).
+		Insert("two", nil).Insert("\n", delta.Attributes{"list": "ordered"}).
+		Insert("done", nil).Insert("\n", delta.Attributes{"list": "checked"}).
+		Insert("todo", nil).Insert("\n", delta.Attributes{"list": "unchecked"}).
+		Insert("quote", nil).Insert("\n", delta.Attributes{"blockquote": true}).
+		Insert("more", nil).Insert("\n", delta.Attributes{"blockquote": true}).
+		InsertEmbed(delta.Embed{Key: "video", Value: "a.mp4"}, nil).
+		Insert("x := 1", nil).Insert("\n", delta.Attributes{"code-block": "go"}).
+		Insert("y := 2", nil).Insert("\n", delta.Attributes{"code-block": "go"}).
+		Insert("see ", nil).InsertEmbed(delta.Embed{Key: "image", Value: "a.png"}, nil).Insert("\n", nil)
+}
+
+func TestParse(t *testing.T) {
+	d := sampleDocument()
+	doc, err := Parse(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Blocks) != 8 {
+		t.Fatalf("expected 8 blocks, got %d: %#v", len(doc.Blocks), doc.Blocks)
+	}
+	if h, ok := doc.Blocks[0].(*Heading); !ok || h.Level != 1 || h.Text() != "Title" {
+		t.Errorf("unexpected heading %#v", doc.Blocks[0])
+	}
+	if p, ok := doc.Blocks[1].(*Paragraph); !ok || len(p.Runs) != 3 || p.Runs[1].Attributes["bold"] != true {
+		t.Errorf("unexpected paragraph %#v", doc.Blocks[1])
+	}
+
+	list, ok := doc.Blocks[2].(*List)
+	if !ok || list.Type != "ordered" || len(list.Items) != 2 || list.Items[1].Text() != "two" {
+		t.Fatalf("unexpected list %#v", doc.Blocks[2])
+	}
+	nested := list.Items[0].Children
+	if len(nested) != 1 || nested[0].Type != "bullet" || nested[0].Indent != 1 || len(nested[0].Items) != 2 {
+		t.Fatalf("unexpected nested list %#v", nested)
+	}
+	deeper := nested[0].Items[1].Children
+	if len(deeper) != 1 || deeper[0].Type != "ordered" || deeper[0].Items[0].Text() != "one.b.i" {
+		t.Errorf("unexpected nested list %#v", deeper)
+	}
+
+	checks, ok := doc.Blocks[3].(*List)
+	if !ok || checks.Type != "checked" || len(checks.Items) != 2 || !checks.Items[0].Checked || checks.Items[1].Checked {
+		t.Errorf("unexpected check list %#v", doc.Blocks[3])
+	}
+	if q, ok := doc.Blocks[4].(*Blockquote); !ok || len(q.Lines) != 2 {
+		t.Errorf("unexpected blockquote %#v", doc.Blocks[4])
+	}
+	if e, ok := doc.Blocks[5].(*EmbedBlock); !ok || e.Embed.Key != "video" {
+		t.Errorf("unexpected embed %#v", doc.Blocks[5])
+	}
+	if c, ok := doc.Blocks[6].(*CodeBlock); !ok || c.Language != "go" || len(c.Lines) != 2 {
+		t.Errorf("unexpected code block %#v", doc.Blocks[6])
+	}
+	if p, ok := doc.Blocks[7].(*Paragraph); !ok || len(p.Runs) != 2 || p.Runs[1].Embed == nil {
+		t.Errorf("unexpected paragraph %#v", doc.Blocks[7])
+	}
+
+	if got := doc.ToDelta(); !reflect.DeepEqual(got, d) {
+		t.Errorf("ToDelta doesn't match\ngot:      %+v\nexpected: %+v", got.Ops, d.Ops)
+	}
+}
+
+func TestParseNotDocument(t *testing.T) {
+	if _, err := Parse(delta.New(nil).Retain(1, nil)); err != ErrNotDocument {
+		t.Errorf("expected ErrNotDocument, got %v", err)
+	}
+}
+
+func TestParseNoNewline(t *testing.T) {
+	d := delta.New(nil).Insert("a\nb", nil)
+	doc, _ := Parse(d)
+	if len(doc.Blocks) != 2 || !doc.Blocks[1].(*Paragraph).NoNewline {
+		t.Errorf("unexpected blocks %#v", doc.Blocks)
+	}
+	if got := doc.ToDelta(); !reflect.DeepEqual(got, d) {
+		t.Errorf("unexpected delta %+v", got.Ops)
+	}
+}
+
+func TestToDeltaRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	lines := []delta.Attributes{
+		nil, nil,
+		{"header": 2},
+		{"list": "bullet"},
+		{"list": "ordered", "indent": 1},
+		{"list": "bullet", "indent": 2},
+		{"list": "unchecked"},
+		{"blockquote": true},
+		{"code-block": true},
+		{"align": "center"},
+	}
+	inline := []delta.Attributes{nil, {"bold": true}, {"link": "https://example.com"}}
+	for i := 0; i < 200; i++ {
+		d := delta.New(nil)
+		for n := r.Intn(20); n >= 0; n-- {
+			switch r.Intn(6) {
+			case 0:
+				d.InsertEmbed(delta.Embed{Key: "video", Value: "v.mp4"}, nil)
+				continue
+			case 1:
+				d.InsertEmbed(delta.Embed{Key: "image", Value: "i.png"}, inline[r.Intn(len(inline))])
+			}
+			d.Insert("ab c", inline[r.Intn(len(inline))])
+			d.Insert("\n", lines[r.Intn(len(lines))])
+		}
+		if r.Intn(4) == 0 {
+			d.Insert("tail", nil)
+		}
+		doc, err := Parse(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := doc.ToDelta(); !reflect.DeepEqual(got, d) {
+			t.Fatalf("%d: ToDelta doesn't match\ngot:      %+v\nexpected: %+v", i, got.Ops, d.Ops)
+		}
+	}
+}
+
+func TestLink(t *testing.T) {
+	for link, expected := range map[string]string{
+		"https://example.com/":   "https://example.com/",
+		"mailto:a@example.com":   "mailto:a@example.com",
+		"/relative":              "/relative",
+		"javascript:alert(1)":    "",
+		" JaVa\tscript:alert(1)": "",
+		"data:text/html,<b>":     "",
+	} {
+		if got := Link(delta.Attributes{"link": link}); got != expected {
+			t.Errorf("%q: expected %q, got %q", link, expected, got)
+		}
+	}
+	if got := Link(delta.Attributes{"bold": true}); got != "" {
+		t.Errorf("expected no link, got %q", got)
+	}
+}
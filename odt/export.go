@@ -0,0 +1,516 @@
+// Package odt exports Quill documents, insert only deltas, as OpenDocument
+// Text files. The inline and line formats of the delta become automatic
+// styles, one for every combination found in the document, on top of the
+// named styles in styles.xml.
+package odt
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+	"github.com/fmpwizard/go-quilljs-delta/model"
+)
+
+const (
+	mimeType = "application/vnd.oasis.opendocument.text"
+
+	nsOffice   = "urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+	nsStyle    = "urn:oasis:names:tc:opendocument:xmlns:style:1.0"
+	nsText     = "urn:oasis:names:tc:opendocument:xmlns:text:1.0"
+	nsDraw     = "urn:oasis:names:tc:opendocument:xmlns:drawing:1.0"
+	nsFO       = "urn:oasis:names:tc:opendocument:xmlns:xsl-fo-compatible:1.0"
+	nsXlink    = "http://www.w3.org/1999/xlink"
+	nsSVG      = "urn:oasis:names:tc:opendocument:xmlns:svg-compatible:1.0"
+	nsManifest = "urn:oasis:names:tc:opendocument:xmlns:manifest:1.0"
+
+	xmlHeader = `<?xml version="1.0" encoding="UTF-8"?>` + "\n"
+
+	// maxImageWidth is the width of the text on the page, in pixels
+	maxImageWidth = 624
+	// indentInches is how much each level of quill's indent format moves a
+	// paragraph
+	indentInches = 0.5
+)
+
+// imageTypes are the image formats of the package, by content type
+var imageTypes = map[string]string{
+	"image/png":     "png",
+	"image/jpeg":    "jpeg",
+	"image/gif":     "gif",
+	"image/bmp":     "bmp",
+	"image/tiff":    "tiff",
+	"image/svg+xml": "svg",
+}
+
+// alignments maps quill's align format to fo:text-align values
+var alignments = map[string]string{"center": "center", "right": "end", "justify": "justify"}
+
+// Exporter writes documents as odt files
+type Exporter struct {
+	// Images gets the content of image embeds. When it returns no image, or
+	// one of a type odt files don't have, the alt text of the embed is used
+	// instead
+	Images model.ImageResolver
+}
+
+// NewExporter returns an Exporter that only includes images from data: URLs
+func NewExporter() *Exporter {
+	return &Exporter{Images: model.DataURLs}
+}
+
+// Export writes doc as an odt file to w, with NewExporter()
+func Export(w io.Writer, doc *delta.Delta) error {
+	return NewExporter().Export(w, doc)
+}
+
+// Export writes doc, an insert only delta, as an odt file to w. It only
+// fails if doc is not a document, the image resolver fails, or w does
+func (e *Exporter) Export(w io.Writer, doc *delta.Delta) error {
+	parsed, err := model.Parse(doc)
+	if err != nil {
+		return err
+	}
+	x := &exporter{
+		images:     e.Images,
+		textStyles: make(map[string]string),
+		paraStyles: make(map[string]string),
+	}
+	if x.images == nil {
+		x.images = model.DataURLs
+	}
+	for _, b := range parsed.Blocks {
+		x.block(b)
+	}
+	if x.err != nil {
+		return x.err
+	}
+
+	z := zip.NewWriter(w)
+	// the mimetype goes first and uncompressed, so the type of the file can
+	// be read at a fixed offset
+	f, err := z.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(f, mimeType); err != nil {
+		return err
+	}
+	parts := []media{
+		{name: "META-INF/manifest.xml", data: x.manifest()},
+		{name: "content.xml", data: x.content()},
+		{name: "styles.xml", data: []byte(stylesXML)},
+	}
+	for _, p := range append(parts, x.media...) {
+		f, err := z.Create(p.name)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(p.data); err != nil {
+			return err
+		}
+	}
+	return z.Close()
+}
+
+type media struct {
+	name        string
+	contentType string
+	data        []byte
+}
+
+// exporter holds the state of one Export call
+type exporter struct {
+	images model.ImageResolver
+	body   bytes.Buffer
+	// automatic styles, by the properties they have, and their definitions
+	// in the order they were made
+	textStyles map[string]string
+	paraStyles map[string]string
+	styles     bytes.Buffer
+	media      []media
+	// space is set after a space and at the start of paragraphs, where odt
+	// needs text:s to keep spaces
+	space bool
+	err   error
+}
+
+func (x *exporter) block(b model.Block) {
+	switch b := b.(type) {
+	case *model.Paragraph:
+		x.paragraph("text:p", "Standard", b.Line, true, "")
+	case *model.Heading:
+		level := b.Level
+		if level > 6 {
+			level = 6
+		}
+		x.paragraph(fmt.Sprintf(`text:h text:outline-level="%d"`, level), "Heading_20_"+strconv.Itoa(level), b.Line, true, "")
+	case *model.List:
+		x.list(b, 0)
+	case *model.Blockquote:
+		for _, l := range b.Lines {
+			x.paragraph("text:p", "Quotations", l, true, "")
+		}
+	case *model.CodeBlock:
+		for _, l := range b.Lines {
+			x.paragraph("text:p", "Preformatted_20_Text", l, false, "")
+		}
+	case *model.EmbedBlock:
+		run := model.Run{Embed: &b.Embed, Attributes: b.Attributes}
+		if src, ok := b.Embed.Value.(string); ok && b.Embed.Key == "video" {
+			run = model.Run{Text: src, Attributes: delta.Attributes{"link": src}}
+		}
+		x.paragraph("text:p", "Standard", model.Line{Runs: []model.Run{run}}, true, "")
+	}
+}
+
+// list writes l, nested in depth lists. Lists are nested one text:list per
+// indent level, as odt wants
+func (x *exporter) list(l *model.List, depth int) {
+	if l.Type == "checked" {
+		// odt has no check lists, the boxes are written as text
+		for _, item := range l.Items {
+			line := item.Line
+			line.Attributes = line.Attributes.Without("list")
+			if line.Attributes == nil {
+				line.Attributes = delta.Attributes{}
+			}
+			line.Attributes["indent"] = l.Indent + 1
+			prefix := "☐ "
+			if item.Checked {
+				prefix = "☑ "
+			}
+			x.paragraph("text:p", "Standard", line, true, prefix)
+			// the items are paragraphs, not a text:list, so nested lists
+			// are still at depth
+			for _, child := range item.Children {
+				x.list(child, depth)
+			}
+		}
+		return
+	}
+	style := "LB"
+	if l.Type == "ordered" {
+		style = "LO"
+	}
+	// lists that skip levels get empty items in between
+	for i := depth; i < l.Indent; i++ {
+		fmt.Fprintf(&x.body, `<text:list text:style-name="%s"><text:list-item>`, style)
+	}
+	fmt.Fprintf(&x.body, `<text:list text:style-name="%s">`, style)
+	for _, item := range l.Items {
+		x.body.WriteString("<text:list-item>")
+		x.paragraph("text:p", "List_20_Contents", item.Line, false, "")
+		for _, child := range item.Children {
+			x.list(child, l.Indent+1)
+		}
+		x.body.WriteString("</text:list-item>")
+	}
+	x.body.WriteString("</text:list>")
+	for i := depth; i < l.Indent; i++ {
+		x.body.WriteString("</text:list-item></text:list>")
+	}
+}
+
+// paragraph writes a line as element, text:p or text:h, with an automatic
+// style based on parent for its line formats
+func (x *exporter) paragraph(element, parent string, l model.Line, indent bool, prefix string) {
+	b := &x.body
+	name := strings.Fields(element)[0]
+	fmt.Fprintf(b, `<%s text:style-name="%s">`, element, x.paragraphStyle(parent, l.Attributes, indent))
+	x.space = true
+	if prefix != "" {
+		x.text(prefix)
+	}
+	for i := 0; i < len(l.Runs); i++ {
+		link := model.Link(l.Runs[i].Attributes)
+		if link == "" {
+			x.run(l.Runs[i])
+			continue
+		}
+		// runs next to each other with the same link share the hyperlink
+		fmt.Fprintf(b, `<text:a xlink:type="simple" xlink:href="%s" text:style-name="Internet_20_link">`, model.EscapeXML(link))
+		for ; i < len(l.Runs); i++ {
+			if next := model.Link(l.Runs[i].Attributes); next != link {
+				break
+			}
+			x.run(l.Runs[i])
+		}
+		i--
+		b.WriteString("</text:a>")
+	}
+	fmt.Fprintf(b, "</%s>", name)
+}
+
+func (x *exporter) run(r model.Run) {
+	style := x.textStyle(r.Attributes.Without("link"))
+	if style != "" {
+		fmt.Fprintf(&x.body, `<text:span text:style-name="%s">`, style)
+	}
+	if r.Embed == nil {
+		x.text(r.Text)
+	} else {
+		src, _ := r.Embed.Value.(string)
+		switch r.Embed.Key {
+		case "image":
+			x.image(src, r.Attributes)
+		case "formula":
+			x.text(src)
+		}
+	}
+	if style != "" {
+		x.body.WriteString("</text:span>")
+	}
+}
+
+// text writes text, with text:tab for tabs and text:s for the spaces odt
+// would collapse
+func (x *exporter) text(text string) {
+	b := &x.body
+	var plain []rune
+	flush := func() {
+		b.WriteString(model.EscapeXML(string(plain)))
+		plain = plain[:0]
+	}
+	spaces := 0
+	for _, r := range text {
+		if r == ' ' && x.space {
+			spaces++
+			continue
+		}
+		if spaces > 0 {
+			flush()
+			fmt.Fprintf(b, `<text:s text:c="%d"/>`, spaces)
+			spaces = 0
+		}
+		if r == '\t' {
+			flush()
+			b.WriteString("<text:tab/>")
+			x.space = false
+			continue
+		}
+		plain = append(plain, r)
+		x.space = r == ' '
+	}
+	flush()
+	if spaces > 0 {
+		fmt.Fprintf(b, `<text:s text:c="%d"/>`, spaces)
+	}
+}
+
+// image writes a frame with the image, or the alt text when there is no
+// image
+func (x *exporter) image(src string, attrs delta.Attributes) {
+	alt, _ := attrs.String("alt")
+	img, err := x.images.ResolveImage(src)
+	if err != nil {
+		if x.err == nil {
+			x.err = err
+		}
+		return
+	}
+	ext := ""
+	if img != nil {
+		ext = imageTypes[strings.ToLower(img.ContentType)]
+	}
+	if ext == "" {
+		x.text(alt)
+		return
+	}
+	width, height := img.DisplaySize(attrs, maxImageWidth)
+	n := len(x.media) + 1
+	name := fmt.Sprintf("Pictures/image%d.%s", n, ext)
+	x.media = append(x.media, media{name: name, contentType: strings.ToLower(img.ContentType), data: img.Data})
+	fmt.Fprintf(&x.body, `<draw:frame draw:name="Image%d" text:anchor-type="as-char" svg:width="%s" svg:height="%s">`+
+		`<draw:image xlink:href="%s" xlink:type="simple" xlink:show="embed" xlink:actuate="onLoad"/>`,
+		n, inches(float64(width)/96), inches(float64(height)/96), name)
+	if alt != "" {
+		fmt.Fprintf(&x.body, "<svg:desc>%s</svg:desc>", model.EscapeXML(alt))
+	}
+	x.body.WriteString("</draw:frame>")
+	x.space = false
+}
+
+// paragraphStyle returns the automatic style for the line formats, parent
+// itself when there are none
+func (x *exporter) paragraphStyle(parent string, attrs delta.Attributes, indent bool) string {
+	var props []string
+	if align, _ := attrs.String("align"); alignments[align] != "" {
+		props = append(props, fmt.Sprintf(`fo:text-align="%s"`, alignments[align]))
+	}
+	if dir, _ := attrs.String("direction"); dir == "rtl" {
+		props = append(props, `style:writing-mode="rl-tb"`)
+	}
+	if n, ok := attrs.Int("indent"); ok && n > 0 && indent {
+		props = append(props, fmt.Sprintf(`fo:margin-left="%s"`, inches(float64(n)*indentInches)))
+	}
+	if len(props) == 0 {
+		return parent
+	}
+	key := parent + " " + strings.Join(props, " ")
+	if name, ok := x.paraStyles[key]; ok {
+		return name
+	}
+	name := "P" + strconv.Itoa(len(x.paraStyles)+1)
+	x.paraStyles[key] = name
+	fmt.Fprintf(&x.styles, `<style:style style:name="%s" style:family="paragraph" style:parent-style-name="%s">`+
+		`<style:paragraph-properties %s/></style:style>`, name, parent, strings.Join(props, " "))
+	return name
+}
+
+// textStyle returns the automatic style for the inline formats, or "" when
+// there are none
+func (x *exporter) textStyle(attrs delta.Attributes) string {
+	var props []string
+	font, _ := attrs.String("font")
+	font = model.FontName(font)
+	if code, _ := attrs.Bool("code"); code {
+		font = model.CodeFont
+	}
+	if font != "" {
+		props = append(props, fmt.Sprintf(`fo:font-family="%s"`, model.EscapeXML("'"+font+"'")))
+	}
+	if v, _ := attrs.Bool("bold"); v {
+		props = append(props, `fo:font-weight="bold" style:font-weight-asian="bold" style:font-weight-complex="bold"`)
+	}
+	if v, _ := attrs.Bool("italic"); v {
+		props = append(props, `fo:font-style="italic" style:font-style-asian="italic" style:font-style-complex="italic"`)
+	}
+	if v, _ := attrs.Bool("underline"); v {
+		props = append(props, `style:text-underline-style="solid" style:text-underline-width="auto" style:text-underline-color="font-color"`)
+	}
+	if v, _ := attrs.Bool("strike"); v {
+		props = append(props, `style:text-line-through-style="solid"`)
+	}
+	if color, ok := attrs.String("color"); ok {
+		if c, ok := delta.NormalizeColor(color); ok {
+			props = append(props, fmt.Sprintf(`fo:color="%s"`, c))
+		}
+	}
+	if background, ok := attrs.String("background"); ok {
+		if c, ok := delta.NormalizeColor(background); ok {
+			props = append(props, fmt.Sprintf(`fo:background-color="%s"`, c))
+		}
+	}
+	if size, ok := attrs.String("size"); ok {
+		if pt, ok := model.Points(size); ok {
+			props = append(props, fmt.Sprintf(`fo:font-size="%spt"`, strconv.FormatFloat(pt, 'f', -1, 64)))
+		}
+	}
+	switch script, _ := attrs.String("script"); script {
+	case "super":
+		props = append(props, `style:text-position="super 58%"`)
+	case "sub":
+		props = append(props, `style:text-position="sub 58%"`)
+	}
+	if len(props) == 0 {
+		return ""
+	}
+	key := strings.Join(props, " ")
+	if name, ok := x.textStyles[key]; ok {
+		return name
+	}
+	name := "T" + strconv.Itoa(len(x.textStyles)+1)
+	x.textStyles[key] = name
+	fmt.Fprintf(&x.styles, `<style:style style:name="%s" style:family="text"><style:text-properties %s/></style:style>`, name, key)
+	return name
+}
+
+func inches(f float64) string {
+	return strconv.FormatFloat(f, 'f', 4, 64) + "in"
+}
+
+func (x *exporter) content() []byte {
+	var b bytes.Buffer
+	b.WriteString(xmlHeader)
+	fmt.Fprintf(&b, `<office:document-content xmlns:office="%s" xmlns:style="%s" xmlns:text="%s" xmlns:draw="%s" `+
+		`xmlns:fo="%s" xmlns:xlink="%s" xmlns:svg="%s" office:version="1.2">`,
+		nsOffice, nsStyle, nsText, nsDraw, nsFO, nsXlink, nsSVG)
+	b.WriteString("<office:automatic-styles>")
+	b.WriteString(listStyle("LB", false))
+	b.WriteString(listStyle("LO", true))
+	b.Write(x.styles.Bytes())
+	b.WriteString("</office:automatic-styles><office:body><office:text>")
+	b.Write(x.body.Bytes())
+	b.WriteString("</office:text></office:body></office:document-content>")
+	return b.Bytes()
+}
+
+// listStyle returns a list style with the 10 levels of odt, bullets or
+// numbers
+func listStyle(name string, ordered bool) string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<text:list-style style:name="%s">`, name)
+	bullets := []string{"•", "◦", "▪"}
+	formats := []string{"1", "a", "i"}
+	for level := 1; level <= 10; level++ {
+		element := "text:list-level-style-bullet"
+		if ordered {
+			fmt.Fprintf(&b, `<text:list-level-style-number text:level="%d" style:num-suffix="." style:num-format="%s">`,
+				level, formats[(level-1)%3])
+			element = "text:list-level-style-number"
+		} else {
+			fmt.Fprintf(&b, `<text:list-level-style-bullet text:level="%d" text:bullet-char="%s">`, level, bullets[(level-1)%3])
+		}
+		fmt.Fprintf(&b, `<style:list-level-properties text:list-level-position-and-space-mode="label-alignment">`+
+			`<style:list-level-label-alignment text:label-followed-by="listtab" text:list-tab-stop-position="%[1]s" `+
+			`fo:text-indent="-0.2500in" fo:margin-left="%[1]s"/></style:list-level-properties></%[2]s>`,
+			inches(float64(level)*indentInches), element)
+	}
+	b.WriteString("</text:list-style>")
+	return b.String()
+}
+
+func (x *exporter) manifest() []byte {
+	var b bytes.Buffer
+	b.WriteString(xmlHeader)
+	fmt.Fprintf(&b, `<manifest:manifest xmlns:manifest="%s" manifest:version="1.2">`+
+		`<manifest:file-entry manifest:full-path="/" manifest:version="1.2" manifest:media-type="%s"/>`+
+		`<manifest:file-entry manifest:full-path="content.xml" manifest:media-type="text/xml"/>`+
+		`<manifest:file-entry manifest:full-path="styles.xml" manifest:media-type="text/xml"/>`, nsManifest, mimeType)
+	names := make([]string, len(x.media))
+	types := make(map[string]string)
+	for i, m := range x.media {
+		names[i] = m.name
+		types[m.name] = m.contentType
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, `<manifest:file-entry manifest:full-path="%s" manifest:media-type="%s"/>`, name, types[name])
+	}
+	b.WriteString("</manifest:manifest>")
+	return b.Bytes()
+}
+
+var stylesXML = xmlHeader + `<office:document-styles xmlns:office="` + nsOffice + `" xmlns:style="` + nsStyle +
+	`" xmlns:text="` + nsText + `" xmlns:fo="` + nsFO + `" office:version="1.2"><office:styles>` +
+	`<style:default-style style:family="paragraph"><style:paragraph-properties fo:margin-bottom="0.0833in"/>` +
+	`<style:text-properties fo:font-family="Calibri" fo:font-size="11pt"/></style:default-style>` +
+	`<style:style style:name="Standard" style:family="paragraph" style:class="text"/>` +
+	`<style:style style:name="Heading" style:family="paragraph" style:parent-style-name="Standard" style:next-style-name="Standard" style:class="text">` +
+	`<style:paragraph-properties fo:margin-top="0.1665in" fo:keep-with-next="always"/>` +
+	`<style:text-properties fo:font-weight="bold" style:font-weight-asian="bold" style:font-weight-complex="bold"/></style:style>` +
+	headingStyle(1, 20) + headingStyle(2, 16) + headingStyle(3, 14) +
+	headingStyle(4, 12) + headingStyle(5, 11) + headingStyle(6, 11) +
+	`<style:style style:name="Quotations" style:display-name="Quotations" style:family="paragraph" style:parent-style-name="Standard" style:class="html">` +
+	`<style:paragraph-properties fo:margin-left="0.25in" fo:padding-left="0.1in" fo:border-left="0.04in solid #cccccc"/>` +
+	`<style:text-properties fo:font-style="italic" fo:color="#555555"/></style:style>` +
+	`<style:style style:name="Preformatted_20_Text" style:display-name="Preformatted Text" style:family="paragraph" style:parent-style-name="Standard" style:class="html">` +
+	`<style:paragraph-properties fo:margin-bottom="0in" fo:background-color="#f0f0f0"/>` +
+	`<style:text-properties fo:font-family="'` + model.CodeFont + `'" fo:font-size="10pt"/></style:style>` +
+	`<style:style style:name="List_20_Contents" style:display-name="List Contents" style:family="paragraph" style:parent-style-name="Standard" style:class="list">` +
+	`<style:paragraph-properties fo:margin-bottom="0in"/></style:style>` +
+	`<style:style style:name="Internet_20_link" style:display-name="Internet link" style:family="text">` +
+	`<style:text-properties fo:color="#0563c1" style:text-underline-style="solid" style:text-underline-width="auto" style:text-underline-color="font-color"/></style:style>` +
+	`</office:styles></office:document-styles>`
+
+func headingStyle(level int, size int) string {
+	return fmt.Sprintf(`<style:style style:name="Heading_20_%[1]d" style:display-name="Heading %[1]d" style:family="paragraph" `+
+		`style:parent-style-name="Heading" style:next-style-name="Standard" style:default-outline-level="%[1]d" style:class="text">`+
+		`<style:text-properties fo:font-size="%[2]dpt"/></style:style>`, level, size)
+}
@@ -0,0 +1,194 @@
+package odt
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+	"github.com/fmpwizard/go-quilljs-delta/model"
+)
+
+func testPNG(w, h int) []byte {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h)))
+	return buf.Bytes()
+}
+
+// unzip returns the parts of an odt file, checking the mimetype comes first
+// and every xml part is well formed
+func unzip(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(z.File) == 0 || z.File[0].Name != "mimetype" || z.File[0].Method != zip.Store {
+		t.Fatalf("the mimetype must be the first part, stored")
+	}
+	parts := make(map[string]string)
+	for _, f := range z.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := ioutil.ReadAll(r)
+		r.Close()
+		parts[f.Name] = string(b)
+		if !strings.HasSuffix(f.Name, ".xml") {
+			continue
+		}
+		dec := xml.NewDecoder(bytes.NewReader(b))
+		for {
+			if _, err := dec.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s is not well formed: %v", f.Name, err)
+			}
+		}
+	}
+	return parts
+}
+
+func TestExport(t *testing.T) {
+	img := "data:image/png;base64," + base64.StdEncoding.EncodeToString(testPNG(96, 48))
+	doc := delta.New(nil).
+		Insert("Title", nil).Insert("\n", delta.Attributes{"header": 1}).
+		Insert("  Some ", nil).Insert("bold", delta.Attributes{"bold": true, "color": "red"}).
+		Insert("  &\t", nil).Insert("linked", delta.Attributes{"link": "https://example.com/?a=1&b=2", "italic": true}).
+		Insert(" bold again", delta.Attributes{"bold": true, "color": "#f00"}).
+		Insert("\n", delta.Attributes{"align": "center"}).
+		Insert("one", nil).Insert("\n", delta.Attributes{"list": "ordered"}).
+		Insert("deep", nil).Insert("\n", delta.Attributes{"list": "bullet", "indent": 2}).
+		Insert("two", nil).Insert("\n", delta.Attributes{"list": "ordered"}).
+		Insert("todo", nil).Insert("\n", delta.Attributes{"list": "unchecked"}).
+		Insert("x := 1", nil).Insert("\n", delta.Attributes{"code-block": true}).
+		Insert("quote", nil).Insert("\n", delta.Attributes{"blockquote": true, "align": "center"}).
+		InsertEmbed(delta.Embed{Key: "image", Value: img}, delta.Attributes{"alt": "a <picture>"}).
+		InsertEmbed(delta.Embed{Key: "image", Value: "https://example.com/a.png"}, delta.Attributes{"alt": "remote"}).
+		Insert("\n", nil)
+
+	var buf bytes.Buffer
+	if err := Export(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	parts := unzip(t, buf.Bytes())
+	if parts["mimetype"] != mimeType {
+		t.Errorf("unexpected mimetype %q", parts["mimetype"])
+	}
+	for _, name := range []string{"META-INF/manifest.xml", "content.xml", "styles.xml", "Pictures/image1.png"} {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("missing part %s", name)
+		}
+	}
+
+	content := parts["content.xml"]
+	for _, s := range []string{
+		`<text:h text:outline-level="1" text:style-name="Heading_20_1">Title</text:h>`,
+		// both bold and red runs share the same automatic style
+		`<style:style style:name="T1" style:family="text"><style:text-properties fo:font-weight="bold" ` +
+			`style:font-weight-asian="bold" style:font-weight-complex="bold" fo:color="#ff0000"/></style:style>`,
+		`<style:style style:name="T2" style:family="text"><style:text-properties fo:font-style="italic"`,
+		`<style:style style:name="P1" style:family="paragraph" style:parent-style-name="Standard">` +
+			`<style:paragraph-properties fo:text-align="center"/></style:style>`,
+		`<style:style style:name="P3" style:family="paragraph" style:parent-style-name="Quotations">`,
+		`<text:p text:style-name="P1"><text:s text:c="2"/>Some <text:span text:style-name="T1">bold</text:span> <text:s text:c="1"/>&amp;<text:tab/>` +
+			`<text:a xlink:type="simple" xlink:href="https://example.com/?a=1&amp;b=2" text:style-name="Internet_20_link">` +
+			`<text:span text:style-name="T2">linked</text:span></text:a><text:span text:style-name="T1"> bold again</text:span></text:p>`,
+		`<text:list text:style-name="LO"><text:list-item><text:p text:style-name="List_20_Contents">one</text:p>` +
+			`<text:list text:style-name="LB"><text:list-item><text:list text:style-name="LB"><text:list-item>` +
+			`<text:p text:style-name="List_20_Contents">deep</text:p></text:list-item></text:list></text:list-item></text:list>` +
+			`</text:list-item><text:list-item><text:p text:style-name="List_20_Contents">two</text:p></text:list-item></text:list>`,
+		`<text:p text:style-name="P2">☐ todo</text:p>`,
+		`<text:p text:style-name="Preformatted_20_Text">x := 1</text:p>`,
+		`<draw:frame draw:name="Image1" text:anchor-type="as-char" svg:width="1.0000in" svg:height="0.5000in">` +
+			`<draw:image xlink:href="Pictures/image1.png" xlink:type="simple" xlink:show="embed" xlink:actuate="onLoad"/>` +
+			`<svg:desc>a &lt;picture&gt;</svg:desc></draw:frame>remote</text:p>`,
+	} {
+		if !strings.Contains(content, s) {
+			t.Errorf("content.xml is missing %s\n%s", s, content)
+		}
+	}
+	if strings.Count(content, `style:family="text"`) != 2 {
+		t.Errorf("expected 2 text styles\n%s", content)
+	}
+	if !strings.Contains(parts["META-INF/manifest.xml"],
+		`<manifest:file-entry manifest:full-path="Pictures/image1.png" manifest:media-type="image/png"/>`) {
+		t.Errorf("picture missing from the manifest\n%s", parts["META-INF/manifest.xml"])
+	}
+}
+
+func TestExportImageResolver(t *testing.T) {
+	doc := delta.New(nil).
+		InsertEmbed(delta.Embed{Key: "image", Value: "https://example.com/a.png"}, nil).
+		Insert("\n", nil)
+	e := &Exporter{Images: model.ImageResolverFunc(func(src string) (*model.Image, error) {
+		return &model.Image{Data: testPNG(10, 10), ContentType: "image/png"}, nil
+	})}
+	var buf bytes.Buffer
+	if err := e.Export(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := unzip(t, buf.Bytes())["Pictures/image1.png"]; !ok {
+		t.Errorf("image not in the package")
+	}
+
+	fail := errors.New("fail")
+	e.Images = model.ImageResolverFunc(func(src string) (*model.Image, error) {
+		return nil, fail
+	})
+	if err := e.Export(&buf, doc); err != fail {
+		t.Errorf("expected the resolver error, got %v", err)
+	}
+	if err := Export(ioutil.Discard, delta.New(nil).Delete(1)); err != model.ErrNotDocument {
+		t.Errorf("expected ErrNotDocument, got %v", err)
+	}
+}
+
+func TestExportUnsafeLinks(t *testing.T) {
+	doc := delta.New(nil).
+		Insert("click", delta.Attributes{"link": "javascript:alert(1)"}).
+		Insert(" here", delta.Attributes{"link": " JaVa\tscript:alert(1)"}).
+		Insert("\n", nil).
+		InsertEmbed(delta.Embed{Key: "video", Value: "javascript:alert(1)"}, nil).
+		Insert("\n", nil)
+	var buf bytes.Buffer
+	if err := Export(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	content := unzip(t, buf.Bytes())["content.xml"]
+	if strings.Contains(content, "<text:a ") || !strings.Contains(content, "click here") {
+		t.Errorf("expected unsafe links as plain text\n%s", content)
+	}
+}
+
+func TestExportNestedCheckList(t *testing.T) {
+	doc := delta.New(nil).
+		Insert("parent", nil).Insert("\n", delta.Attributes{"list": "unchecked"}).
+		Insert("bullet", nil).Insert("\n", delta.Attributes{"list": "bullet", "indent": 1}).
+		Insert("child", nil).Insert("\n", delta.Attributes{"list": "checked", "indent": 1}).
+		Insert("deeper", nil).Insert("\n", delta.Attributes{"list": "ordered", "indent": 2})
+	var buf bytes.Buffer
+	if err := Export(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	content := unzip(t, buf.Bytes())["content.xml"]
+	for _, s := range []string{
+		`>☐ parent</text:p><text:list text:style-name="LB"><text:list-item><text:list text:style-name="LB"><text:list-item>` +
+			`<text:p text:style-name="List_20_Contents">bullet</text:p></text:list-item></text:list></text:list-item></text:list>`,
+		`>☑ child</text:p><text:list text:style-name="LO"><text:list-item><text:list text:style-name="LO"><text:list-item>` +
+			`<text:list text:style-name="LO"><text:list-item><text:p text:style-name="List_20_Contents">deeper</text:p>`,
+	} {
+		if !strings.Contains(content, s) {
+			t.Errorf("content.xml is missing %s\n%s", s, content)
+		}
+	}
+}
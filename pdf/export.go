@@ -0,0 +1,639 @@
+// Package pdf renders Quill documents, insert only deltas, as PDF files
+// for printing, without a browser or any service. Text is set in the
+// standard Helvetica and Courier fonts, wrapped to the width of the page and
+// broken across pages; links with safe URLs, see delta.Sanitizer, become
+// link annotations and images are embedded.
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+	"github.com/fmpwizard/go-quilljs-delta/model"
+)
+
+// ErrPageTooSmall is returned for pages that have no room left for text
+// inside their margins
+var ErrPageTooSmall = errors.New("pdf: no room for text inside the margins")
+
+const (
+	// lineSpacing is the height of lines, relative to their font size
+	lineSpacing = 1.25
+	// indentWidth is how much each level of quill's indent format moves a
+	// paragraph, half an inch
+	indentWidth = 36
+	// listIndent is the room list items leave for their marker
+	listIndent = 24
+	// quoteIndent is how far blockquotes are from the margin
+	quoteIndent = 18
+	// pointsPerPixel converts css pixels to points
+	pointsPerPixel = 0.75
+)
+
+// sizes are the font sizes of quill's size format, relative to the size of
+// body text, as in quill's stylesheet
+var sizes = map[string]float64{"small": 0.75, "large": 1.5, "huge": 2.5}
+
+// headingSizes are the font sizes of the header levels, relative to the
+// size of body text, as browsers show h1 to h6
+var headingSizes = []float64{2, 1.5, 1.17, 1, 0.83, 0.67}
+
+const (
+	linkColor  = "#0000ee"
+	quoteColor = "#cccccc"
+	codeColor  = "#f0f0f0"
+)
+
+// Exporter renders documents as PDF
+type Exporter struct {
+	// Images gets the content of image embeds. When it returns no image, or
+	// one it can't decode, the alt text of the embed is used instead
+	Images model.ImageResolver
+	// PageWidth and PageHeight are the size of the pages, and Margin the
+	// space around the text, in points
+	PageWidth, PageHeight, Margin float64
+	// FontSize is the size of body text, in points
+	FontSize float64
+}
+
+// NewExporter returns an Exporter for US letter pages with one inch
+// margins, that only includes images from data: URLs
+func NewExporter() *Exporter {
+	return &Exporter{
+		Images:     model.DataURLs,
+		PageWidth:  612,
+		PageHeight: 792,
+		Margin:     72,
+		FontSize:   11,
+	}
+}
+
+// Export writes doc as a PDF file to w, with NewExporter()
+func Export(w io.Writer, doc *delta.Delta) error {
+	return NewExporter().Export(w, doc)
+}
+
+// Export writes doc, an insert only delta, as a PDF file to w. Fields left
+// zero take the value NewExporter gives them
+func (e *Exporter) Export(w io.Writer, doc *delta.Delta) error {
+	parsed, err := model.Parse(doc)
+	if err != nil {
+		return err
+	}
+	defaults := NewExporter()
+	x := &exporter{
+		images:     e.Images,
+		pageWidth:  e.PageWidth,
+		pageHeight: e.PageHeight,
+		margin:     e.Margin,
+		fontSize:   e.FontSize,
+		fonts:      make(map[font]bool),
+		imageIndex: make(map[string]int),
+	}
+	if x.images == nil {
+		x.images = defaults.Images
+	}
+	if x.pageWidth <= 0 || x.pageHeight <= 0 {
+		x.pageWidth, x.pageHeight = defaults.PageWidth, defaults.PageHeight
+	}
+	if x.margin <= 0 {
+		x.margin = defaults.Margin
+	}
+	if x.fontSize <= 0 {
+		x.fontSize = defaults.FontSize
+	}
+	if x.pageWidth-2*x.margin < 4*x.fontSize || x.pageHeight-2*x.margin < 4*x.fontSize {
+		return ErrPageTooSmall
+	}
+
+	x.newPage()
+	for _, b := range parsed.Blocks {
+		x.block(b)
+	}
+	if x.err != nil {
+		return x.err
+	}
+	_, err = w.Write(x.file())
+	return err
+}
+
+// exporter holds the state of one Export call
+type exporter struct {
+	images                        model.ImageResolver
+	pageWidth, pageHeight, margin float64
+	fontSize                      float64
+	pages                         []*page
+	page                          *page
+	// y is where the next line goes, from the bottom of the page
+	y          float64
+	fonts      map[font]bool
+	pictures   []*picture
+	imageIndex map[string]int
+	err        error
+}
+
+// page is the content of one page
+type page struct {
+	content bytes.Buffer
+	links   []link
+}
+
+// link is the area of a link annotation
+type link struct {
+	x0, y0, x1, y1 float64
+	uri            string
+}
+
+// style is how a piece of text is set
+type style struct {
+	font              font
+	size              float64
+	color, background string
+	underline, strike bool
+	// rise moves the text up from the baseline, for super and subscripts
+	rise float64
+	link string
+}
+
+// fragment is a word, a space or an image, what lines are made of
+type fragment struct {
+	// text is in WinAnsiEncoding
+	text  string
+	style style
+	width float64
+	space bool
+	// picture is the number of the image of image fragments, from 1
+	picture int
+	height  float64
+}
+
+// marker is the bullet, number or check box in front of a list item
+type marker struct {
+	text     string
+	checkbox bool
+	checked  bool
+}
+
+// layout is how the lines of a block are placed
+type layout struct {
+	// left is how far the lines are from the margin
+	left          float64
+	align         string
+	before, after float64
+	marker        *marker
+	quote, code   bool
+	// keep keeps the block on the same page as the lines after it
+	keep bool
+}
+
+func (x *exporter) newPage() {
+	x.page = &page{}
+	x.pages = append(x.pages, x.page)
+	x.y = x.pageHeight - x.margin
+}
+
+// top reports whether nothing was written on the page yet
+func (x *exporter) top() bool {
+	return x.y == x.pageHeight-x.margin
+}
+
+func (x *exporter) body() style {
+	return style{font: font{family: helvetica}, size: x.fontSize}
+}
+
+func (x *exporter) block(b model.Block) {
+	switch b := b.(type) {
+	case *model.Paragraph:
+		x.line(b.Line, x.body(), layout{})
+	case *model.Heading:
+		level := b.Level
+		if level > len(headingSizes) {
+			level = len(headingSizes)
+		}
+		base := x.body()
+		base.font.bold = true
+		base.size = x.fontSize * headingSizes[level-1]
+		x.line(b.Line, base, layout{before: base.size * 0.6, after: base.size * 0.3, keep: true})
+	case *model.List:
+		x.list(b)
+	case *model.Blockquote:
+		for _, l := range b.Lines {
+			x.line(l, x.body(), layout{left: quoteIndent, quote: true})
+		}
+	case *model.CodeBlock:
+		base := x.body()
+		base.font.family = courier
+		base.size = x.fontSize * 0.9
+		for i, l := range b.Lines {
+			text := strings.Replace(l.Text(), "\t", "    ", -1)
+			lay := layout{code: true}
+			if i == 0 {
+				lay.before = 4
+			}
+			if i == len(b.Lines)-1 {
+				lay.after = 4
+			}
+			x.paragraph(words(encode(text), base, nil), base, lay)
+		}
+	case *model.EmbedBlock:
+		src, _ := b.Embed.Value.(string)
+		if b.Embed.Key == "video" && src != "" {
+			x.line(model.Line{Runs: []model.Run{{Text: src, Attributes: delta.Attributes{"link": src}}}}, x.body(), layout{})
+		} else {
+			x.line(model.Line{Runs: []model.Run{{Embed: &b.Embed, Attributes: b.Attributes}}}, x.body(), layout{})
+		}
+	}
+}
+
+// line lays out l with the block attributes it has, on top of lay
+func (x *exporter) line(l model.Line, base style, lay layout) {
+	indent, _ := l.Attributes.Int("indent")
+	if indent > 0 {
+		lay.left += float64(indent) * indentWidth
+	}
+	lay.align, _ = l.Attributes.String("align")
+	x.paragraph(x.fragments(l.Runs, base, lay.left), base, lay)
+}
+
+func (x *exporter) list(l *model.List) {
+	for n, item := range l.Items {
+		m := &marker{text: "•"}
+		switch l.Type {
+		case "ordered":
+			m.text = strconv.Itoa(n+1) + "."
+		case "checked":
+			m = &marker{checkbox: true, checked: item.Checked}
+		}
+		x.line(item.Line, x.body(), layout{left: listIndent, marker: m})
+		for _, child := range item.Children {
+			x.list(child)
+		}
+	}
+}
+
+// fragments returns the words, spaces and images of runs, set in base
+// with the formats of each run. Images are no wider than the text, left
+// from the margin
+func (x *exporter) fragments(runs []model.Run, base style, left float64) []fragment {
+	var frags []fragment
+	for _, r := range runs {
+		s := x.style(r.Attributes, base)
+		switch {
+		case r.Embed == nil:
+			frags = words(encode(r.Text), s, frags)
+		case r.Embed.Key == "image":
+			src, _ := r.Embed.Value.(string)
+			if f, ok := x.image(src, r.Attributes, s, x.pageWidth-2*x.margin-left); ok {
+				frags = append(frags, f)
+			} else {
+				alt, _ := r.Attributes.String("alt")
+				frags = words(encode(alt), s, frags)
+			}
+		case r.Embed.Key == "formula":
+			formula, _ := r.Embed.Value.(string)
+			s.font.italic = true
+			frags = words(encode(formula), s, frags)
+		}
+	}
+	return frags
+}
+
+// style returns base with the formats of attrs
+func (x *exporter) style(attrs delta.Attributes, base style) style {
+	s := base
+	if v, _ := attrs.Bool("bold"); v {
+		s.font.bold = true
+	}
+	if v, _ := attrs.Bool("italic"); v {
+		s.font.italic = true
+	}
+	if v, _ := attrs.Bool("code"); v {
+		s.font.family = courier
+	}
+	if f, _ := attrs.String("font"); f == "monospace" {
+		s.font.family = courier
+	}
+	if size, _ := attrs.String("size"); sizes[size] != 0 {
+		s.size = x.fontSize * sizes[size]
+	}
+	if v, _ := attrs.Bool("underline"); v {
+		s.underline = true
+	}
+	if v, _ := attrs.Bool("strike"); v {
+		s.strike = true
+	}
+	if link := model.Link(attrs); link != "" {
+		s.link = link
+		s.color = linkColor
+		if v, ok := attrs.Bool("underline"); !ok || v {
+			s.underline = true
+		}
+	}
+	if color, ok := attrs.String("color"); ok {
+		if c, ok := delta.NormalizeColor(color); ok {
+			s.color = c
+		}
+	}
+	if background, ok := attrs.String("background"); ok {
+		if c, ok := delta.NormalizeColor(background); ok {
+			s.background = c
+		}
+	}
+	switch script, _ := attrs.String("script"); script {
+	case "super":
+		s.rise = s.size * 0.35
+		s.size *= 0.7
+	case "sub":
+		s.rise = -s.size * 0.15
+		s.size *= 0.7
+	}
+	return s
+}
+
+// words appends the words and spaces of text, in WinAnsiEncoding, to frags
+func words(text string, s style, frags []fragment) []fragment {
+	for len(text) > 0 {
+		n := strings.IndexByte(text, ' ')
+		switch {
+		case n == 0:
+			n = 1
+		case n < 0:
+			n = len(text)
+		}
+		word := text[:n]
+		frags = append(frags, fragment{text: word, style: s, width: s.font.width(word, s.size), space: word == " "})
+		text = text[n:]
+	}
+	return frags
+}
+
+// lines breaks frags into lines no wider than width. Lines break between
+// words, words wider than a line are broken where they reach its end
+func (x *exporter) lines(frags []fragment, width float64) [][]fragment {
+	var lines [][]fragment
+	var line []fragment
+	used := 0.0
+	for i := 0; i < len(frags); i++ {
+		f := frags[i]
+		if f.space && len(line) == 0 && len(lines) > 0 {
+			// wrapped lines don't start with a space
+			continue
+		}
+		if !f.space && (i == 0 || frags[i-1].space) && len(line) > 0 {
+			// a word can be made of several fragments, with different
+			// formats, it goes to the next line as a whole
+			word := 0.0
+			for j := i; j < len(frags) && !frags[j].space; j++ {
+				word += frags[j].width
+			}
+			if used+word > width {
+				lines = append(lines, line)
+				line, used = nil, 0
+			}
+		}
+		if used+f.width > width && len(line) > 0 && (f.space || f.picture > 0 || f.width <= width) {
+			lines = append(lines, line)
+			line, used = nil, 0
+			if f.space {
+				continue
+			}
+		}
+		if used+f.width > width && f.picture == 0 && len(f.text) > 1 {
+			head, tail := split(f, width-used)
+			line = append(line, head)
+			lines = append(lines, line)
+			line, used = nil, 0
+			frags[i] = tail
+			i--
+			continue
+		}
+		line = append(line, f)
+		used += f.width
+	}
+	if len(line) > 0 || len(lines) == 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// split breaks a word into the part that fits in width, at least one
+// character, and the rest
+func split(f fragment, width float64) (fragment, fragment) {
+	n := 1
+	for n < len(f.text)-1 && f.style.font.width(f.text[:n+1], f.style.size) <= width {
+		n++
+	}
+	head, tail := f, f
+	head.text, tail.text = f.text[:n], f.text[n:]
+	head.width = f.style.font.width(head.text, f.style.size)
+	tail.width = f.style.font.width(tail.text, f.style.size)
+	return head, tail
+}
+
+// paragraph places frags as lines on the pages
+func (x *exporter) paragraph(frags []fragment, base style, lay layout) {
+	left := x.margin + lay.left
+	right := x.pageWidth - x.margin
+	lines := x.lines(frags, right-left)
+	if !x.top() {
+		x.y -= lay.before
+	}
+	for i, line := range lines {
+		for len(line) > 0 && line[len(line)-1].space {
+			line = line[:len(line)-1]
+		}
+		above, below := base.size*0.95, base.size*(lineSpacing-0.95)
+		for _, f := range line {
+			a, b := f.style.size*0.95+f.style.rise, f.style.size*(lineSpacing-0.95)-f.style.rise
+			if f.picture > 0 {
+				a, b = f.height, 2
+			}
+			if a > above {
+				above = a
+			}
+			if b > below {
+				below = b
+			}
+		}
+		height := above + below
+		need := height
+		if lay.keep && i == len(lines)-1 {
+			need += 2 * x.fontSize * lineSpacing
+		}
+		if x.y-need < x.margin && !x.top() {
+			x.newPage()
+		}
+		baseline := x.y - above
+		c := &x.page.content
+		if lay.code {
+			fmt.Fprintf(c, "%s %s %s %s %s re f\n", rgb(codeColor), num(left-4), num(x.y-height), num(right-left+8), num(height))
+		}
+		if lay.quote {
+			fmt.Fprintf(c, "%s %s %s 3 %s re f\n", rgb(quoteColor), num(left-quoteIndent+4), num(x.y-height), num(height))
+		}
+		if lay.marker != nil && i == 0 {
+			x.marker(lay.marker, left, baseline, base)
+		}
+
+		width := 0.0
+		spaces := 0
+		for _, f := range line {
+			width += f.width
+			if f.space {
+				spaces++
+			}
+		}
+		pos, stretch := left, 0.0
+		switch lay.align {
+		case "center":
+			pos += (right - left - width) / 2
+		case "right":
+			pos += right - left - width
+		case "justify":
+			if i < len(lines)-1 && spaces > 0 {
+				stretch = (right - left - width) / float64(spaces)
+			}
+		}
+		for _, f := range line {
+			w := f.width
+			if f.space {
+				w += stretch
+			}
+			x.draw(f, pos, baseline, w)
+			pos += w
+		}
+		x.y -= height
+	}
+	x.y -= lay.after
+}
+
+// marker draws m in front of a list item that starts at left
+func (x *exporter) marker(m *marker, left, baseline float64, base style) {
+	c := &x.page.content
+	if m.checkbox {
+		size := base.size * 0.7
+		x0 := left - listIndent/2 - size/2
+		fmt.Fprintf(c, "0 G 0.8 w %s %s %s %s re S\n", num(x0), num(baseline), num(size), num(size))
+		if m.checked {
+			fmt.Fprintf(c, "1.2 w %s %s m %s %s l %s %s l S\n",
+				num(x0+size*0.2), num(baseline+size*0.5), num(x0+size*0.45), num(baseline+size*0.2),
+				num(x0+size*0.85), num(baseline+size*0.85))
+		}
+		return
+	}
+	text := encode(m.text)
+	width := base.font.width(text, base.size)
+	x.draw(fragment{text: text, style: base, width: width}, left-width-6, baseline, width)
+}
+
+// draw writes f at pos, with width the room it takes on its line
+func (x *exporter) draw(f fragment, pos, baseline, width float64) {
+	c := &x.page.content
+	s := f.style
+	if f.picture > 0 {
+		fmt.Fprintf(c, "q %s 0 0 %s %s %s cm /Im%d Do Q\n", num(f.width), num(f.height), num(pos), num(baseline), f.picture)
+		x.addLink(s.link, pos, baseline, pos+width, baseline+f.height)
+		return
+	}
+	color := s.color
+	if color == "" {
+		color = "#000000"
+	}
+	if s.background != "" {
+		fmt.Fprintf(c, "%s %s %s %s %s re f\n", rgb(s.background), num(pos), num(baseline+s.rise-s.size*0.25), num(width), num(s.size*1.15))
+	}
+	if !f.space {
+		x.fonts[s.font] = true
+		fmt.Fprintf(c, "BT %s /F%d %s Tf %s %s Td (%s) Tj ET\n", rgb(color), s.font.index(), num(s.size), num(pos), num(baseline+s.rise), escape(f.text))
+	}
+	if s.underline {
+		fmt.Fprintf(c, "%s %s %s %s %s re f\n", rgb(color), num(pos), num(baseline+s.rise-s.size*0.12), num(width), num(s.size*0.05))
+	}
+	if s.strike {
+		fmt.Fprintf(c, "%s %s %s %s %s re f\n", rgb(color), num(pos), num(baseline+s.rise+s.size*0.28), num(width), num(s.size*0.05))
+	}
+	x.addLink(s.link, pos, baseline+s.rise-s.size*0.25, pos+width, baseline+s.rise+s.size*0.9)
+}
+
+// addLink adds a link annotation, growing the last one when it is the
+// same link, on the same line
+func (x *exporter) addLink(uri string, x0, y0, x1, y1 float64) {
+	if uri == "" {
+		return
+	}
+	links := x.page.links
+	if n := len(links); n > 0 {
+		last := &links[n-1]
+		if last.uri == uri && last.x1 >= x0-0.01 && last.x1 <= x0+0.01 && last.y0 < y1 && y0 < last.y1 {
+			last.x1 = x1
+			if y0 < last.y0 {
+				last.y0 = y0
+			}
+			if y1 > last.y1 {
+				last.y1 = y1
+			}
+			return
+		}
+	}
+	x.page.links = append(links, link{x0: x0, y0: y0, x1: x1, y1: y1, uri: uri})
+}
+
+// image returns the fragment of an image embed, no wider than maxWidth
+// points and no taller than a page. It reports false for images that are
+// written as their alt text
+func (x *exporter) image(src string, attrs delta.Attributes, s style, maxWidth float64) (fragment, bool) {
+	img, err := x.images.ResolveImage(src)
+	if err != nil {
+		if x.err == nil {
+			x.err = err
+		}
+		return fragment{}, false
+	}
+	if img == nil {
+		return fragment{}, false
+	}
+	n, ok := x.imageIndex[src]
+	if !ok {
+		decoded, _, err := image.Decode(bytes.NewReader(img.Data))
+		if err != nil {
+			return fragment{}, false
+		}
+		x.pictures = append(x.pictures, newPicture(decoded))
+		n = len(x.pictures)
+		x.imageIndex[src] = n
+	}
+	w, h := img.DisplaySize(attrs, int(maxWidth/pointsPerPixel))
+	width, height := float64(w)*pointsPerPixel, float64(h)*pointsPerPixel
+	if maxHeight := x.pageHeight - 2*x.margin - 2; height > maxHeight {
+		width, height = width*maxHeight/height, maxHeight
+	}
+	return fragment{style: s, width: width, height: height, picture: n}, true
+}
+
+// num formats a length for the content of a page
+func num(f float64) string {
+	s := strconv.FormatFloat(f, 'f', 2, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+// rgb returns the operator that sets the fill color to a "#rrggbb" color
+func rgb(color string) string {
+	var r, g, b int
+	fmt.Sscanf(color, "#%02x%02x%02x", &r, &g, &b)
+	return fmt.Sprintf("%s %s %s rg", num(float64(r)/255), num(float64(g)/255), num(float64(b)/255))
+}
+
+// escape returns s as the content of a PDF literal string
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`, "\r", `\r`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,198 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+	"github.com/fmpwizard/go-quilljs-delta/model"
+)
+
+func testPNG(w, h int) []byte {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h)))
+	return buf.Bytes()
+}
+
+// render exports doc and checks the cross reference table of the file
+// points at its objects
+func render(t *testing.T, e *Exporter, doc *delta.Delta) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := e.Export(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	file := buf.String()
+	if !strings.HasPrefix(file, "%PDF-1.4\n") || !strings.HasSuffix(file, "%%EOF\n") {
+		t.Fatalf("not a pdf file")
+	}
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindStringSubmatch(file)
+	if m == nil {
+		t.Fatalf("missing startxref")
+	}
+	xref, _ := strconv.Atoi(m[1])
+	if !strings.HasPrefix(file[xref:], "xref\n") {
+		t.Fatalf("startxref doesn't point at the cross reference table")
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllStringSubmatch(file[xref:], -1)
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(entry[1])
+		if !strings.HasPrefix(file[offset:], strconv.Itoa(i+1)+" 0 obj\n") {
+			t.Errorf("the offset of object %d is wrong", i+1)
+		}
+	}
+	if !strings.Contains(file, "/Size "+strconv.Itoa(len(entries)+1)+" ") {
+		t.Errorf("wrong trailer size for %d objects", len(entries))
+	}
+	return file
+}
+
+func TestExport(t *testing.T) {
+	doc := delta.New(nil).
+		Insert("Title", nil).Insert("\n", delta.Attributes{"header": 1}).
+		Insert("Some ", nil).Insert("bold", delta.Attributes{"bold": true}).
+		Insert(" and (", nil).Insert("italic", delta.Attributes{"italic": true, "color": "#ff0000"}).
+		Insert(") ", nil).Insert("a link", delta.Attributes{"link": "https://example.com/"}).
+		Insert(" café", delta.Attributes{"strike": true}).
+		Insert("\n", delta.Attributes{"align": "center"}).
+		Insert("one", nil).Insert("\n", delta.Attributes{"list": "ordered"}).
+		Insert("nested", nil).Insert("\n", delta.Attributes{"list": "bullet", "indent": 1}).
+		Insert("two", nil).Insert("\n", delta.Attributes{"list": "ordered"}).
+		Insert("done", nil).Insert("\n", delta.Attributes{"list": "checked"}).
+		Insert("quoted", nil).Insert("\n", delta.Attributes{"blockquote": true}).
+		Insert("x := f(1)", nil).Insert("\n", delta.Attributes{"code-block": true}).
+		Insert("\n", nil)
+
+	file := render(t, NewExporter(), doc)
+	for _, s := range []string{
+		"/Type /Pages /Kids [7 0 R] /Count 1",
+		"/BaseFont /Helvetica /Encoding /WinAnsiEncoding",
+		"/BaseFont /Helvetica-Bold ",
+		"/BaseFont /Helvetica-Oblique ",
+		"/BaseFont /Courier ",
+		"/F2 22 Tf 72 699.1 Td (Title) Tj",
+		"1 0 0 rg /F3 11 Tf",
+		"(italic) Tj",
+		`(\() Tj`,
+		"(caf\xe9) Tj",
+		"/F1 11 Tf 80.83 661.7 Td (1.) Tj", // the number of the first item
+		"(1.) Tj", "(2.) Tj", "(\x95) Tj",
+		"/F5 9.9 Tf 72 ", // code at the margin
+		"(f\\(1\\)) Tj",
+		"/Subtype /Link /Rect [",
+		"/A << /S /URI /URI (https://example.com/) >>",
+		" re S\n1.2 w ", // the check mark of the checked item
+	} {
+		if !strings.Contains(file, s) {
+			t.Errorf("missing %q\n%s", s, file)
+		}
+	}
+	// the words of a link get a single annotation
+	if strings.Count(file, "/Subtype /Link") != 1 {
+		t.Errorf("expected one link annotation\n%s", file)
+	}
+}
+
+func TestExportWrapping(t *testing.T) {
+	e := &Exporter{PageWidth: 200, PageHeight: 200, Margin: 20, FontSize: 10}
+	long := strings.Repeat("word ", 60) + strings.Repeat("x", 100)
+	doc := delta.New(nil).Insert(long, nil).Insert("\n", nil)
+	file := render(t, e, doc)
+	lines := regexp.MustCompile(`Td \((word|x+)\) Tj`).FindAllString(file, -1)
+	if len(lines) != 60+4 {
+		t.Errorf("expected the long word broken in 4, got %d fragments", len(lines))
+	}
+	regular := font{family: helvetica}
+	for _, m := range regexp.MustCompile(`Tf ([\d.]+) [\d.]+ Td \((x+)\)`).FindAllStringSubmatch(file, -1) {
+		pos, _ := strconv.ParseFloat(m[1], 64)
+		if pos+regular.width(m[2], 10) > 180.01 {
+			t.Errorf("%s goes past the margin", m[2])
+		}
+	}
+	// 64 fragments on lines of 160 points: the text goes on several pages
+	if !strings.Contains(file, "/Count 2") && !strings.Contains(file, "/Count 3") {
+		t.Errorf("expected page breaks\n%s", file)
+	}
+}
+
+func TestExportHeadingKeptWithText(t *testing.T) {
+	e := &Exporter{PageWidth: 300, PageHeight: 200, Margin: 20, FontSize: 10}
+	doc := delta.New(nil)
+	for i := 0; i < 11; i++ {
+		doc.Insert("line\n", nil)
+	}
+	doc.Insert("Heading", nil).Insert("\n", delta.Attributes{"header": 2}).Insert("text\n", nil)
+	file := render(t, e, doc)
+	pages := strings.Split(file, "/Type /Page ")
+	if len(pages) != 3 {
+		t.Fatalf("expected 2 pages, got %d", len(pages)-1)
+	}
+	if !strings.Contains(file, "/F2 15 Tf 20 165.75 Td (Heading) Tj") {
+		t.Errorf("the heading should start the second page\n%s", file)
+	}
+}
+
+func TestExportImages(t *testing.T) {
+	img := "data:image/png;base64," + base64.StdEncoding.EncodeToString(testPNG(40, 20))
+	doc := delta.New(nil).
+		InsertEmbed(delta.Embed{Key: "image", Value: img}, nil).
+		InsertEmbed(delta.Embed{Key: "image", Value: img}, delta.Attributes{"width": "20"}).
+		InsertEmbed(delta.Embed{Key: "image", Value: "https://example.com/a.png"}, delta.Attributes{"alt": "remote"}).
+		Insert("\n", nil)
+
+	file := render(t, NewExporter(), doc)
+	if strings.Count(file, "/Subtype /Image /Width 40 /Height 20") != 1 {
+		t.Errorf("expected the image once\n%s", file)
+	}
+	for _, s := range []string{"q 30 0 0 15 72 ", "q 15 0 0 7.5 102 ", "/Im1 Do", "(remote) Tj", "/XObject << /Im1 "} {
+		if !strings.Contains(file, s) {
+			t.Errorf("missing %q\n%s", s, file)
+		}
+	}
+
+	fail := errors.New("fail")
+	e := &Exporter{Images: model.ImageResolverFunc(func(src string) (*model.Image, error) {
+		return nil, fail
+	})}
+	if err := e.Export(ioutil.Discard, doc); err != fail {
+		t.Errorf("expected the resolver error, got %v", err)
+	}
+	if err := Export(ioutil.Discard, delta.New(nil).Delete(1)); err != model.ErrNotDocument {
+		t.Errorf("expected ErrNotDocument, got %v", err)
+	}
+	e = &Exporter{PageWidth: 100, PageHeight: 100, Margin: 40}
+	if err := e.Export(ioutil.Discard, delta.New(nil).Insert("\n", nil)); err != ErrPageTooSmall {
+		t.Errorf("expected ErrPageTooSmall, got %v", err)
+	}
+}
+
+func TestExportUnsafeLinks(t *testing.T) {
+	doc := delta.New(nil).
+		Insert("click", delta.Attributes{"link": "javascript:alert(1)"}).
+		Insert(" here", delta.Attributes{"link": " JaVa\tscript:alert(1)"}).
+		Insert("\n", nil).
+		InsertEmbed(delta.Embed{Key: "video", Value: "javascript:alert(1)"}, nil).
+		Insert("\n", nil)
+	file := render(t, NewExporter(), doc)
+	if strings.Contains(file, "/URI") || !strings.Contains(file, "(click) Tj") {
+		t.Errorf("expected unsafe links as plain text\n%s", file)
+	}
+}
+
+func TestExportTransparentColors(t *testing.T) {
+	doc := delta.New(nil).
+		Insert("clear", delta.Attributes{"color": "transparent", "background": "transparent"}).
+		Insert("\n", nil)
+	file := render(t, NewExporter(), doc)
+	if strings.Contains(file, " re f") || !strings.Contains(file, "(clear) Tj") {
+		t.Errorf("expected transparent colors to be left out\n%s", file)
+	}
+}
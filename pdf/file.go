@@ -0,0 +1,115 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"sort"
+	"strings"
+)
+
+// picture is an image as an image XObject: 8 bit RGB samples, compressed
+type picture struct {
+	width, height int
+	data          []byte
+}
+
+// newPicture converts img, set on a white background when it has
+// transparency
+func newPicture(img image.Image) *picture {
+	b := img.Bounds()
+	var buf bytes.Buffer
+	z := zlib.NewWriter(&buf)
+	row := make([]byte, 0, b.Dx()*3)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row = row[:0]
+		for x := b.Min.X; x < b.Max.X; x++ {
+			// the colors are premultiplied, adding the missing alpha blends
+			// them with white
+			r, g, bl, a := img.At(x, y).RGBA()
+			row = append(row, byte((r+0xffff-a)>>8), byte((g+0xffff-a)>>8), byte((bl+0xffff-a)>>8))
+		}
+		z.Write(row)
+	}
+	z.Close()
+	return &picture{width: b.Dx(), height: b.Dy(), data: buf.Bytes()}
+}
+
+// file returns the PDF file of the pages. Objects are numbered the catalog
+// first, then the page tree, the fonts, the images, and each page followed
+// by its content
+func (x *exporter) file() []byte {
+	var used []font
+	for f := range x.fonts {
+		used = append(used, f)
+	}
+	sort.Slice(used, func(i, j int) bool { return used[i].index() < used[j].index() })
+	firstFont := 3
+	firstPicture := firstFont + len(used)
+	firstPage := firstPicture + len(x.pictures)
+
+	var resources bytes.Buffer
+	resources.WriteString("<< /Font <<")
+	for i, f := range used {
+		fmt.Fprintf(&resources, " /F%d %d 0 R", f.index(), firstFont+i)
+	}
+	resources.WriteString(" >>")
+	if len(x.pictures) > 0 {
+		resources.WriteString(" /XObject <<")
+		for i := range x.pictures {
+			fmt.Fprintf(&resources, " /Im%d %d 0 R", i+1, firstPicture+i)
+		}
+		resources.WriteString(" >>")
+	}
+	resources.WriteString(" >>")
+
+	var b bytes.Buffer
+	var offsets []int
+	object := func(format string, args ...interface{}) {
+		offsets = append(offsets, b.Len())
+		fmt.Fprintf(&b, "%d 0 obj\n", len(offsets))
+		fmt.Fprintf(&b, format, args...)
+		b.WriteString("\nendobj\n")
+	}
+	stream := func(dict string, data []byte) {
+		object("<< %s/Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+	}
+
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	var kids []string
+	for i := range x.pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", firstPage+2*i))
+	}
+	object("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(x.pages))
+	for _, f := range used {
+		object("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", f.name())
+	}
+	for _, p := range x.pictures {
+		stream(fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB "+
+			"/BitsPerComponent 8 /Filter /FlateDecode ", p.width, p.height), p.data)
+	}
+	for i, p := range x.pages {
+		var annots string
+		if len(p.links) > 0 {
+			var l []string
+			for _, a := range p.links {
+				l = append(l, fmt.Sprintf("<< /Type /Annot /Subtype /Link /Rect [%s %s %s %s] /Border [0 0 0] /A << /S /URI /URI (%s) >> >>",
+					num(a.x0), num(a.y0), num(a.x1), num(a.y1), escape(a.uri)))
+			}
+			annots = " /Annots [" + strings.Join(l, " ") + "]"
+		}
+		object("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources %s /Contents %d 0 R%s >>",
+			num(x.pageWidth), num(x.pageHeight), resources.String(), firstPage+2*i+1, annots)
+		stream("", p.content.Bytes())
+	}
+
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, o := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", o)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return b.Bytes()
+}
@@ -0,0 +1,132 @@
+package pdf
+
+// The renderer only uses standard fonts, the base-14 fonts every PDF reader
+// has, so no font is embedded. Text is written in WinAnsiEncoding and
+// measured with the widths of the fonts' Adobe font metrics.
+
+// family is a typeface of the standard fonts
+type family int
+
+const (
+	helvetica family = iota
+	courier
+)
+
+// font is one of the fonts the renderer uses, a family and its variant
+type font struct {
+	family       family
+	bold, italic bool
+}
+
+// index returns the number of f among the fonts of a document, as in its
+// resource name /F1 to /F8
+func (f font) index() int {
+	i := int(f.family)*4 + 1
+	if f.bold {
+		i++
+	}
+	if f.italic {
+		i += 2
+	}
+	return i
+}
+
+// name returns the PostScript name of f
+func (f font) name() string {
+	name := "Helvetica"
+	if f.family == courier {
+		name = "Courier"
+	}
+	switch {
+	case f.bold && f.italic:
+		return name + "-BoldOblique"
+	case f.bold:
+		return name + "-Bold"
+	case f.italic:
+		return name + "-Oblique"
+	}
+	return name
+}
+
+// helveticaWidths and helveticaBoldWidths are the widths of the printable
+// ASCII characters, from space to ~, in thousandths of the font size. The
+// oblique variants have the same widths
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBoldWidths = [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
+
+// courierWidth is the width of every character of the courier fonts
+const courierWidth = 600
+
+// winAnsi maps the runes WinAnsiEncoding has outside of ASCII and Latin-1
+// to their code
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8a, '‹': 0x8b, 'Œ': 0x8c, 'Ž': 0x8e, '‘': 0x91,
+	'’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98,
+	'™': 0x99, 'š': 0x9a, '›': 0x9b, 'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+}
+
+// lookalikes are ASCII characters about as wide as the characters of
+// WinAnsiEncoding from 0x80 to 0xff, the widths of those are taken from
+// them. The metrics of the letters with accents are very close to the ones
+// without
+const lookalikes = "0?,0\"W00`WS(W?Z??,,(((0W`Ws)m?zY" +
+	" !0000|0\"@a<+-@-o+--`u0.`-o>%%%?" +
+	"AAAAAAWCEEEEIIIIDNOOOOO+OUUUUYPB" +
+	"aaaaaamceeeeiiiionooooo+ouuuuypy"
+
+// encode returns s in WinAnsiEncoding, runes it doesn't have become ?
+func encode(s string) string {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r == '\t':
+			b = append(b, ' ')
+		case r >= 0x20 && r < 0x7f, r >= 0xa0 && r <= 0xff:
+			b = append(b, byte(r))
+		case winAnsi[r] != 0:
+			b = append(b, winAnsi[r])
+		default:
+			b = append(b, '?')
+		}
+	}
+	return string(b)
+}
+
+// width returns the width of s, text in WinAnsiEncoding, set in f at size
+func (f font) width(s string, size float64) float64 {
+	if f.family == courier {
+		return float64(len(s)*courierWidth) * size / 1000
+	}
+	widths := &helveticaWidths
+	if f.bold {
+		widths = &helveticaBoldWidths
+	}
+	total := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 0x80 {
+			c = lookalikes[c-0x80]
+		}
+		if c < 0x20 || c > 0x7e {
+			c = '?'
+		}
+		total += widths[c-0x20]
+	}
+	return float64(total) * size / 1000
+}
@@ -0,0 +1,51 @@
+package pdf
+
+import "testing"
+
+func TestEncode(t *testing.T) {
+	got := encode("café “quoted” – 😀\t€")
+	expected := "caf\xe9 \x93quoted\x94 \x96 ? \x80"
+	if got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestWidth(t *testing.T) {
+	if len(lookalikes) != 128 {
+		t.Fatalf("lookalikes must cover 0x80 to 0xff, it has %d characters", len(lookalikes))
+	}
+	regular := font{family: helvetica}
+	for _, test := range []struct {
+		font     font
+		text     string
+		expected float64
+	}{
+		{regular, "Hello", 22.78}, // 722+556+222+222+556 at 10pt
+		{font{family: helvetica, bold: true}, "Hello", 24.45},
+		{font{family: helvetica, italic: true}, "Hello", 22.78},
+		{font{family: courier}, "Hello", 30},
+		{regular, encode("é"), 5.56},
+	} {
+		got := test.font.width(test.text, 10)
+		if got < test.expected-0.001 || got > test.expected+0.001 {
+			t.Errorf("%s %q: expected %v, got %v", test.font.name(), test.text, test.expected, got)
+		}
+	}
+}
+
+func TestFontNames(t *testing.T) {
+	for _, test := range []struct {
+		font  font
+		name  string
+		index int
+	}{
+		{font{family: helvetica}, "Helvetica", 1},
+		{font{family: helvetica, bold: true}, "Helvetica-Bold", 2},
+		{font{family: helvetica, italic: true}, "Helvetica-Oblique", 3},
+		{font{family: courier, bold: true, italic: true}, "Courier-BoldOblique", 8},
+	} {
+		if test.font.name() != test.name || test.font.index() != test.index {
+			t.Errorf("expected %s /F%d, got %s /F%d", test.name, test.index, test.font.name(), test.font.index())
+		}
+	}
+}
@@ -0,0 +1,270 @@
+// Package presence keeps track of the collaborators connected to a document,
+// their cursor or selection, name and color, and broadcasts the changes to
+// any number of subscribers.
+//
+// Ranges are expressed in document indexes, the same way Quill does, and are
+// shifted through every change applied to the document using
+// delta.TransformPosition, so a cursor stays next to the text it was on.
+package presence
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+)
+
+// Range is a cursor (Length == 0) or a selection in the document
+type Range struct {
+	Index  int `json:"index"`
+	Length int `json:"length"`
+}
+
+// State is everything we know about one collaborator
+type State struct {
+	ClientID string    `json:"clientId"`
+	Name     string    `json:"name,omitempty"`
+	Color    string    `json:"color,omitempty"`
+	Range    *Range    `json:"range,omitempty"`
+	LastSeen time.Time `json:"-"`
+}
+
+// Diff describes what changed since the last notification. Updated holds the
+// full state of new or changed clients and Removed the ids of the clients that
+// left or expired
+type Diff struct {
+	Updated []State  `json:"updated,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// IsEmpty tells you if the diff has nothing worth sending
+func (d Diff) IsEmpty() bool {
+	return len(d.Updated) == 0 && len(d.Removed) == 0
+}
+
+// Subscriber gets notified every time the presence information changes. This
+// is the hook a transport uses to fan out the diffs to its connections.
+// PresenceChanged is called outside of the Manager's lock, but the calls are
+// not serialized, so implementations must be safe for concurrent use.
+type Subscriber interface {
+	PresenceChanged(diff Diff)
+}
+
+// SubscriberFunc lets you use a plain function as a Subscriber
+type SubscriberFunc func(diff Diff)
+
+// PresenceChanged calls f(diff)
+func (f SubscriberFunc) PresenceChanged(diff Diff) {
+	f(diff)
+}
+
+// Manager holds the presence state of all the clients of one document
+type Manager struct {
+	mu      sync.Mutex
+	clients map[string]*State
+	subs    map[int]Subscriber
+	nextSub int
+	ttl     time.Duration
+
+	// Now returns the current time, it defaults to time.Now and it is only
+	// here so tests can control the clock
+	Now func() time.Time
+}
+
+// NewManager creates a Manager that expires clients after ttl without
+// updates. A ttl <= 0 means clients never expire
+func NewManager(ttl time.Duration) *Manager {
+	return &Manager{
+		clients: make(map[string]*State),
+		subs:    make(map[int]Subscriber),
+		ttl:     ttl,
+		Now:     time.Now,
+	}
+}
+
+// Subscribe registers s to receive every Diff, and returns a function that
+// removes the subscription
+func (m *Manager) Subscribe(s Subscriber) func() {
+	m.mu.Lock()
+	id := m.nextSub
+	m.nextSub++
+	m.subs[id] = s
+	m.mu.Unlock()
+	return func() {
+		m.mu.Lock()
+		delete(m.subs, id)
+		m.mu.Unlock()
+	}
+}
+
+// Update sets the state of the client s.ClientID, marking it as seen now.
+// Subscribers are only notified if something other than LastSeen changed
+func (m *Manager) Update(s State) {
+	m.mu.Lock()
+	s.LastSeen = m.Now()
+	if s.Range != nil {
+		r := *s.Range
+		s.Range = &r
+	}
+	old, found := m.clients[s.ClientID]
+	m.clients[s.ClientID] = &s
+	var diff Diff
+	if !found || !sameState(*old, s) {
+		diff.Updated = append(diff.Updated, s.clone())
+	}
+	m.mu.Unlock()
+	m.notify(diff)
+}
+
+// Touch marks the client as active without changing its state. It returns
+// false if the client is unknown
+func (m *Manager) Touch(clientID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, found := m.clients[clientID]
+	if found {
+		s.LastSeen = m.Now()
+	}
+	return found
+}
+
+// Remove forgets about the client, usually because it disconnected
+func (m *Manager) Remove(clientID string) {
+	m.mu.Lock()
+	var diff Diff
+	if _, found := m.clients[clientID]; found {
+		delete(m.clients, clientID)
+		diff.Removed = append(diff.Removed, clientID)
+	}
+	m.mu.Unlock()
+	m.notify(diff)
+}
+
+// Apply shifts the ranges of all the clients through change, which is a
+// change delta that was just applied to the document by author.
+// Like Quill does, the author's own cursor is pushed after its inserts, while
+// everybody else's cursor keeps its place when the insert happens at the
+// same index
+func (m *Manager) Apply(change *delta.Delta, author string) {
+	m.mu.Lock()
+	var diff Diff
+	for _, id := range m.sortedIDs() {
+		s := m.clients[id]
+		if s.Range == nil {
+			continue
+		}
+		r := TransformRange(change, *s.Range, id != author)
+		if r != *s.Range {
+			s.Range = &r
+			diff.Updated = append(diff.Updated, s.clone())
+		}
+	}
+	m.mu.Unlock()
+	m.notify(diff)
+}
+
+// Expire removes every client that has not been seen for longer than the ttl
+// and returns their ids
+func (m *Manager) Expire() []string {
+	if m.ttl <= 0 {
+		return nil
+	}
+	m.mu.Lock()
+	var diff Diff
+	now := m.Now()
+	for _, id := range m.sortedIDs() {
+		if now.Sub(m.clients[id].LastSeen) > m.ttl {
+			delete(m.clients, id)
+			diff.Removed = append(diff.Removed, id)
+		}
+	}
+	m.mu.Unlock()
+	m.notify(diff)
+	return diff.Removed
+}
+
+// Get returns the state of one client
+func (m *Manager) Get(clientID string) (State, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, found := m.clients[clientID]
+	if !found {
+		return State{}, false
+	}
+	return s.clone(), true
+}
+
+// Snapshot returns the state of all the clients, sorted by id. This is what a
+// transport sends to a client that just connected
+func (m *Manager) Snapshot() []State {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ret := make([]State, 0, len(m.clients))
+	for _, id := range m.sortedIDs() {
+		ret = append(ret, m.clients[id].clone())
+	}
+	return ret
+}
+
+// TransformRange returns r after applying change to the document. Both ends
+// of the range are moved with delta.TransformPosition, so the selection grows
+// with inserts inside it and shrinks with deletes
+func TransformRange(change *delta.Delta, r Range, priority bool) Range {
+	start := change.TransformPosition(r.Index, priority)
+	if r.Length == 0 {
+		return Range{Index: start}
+	}
+	end := change.TransformPosition(r.Index+r.Length, priority)
+	if end < start {
+		end = start
+	}
+	return Range{Index: start, Length: end - start}
+}
+
+func (m *Manager) sortedIDs() []string {
+	ids := make([]string, 0, len(m.clients))
+	for id := range m.clients {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (m *Manager) notify(diff Diff) {
+	if diff.IsEmpty() {
+		return
+	}
+	m.mu.Lock()
+	keys := make([]int, 0, len(m.subs))
+	for k := range m.subs {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	subs := make([]Subscriber, 0, len(keys))
+	for _, k := range keys {
+		subs = append(subs, m.subs[k])
+	}
+	m.mu.Unlock()
+	for _, s := range subs {
+		s.PresenceChanged(diff)
+	}
+}
+
+func (s State) clone() State {
+	if s.Range != nil {
+		r := *s.Range
+		s.Range = &r
+	}
+	return s
+}
+
+func sameState(a, b State) bool {
+	if a.Name != b.Name || a.Color != b.Color {
+		return false
+	}
+	if a.Range == nil || b.Range == nil {
+		return a.Range == nil && b.Range == nil
+	}
+	return *a.Range == *b.Range
+}
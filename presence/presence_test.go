@@ -0,0 +1,133 @@
+package presence
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+)
+
+type recorder struct {
+	diffs []Diff
+}
+
+func (r *recorder) PresenceChanged(diff Diff) {
+	r.diffs = append(r.diffs, diff)
+}
+
+func TestUpdateNotifies(t *testing.T) {
+	m := NewManager(0)
+	rec := &recorder{}
+	m.Subscribe(rec)
+	m.Update(State{ClientID: "a", Name: "Diego", Color: "red", Range: &Range{Index: 3}})
+	if len(rec.diffs) != 1 || len(rec.diffs[0].Updated) != 1 {
+		t.Fatalf("expected one diff with one update, got: %+v\n", rec.diffs)
+	}
+	if u := rec.diffs[0].Updated[0]; u.ClientID != "a" || u.Range.Index != 3 {
+		t.Errorf("unexpected update %+v\n", u)
+	}
+	// same state again, nothing to broadcast
+	m.Update(State{ClientID: "a", Name: "Diego", Color: "red", Range: &Range{Index: 3}})
+	if len(rec.diffs) != 1 {
+		t.Errorf("expected no new diff, got: %+v\n", rec.diffs)
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	m := NewManager(0)
+	rec := &recorder{}
+	cancel := m.Subscribe(rec)
+	cancel()
+	m.Update(State{ClientID: "a"})
+	if len(rec.diffs) != 0 {
+		t.Errorf("expected no diffs after unsubscribing, got: %+v\n", rec.diffs)
+	}
+}
+
+func TestApplyShiftsRanges(t *testing.T) {
+	m := NewManager(0)
+	m.Update(State{ClientID: "author", Range: &Range{Index: 2}})
+	m.Update(State{ClientID: "other", Range: &Range{Index: 2}})
+	m.Update(State{ClientID: "selection", Range: &Range{Index: 1, Length: 3}})
+	m.Update(State{ClientID: "before", Range: &Range{Index: 1}})
+	m.Update(State{ClientID: "noRange"})
+	rec := &recorder{}
+	m.Subscribe(rec)
+
+	change := delta.New(nil).Retain(2, nil).Insert("abc", nil)
+	m.Apply(change, "author")
+
+	expected := map[string]Range{
+		"author":    {Index: 5},
+		"other":     {Index: 2},
+		"selection": {Index: 1, Length: 6},
+		"before":    {Index: 1},
+	}
+	for id, r := range expected {
+		s, _ := m.Get(id)
+		if *s.Range != r {
+			t.Errorf("%s expected range %+v but got %+v\n", id, r, *s.Range)
+		}
+	}
+	if len(rec.diffs) != 1 || len(rec.diffs[0].Updated) != 2 {
+		t.Errorf("expected author and selection updates, got: %+v\n", rec.diffs)
+	}
+}
+
+func TestApplyDelete(t *testing.T) {
+	m := NewManager(0)
+	m.Update(State{ClientID: "a", Range: &Range{Index: 4, Length: 4}})
+	change := delta.New(nil).Retain(2, nil).Delete(4)
+	m.Apply(change, "b")
+	s, _ := m.Get("a")
+	if *s.Range != (Range{Index: 2, Length: 2}) {
+		t.Errorf("unexpected range after delete %+v\n", *s.Range)
+	}
+}
+
+func TestExpire(t *testing.T) {
+	now := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+	m := NewManager(time.Minute)
+	m.Now = func() time.Time { return now }
+	m.Update(State{ClientID: "a"})
+	m.Update(State{ClientID: "b"})
+	rec := &recorder{}
+	m.Subscribe(rec)
+
+	now = now.Add(45 * time.Second)
+	m.Touch("b")
+	now = now.Add(30 * time.Second)
+	removed := m.Expire()
+	if !reflect.DeepEqual(removed, []string{"a"}) {
+		t.Errorf("expected a to expire, got: %+v\n", removed)
+	}
+	if len(rec.diffs) != 1 || !reflect.DeepEqual(rec.diffs[0].Removed, []string{"a"}) {
+		t.Errorf("expected a removal diff, got: %+v\n", rec.diffs)
+	}
+	if snap := m.Snapshot(); len(snap) != 1 || snap[0].ClientID != "b" {
+		t.Errorf("expected only b in the snapshot, got: %+v\n", snap)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	m := NewManager(0)
+	m.Update(State{ClientID: "a"})
+	rec := &recorder{}
+	m.Subscribe(SubscriberFunc(rec.PresenceChanged))
+	m.Remove("a")
+	m.Remove("a")
+	if len(rec.diffs) != 1 || rec.diffs[0].Removed[0] != "a" {
+		t.Errorf("expected exactly one removal, got: %+v\n", rec.diffs)
+	}
+}
+
+func TestSnapshotIsACopy(t *testing.T) {
+	m := NewManager(0)
+	m.Update(State{ClientID: "a", Range: &Range{Index: 1}})
+	snap := m.Snapshot()
+	snap[0].Range.Index = 10
+	if s, _ := m.Get("a"); s.Range.Index != 1 {
+		t.Errorf("snapshot shares memory with the manager, got: %+v\n", s.Range)
+	}
+}
@@ -0,0 +1,352 @@
+package prosemirror
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+	"github.com/fmpwizard/go-quilljs-delta/model"
+)
+
+// formatOrder is the order marks are written in, the one of the basic
+// schema's marks
+var formatOrder = []string{"link", "italic", "bold", "code", "underline", "strike"}
+
+// imageAttributes are the formats of image embeds kept as image attributes
+var imageAttributes = []string{"alt", "width", "height"}
+
+// Converter converts documents with the node and mark names of Schema.
+// Formats a ProseMirror schema has no place for, like colors or alignment,
+// are left out, and so are embeds other than images
+type Converter struct {
+	Schema *Schema
+}
+
+// NewConverter returns a Converter for DefaultSchema
+func NewConverter() *Converter {
+	return &Converter{Schema: DefaultSchema()}
+}
+
+// ToDelta converts doc with NewConverter()
+func ToDelta(doc *Node) (*delta.Delta, error) {
+	return NewConverter().ToDelta(doc)
+}
+
+// FromDelta converts d with NewConverter()
+func FromDelta(d *delta.Delta) (*Node, error) {
+	return NewConverter().FromDelta(d)
+}
+
+func (c *Converter) schema() *Schema {
+	if c.Schema == nil {
+		return DefaultSchema()
+	}
+	return c.Schema
+}
+
+// ToDelta returns the delta of doc. Nested lists become indented list
+// lines, and hard breaks end the line, the text after them gets a line of
+// its own with the same formats. The content of nodes missing from the
+// schema is kept
+func (c *Converter) ToDelta(doc *Node) (*delta.Delta, error) {
+	s := c.schema()
+	if doc == nil || doc.Type != s.Doc {
+		return nil, ErrNotDoc
+	}
+	im := &importer{Schema: s, d: delta.New(nil)}
+	im.blocks(doc.Content, nil, 0)
+	if len(im.d.Ops) == 0 {
+		im.d.Insert("\n", nil)
+	}
+	return im.d, nil
+}
+
+// importer holds the state of one ToDelta call
+type importer struct {
+	*Schema
+	d *delta.Delta
+}
+
+// blocks adds the lines of nodes, with the block formats of attrs. depth is
+// how deep lists are nested
+func (im *importer) blocks(nodes []*Node, attrs delta.Attributes, depth int) {
+	for _, n := range nodes {
+		if n != nil {
+			im.block(n, attrs, depth)
+		}
+	}
+}
+
+func (im *importer) block(n *Node, attrs delta.Attributes, depth int) {
+	switch n.Type {
+	case "":
+	case im.Paragraph:
+		im.line(n.Content, attrs)
+	case im.Heading:
+		level, _ := delta.Attributes(n.Attrs).Int("level")
+		if level < 1 {
+			level = 1
+		}
+		im.line(n.Content, attrs.With("header", level))
+	case im.Blockquote:
+		im.blocks(n.Content, delta.Attributes{"blockquote": true}, depth)
+	case im.CodeBlock:
+		var format interface{} = true
+		if lang, _ := n.Attrs["language"].(string); lang != "" {
+			format = lang
+		}
+		var text strings.Builder
+		for _, child := range n.Content {
+			if child != nil && child.Type == im.HardBreak {
+				text.WriteString("\n")
+			} else if child != nil {
+				text.WriteString(child.Text)
+			}
+		}
+		for _, line := range strings.Split(text.String(), "\n") {
+			im.d.Insert(line, nil)
+			im.d.Insert("\n", delta.Attributes{"code-block": format})
+		}
+	case im.BulletList, im.OrderedList, im.TaskList:
+		for _, item := range n.Content {
+			if item != nil {
+				im.listItem(n.Type, item, depth)
+			}
+		}
+	case im.Text, im.Image, im.HardBreak:
+		// inline content outside of a textblock
+		im.line([]*Node{n}, attrs)
+	default:
+		im.blocks(n.Content, attrs, depth)
+	}
+}
+
+// listItem adds the lines of an item of a list of type listType
+func (im *importer) listItem(listType string, item *Node, depth int) {
+	attrs := delta.Attributes{"list": "bullet"}
+	switch {
+	case listType == im.OrderedList:
+		attrs["list"] = "ordered"
+	case listType == im.TaskList:
+		attrs["list"] = "unchecked"
+		if checked, _ := delta.Attributes(item.Attrs).Bool("checked"); checked {
+			attrs["list"] = "checked"
+		}
+	}
+	if depth > 0 {
+		attrs["indent"] = depth
+	}
+	if len(item.Content) == 0 {
+		im.d.Insert("\n", attrs)
+	}
+	for _, child := range item.Content {
+		if child == nil {
+			continue
+		}
+		switch child.Type {
+		case im.BulletList, im.OrderedList, im.TaskList:
+			im.block(child, nil, depth+1)
+		default:
+			im.block(child, attrs, depth)
+		}
+	}
+}
+
+// line adds the inline content of a textblock and the newline that ends it
+func (im *importer) line(nodes []*Node, attrs delta.Attributes) {
+	im.inline(nodes, attrs)
+	im.d.Insert("\n", attrs)
+}
+
+func (im *importer) inline(nodes []*Node, attrs delta.Attributes) {
+	for _, n := range nodes {
+		if n == nil {
+			continue
+		}
+		switch n.Type {
+		case im.Text:
+			im.d.Insert(n.Text, im.formats(n.Marks))
+		case im.Image:
+			src, _ := n.Attrs["src"].(string)
+			if src == "" {
+				continue
+			}
+			formats := im.formats(n.Marks)
+			for _, name := range imageAttributes {
+				switch v := n.Attrs[name].(type) {
+				case string:
+					if v != "" {
+						formats = formats.With(name, v)
+					}
+				case float64:
+					formats = formats.With(name, strconv.FormatFloat(v, 'f', -1, 64))
+				case int:
+					formats = formats.With(name, strconv.Itoa(v))
+				}
+			}
+			im.d.InsertEmbed(delta.Embed{Key: "image", Value: src}, formats)
+		case im.HardBreak:
+			im.d.Insert("\n", attrs)
+		default:
+			im.inline(n.Content, attrs)
+		}
+	}
+}
+
+// formats returns the formats of marks, marks missing from the schema are
+// left out
+func (im *importer) formats(marks []*Mark) delta.Attributes {
+	var attrs delta.Attributes
+	for _, m := range marks {
+		if m == nil {
+			continue
+		}
+		switch format := im.Marks[m.Type]; format {
+		case "":
+		case "link":
+			if href, _ := m.Attrs["href"].(string); href != "" {
+				attrs = attrs.With(format, href)
+			}
+		default:
+			attrs = attrs.With(format, true)
+		}
+	}
+	return attrs
+}
+
+// FromDelta returns the ProseMirror document of d, an insert only delta.
+// Check lists become bullet lists with schemas that have no task lists
+func (c *Converter) FromDelta(d *delta.Delta) (*Node, error) {
+	parsed, err := model.Parse(d)
+	if err != nil {
+		return nil, err
+	}
+	s := c.schema()
+	ex := &exporter{Schema: s, marks: make(map[string]string)}
+	// the first mark type, in order, of each format
+	types := make([]string, 0, len(s.Marks))
+	for t := range s.Marks {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		if _, ok := ex.marks[s.Marks[t]]; !ok {
+			ex.marks[s.Marks[t]] = t
+		}
+	}
+
+	doc := &Node{Type: s.Doc}
+	for _, b := range parsed.Blocks {
+		doc.Content = append(doc.Content, ex.block(b)...)
+	}
+	return doc, nil
+}
+
+// exporter holds the state of one FromDelta call
+type exporter struct {
+	*Schema
+	// marks maps formats to mark types
+	marks map[string]string
+}
+
+func (ex *exporter) block(b model.Block) []*Node {
+	switch b := b.(type) {
+	case *model.Paragraph:
+		return []*Node{ex.paragraph(b.Runs)}
+	case *model.Heading:
+		return []*Node{{Type: ex.Heading, Attrs: map[string]interface{}{"level": b.Level}, Content: ex.inline(b.Runs)}}
+	case *model.List:
+		return []*Node{ex.list(b)}
+	case *model.Blockquote:
+		quote := &Node{Type: ex.Blockquote}
+		for _, l := range b.Lines {
+			quote.Content = append(quote.Content, ex.paragraph(l.Runs))
+		}
+		return []*Node{quote}
+	case *model.CodeBlock:
+		code := &Node{Type: ex.CodeBlock}
+		if b.Language != "" {
+			code.Attrs = map[string]interface{}{"language": b.Language}
+		}
+		lines := make([]string, len(b.Lines))
+		for i, l := range b.Lines {
+			lines[i] = l.Text()
+		}
+		if text := strings.Join(lines, "\n"); text != "" {
+			code.Content = []*Node{{Type: ex.Text, Text: text}}
+		}
+		return []*Node{code}
+	case *model.EmbedBlock:
+		// block embeds, videos, are kept as a link to them
+		if src, _ := b.Embed.Value.(string); src != "" {
+			return []*Node{ex.paragraph([]model.Run{{Text: src, Attributes: delta.Attributes{"link": src}}})}
+		}
+	}
+	return nil
+}
+
+func (ex *exporter) paragraph(runs []model.Run) *Node {
+	return &Node{Type: ex.Paragraph, Content: ex.inline(runs)}
+}
+
+func (ex *exporter) list(l *model.List) *Node {
+	listType, itemType := ex.BulletList, ex.ListItem
+	switch {
+	case l.Type == "ordered":
+		listType = ex.OrderedList
+	case l.Type == "checked" && ex.TaskList != "":
+		listType, itemType = ex.TaskList, ex.TaskItem
+	}
+	list := &Node{Type: listType}
+	for _, item := range l.Items {
+		n := &Node{Type: itemType, Content: []*Node{ex.paragraph(item.Runs)}}
+		if listType == ex.TaskList {
+			n.Attrs = map[string]interface{}{"checked": item.Checked}
+		}
+		for _, child := range item.Children {
+			n.Content = append(n.Content, ex.list(child))
+		}
+		list.Content = append(list.Content, n)
+	}
+	return list
+}
+
+func (ex *exporter) inline(runs []model.Run) []*Node {
+	var nodes []*Node
+	for _, r := range runs {
+		switch {
+		case r.Embed == nil:
+			nodes = append(nodes, &Node{Type: ex.Text, Text: r.Text, Marks: ex.markList(r.Attributes)})
+		case r.Embed.Key == "image":
+			src, _ := r.Embed.Value.(string)
+			attrs := map[string]interface{}{"src": src}
+			for _, name := range imageAttributes {
+				if v, ok := r.Attributes.String(name); ok {
+					attrs[name] = v
+				}
+			}
+			nodes = append(nodes, &Node{Type: ex.Image, Attrs: attrs, Marks: ex.markList(r.Attributes)})
+		}
+	}
+	return nodes
+}
+
+// markList returns the marks of the formats of attrs
+func (ex *exporter) markList(attrs delta.Attributes) []*Mark {
+	var marks []*Mark
+	for _, format := range formatOrder {
+		t := ex.marks[format]
+		if t == "" {
+			continue
+		}
+		if format == "link" {
+			if href, _ := attrs.String("link"); href != "" {
+				marks = append(marks, &Mark{Type: t, Attrs: map[string]interface{}{"href": href}})
+			}
+		} else if v, _ := attrs.Bool(format); v {
+			marks = append(marks, &Mark{Type: t})
+		}
+	}
+	return marks
+}
@@ -0,0 +1,140 @@
+package prosemirror
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+)
+
+// roundTrip converts d to JSON and back with c
+func roundTrip(t *testing.T, c *Converter, d *delta.Delta) *delta.Delta {
+	t.Helper()
+	doc, err := c.FromDelta(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := FromJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.ToDelta(parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func testDoc() *delta.Delta {
+	return delta.New(nil).
+		Insert("Title", nil).Insert("\n", delta.Attributes{"header": 1}).
+		Insert("Some ", nil).Insert("bold", delta.Attributes{"bold": true}).
+		Insert(" ", nil).Insert("both", delta.Attributes{"bold": true, "italic": true}).
+		Insert(" ", nil).Insert("linked", delta.Attributes{"link": "https://example.com/", "underline": true}).
+		Insert(" ", nil).Insert("code", delta.Attributes{"code": true}).
+		Insert(" ", nil).Insert("gone", delta.Attributes{"strike": true}).
+		Insert("\n\n", nil).
+		Insert("one", nil).Insert("\n", delta.Attributes{"list": "ordered"}).
+		Insert("nested", nil).Insert("\n", delta.Attributes{"list": "bullet", "indent": 1}).
+		Insert("deeper", nil).Insert("\n", delta.Attributes{"list": "ordered", "indent": 2}).
+		Insert("two", nil).Insert("\n", delta.Attributes{"list": "ordered"}).
+		Insert("quoted", nil).Insert("\n", delta.Attributes{"blockquote": true}).
+		Insert("again", nil).Insert("\n", delta.Attributes{"blockquote": true}).
+		Insert("x := 1", nil).Insert("\n", delta.Attributes{"code-block": "go"}).
+		Insert("\n", delta.Attributes{"code-block": "go"}).
+		Insert("y", nil).Insert("\n", delta.Attributes{"code-block": true}).
+		InsertEmbed(delta.Embed{Key: "image", Value: "https://example.com/a.png"}, delta.Attributes{"alt": "a", "width": "20"}).
+		Insert("\n", nil)
+}
+
+func TestRoundTrip(t *testing.T) {
+	doc := testDoc()
+	if got := roundTrip(t, NewConverter(), doc); !reflect.DeepEqual(got, doc) {
+		t.Errorf("round trip doesn't match\ngot:      %v\nexpected: %v", got.Ops, doc.Ops)
+	}
+
+	tiptap := &Converter{Schema: TiptapSchema()}
+	doc = testDoc().
+		Insert("todo", nil).Insert("\n", delta.Attributes{"list": "unchecked"}).
+		Insert("done", nil).Insert("\n", delta.Attributes{"list": "checked", "indent": 1})
+	if got := roundTrip(t, tiptap, doc); !reflect.DeepEqual(got, doc) {
+		t.Errorf("round trip doesn't match\ngot:      %v\nexpected: %v", got.Ops, doc.Ops)
+	}
+}
+
+func TestFromDelta(t *testing.T) {
+	doc := delta.New(nil).
+		Insert("Hi ", delta.Attributes{"color": "#ff0000"}).
+		Insert("there", delta.Attributes{"bold": true, "link": "https://example.com/"}).
+		Insert("\n", delta.Attributes{"align": "center"}).
+		Insert("todo", nil).Insert("\n", delta.Attributes{"list": "checked"}).
+		Insert("x\ny", nil).Insert("\n", delta.Attributes{"code-block": true})
+
+	got, err := FromDelta(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(got)
+	expected := `{"type":"doc","content":[` +
+		`{"type":"paragraph","content":[{"type":"text","text":"Hi "},{"type":"text","text":"there",` +
+		`"marks":[{"type":"link","attrs":{"href":"https://example.com/"}},{"type":"strong"}]}]},` +
+		// no task lists in the basic schema
+		`{"type":"bullet_list","content":[{"type":"list_item","content":[{"type":"paragraph","content":[{"type":"text","text":"todo"}]}]}]},` +
+		`{"type":"paragraph","content":[{"type":"text","text":"x"}]},` +
+		`{"type":"code_block","content":[{"type":"text","text":"y"}]}]}`
+	if string(data) != expected {
+		t.Errorf("expected %s\ngot      %s", expected, data)
+	}
+}
+
+func TestToDelta(t *testing.T) {
+	doc, err := FromJSON([]byte(`{"type":"doc","content":[
+		{"type":"heading","attrs":{"level":2},"content":[{"type":"text","text":"Head"}]},
+		{"type":"paragraph","content":[
+			{"type":"text","text":"a","marks":[{"type":"em"},{"type":"highlight"}]},
+			{"type":"hard_break"},
+			{"type":"text","text":"b"},
+			{"type":"image","attrs":{"src":"a.png","alt":"pic","title":null,"width":30}}]},
+		{"type":"paragraph"},
+		{"type":"bullet_list","content":[{"type":"list_item","content":[
+			{"type":"paragraph","content":[{"type":"text","text":"item"}]},
+			{"type":"ordered_list","attrs":{"order":1},"content":[{"type":"list_item","content":[
+				{"type":"paragraph","content":[{"type":"text","text":"sub"}]}]}]}]}]},
+		{"type":"table","content":[{"type":"table_row","content":[{"type":"table_cell","content":[
+			{"type":"paragraph","content":[{"type":"text","text":"cell"}]}]}]}]},
+		{"type":"horizontal_rule"},
+		{"type":"code_block","attrs":{"language":null},"content":[{"type":"text","text":"a\nb"}]}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ToDelta(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := delta.New(nil).
+		Insert("Head", nil).Insert("\n", delta.Attributes{"header": 2}).
+		Insert("a", delta.Attributes{"italic": true}).Insert("\nb", nil).
+		InsertEmbed(delta.Embed{Key: "image", Value: "a.png"}, delta.Attributes{"alt": "pic", "width": "30"}).
+		Insert("\n\n", nil).
+		Insert("item", nil).Insert("\n", delta.Attributes{"list": "bullet"}).
+		Insert("sub", nil).Insert("\n", delta.Attributes{"list": "ordered", "indent": 1}).
+		Insert("cell\n", nil).
+		Insert("a", nil).Insert("\n", delta.Attributes{"code-block": true}).
+		Insert("b", nil).Insert("\n", delta.Attributes{"code-block": true})
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected delta\ngot:      %v\nexpected: %v", got.Ops, expected.Ops)
+	}
+
+	if _, err := ToDelta(&Node{Type: "paragraph"}); err != ErrNotDoc {
+		t.Errorf("expected ErrNotDoc, got %v", err)
+	}
+	if got, _ := ToDelta(&Node{Type: "doc"}); !reflect.DeepEqual(got, delta.New(nil).Insert("\n", nil)) {
+		t.Errorf("expected an empty document, got %v", got.Ops)
+	}
+}
@@ -0,0 +1,106 @@
+// Package prosemirror converts between Quill documents, insert only deltas,
+// and the JSON of ProseMirror documents, as editors built on ProseMirror,
+// like Tiptap, store them. The names of the node and mark types come from a
+// Schema, so documents of schemas that only rename the usual nodes and
+// marks convert as well.
+package prosemirror
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// ErrNotDoc is returned when converting a node that isn't the top node of
+// a document
+var ErrNotDoc = errors.New("prosemirror: node is not a doc")
+
+// Node is a node of a ProseMirror document, as in its JSON
+type Node struct {
+	Type    string                 `json:"type"`
+	Attrs   map[string]interface{} `json:"attrs,omitempty"`
+	Content []*Node                `json:"content,omitempty"`
+	Text    string                 `json:"text,omitempty"`
+	Marks   []*Mark                `json:"marks,omitempty"`
+}
+
+// Mark is a mark of a text node
+type Mark struct {
+	Type  string                 `json:"type"`
+	Attrs map[string]interface{} `json:"attrs,omitempty"`
+}
+
+// FromJSON parses the JSON of a document
+func FromJSON(in []byte) (*Node, error) {
+	var n Node
+	if err := json.Unmarshal(in, &n); err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// Schema names the node and mark types of the ProseMirror schema documents
+// use. Nodes with an empty name aren't part of the schema
+type Schema struct {
+	Doc, Text, Paragraph, Heading, Blockquote, CodeBlock, Image, HardBreak string
+	BulletList, OrderedList, ListItem                                      string
+	// TaskList and TaskItem are the nodes of check lists, when the schema
+	// has them. Items have a boolean checked attribute
+	TaskList, TaskItem string
+	// Marks maps mark types to the quill formats they stand for: bold,
+	// italic, code, link, underline or strike. The href attribute of link
+	// marks is the value of the link format
+	Marks map[string]string
+}
+
+// DefaultSchema returns the names of prosemirror-schema-basic and
+// prosemirror-schema-list, with underline and strike marks
+func DefaultSchema() *Schema {
+	return &Schema{
+		Doc:         "doc",
+		Text:        "text",
+		Paragraph:   "paragraph",
+		Heading:     "heading",
+		Blockquote:  "blockquote",
+		CodeBlock:   "code_block",
+		Image:       "image",
+		HardBreak:   "hard_break",
+		BulletList:  "bullet_list",
+		OrderedList: "ordered_list",
+		ListItem:    "list_item",
+		Marks: map[string]string{
+			"strong":    "bold",
+			"em":        "italic",
+			"code":      "code",
+			"link":      "link",
+			"underline": "underline",
+			"strike":    "strike",
+		},
+	}
+}
+
+// TiptapSchema returns the names of Tiptap's extensions, with check lists
+func TiptapSchema() *Schema {
+	return &Schema{
+		Doc:         "doc",
+		Text:        "text",
+		Paragraph:   "paragraph",
+		Heading:     "heading",
+		Blockquote:  "blockquote",
+		CodeBlock:   "codeBlock",
+		Image:       "image",
+		HardBreak:   "hardBreak",
+		BulletList:  "bulletList",
+		OrderedList: "orderedList",
+		ListItem:    "listItem",
+		TaskList:    "taskList",
+		TaskItem:    "taskItem",
+		Marks: map[string]string{
+			"bold":      "bold",
+			"italic":    "italic",
+			"code":      "code",
+			"link":      "link",
+			"underline": "underline",
+			"strike":    "strike",
+		},
+	}
+}
@@ -0,0 +1,33 @@
+package prosemirror
+
+import "testing"
+
+func TestFromJSON(t *testing.T) {
+	doc, err := FromJSON([]byte(`{"type":"doc","content":[{"type":"paragraph","content":[` +
+		`{"type":"text","text":"hi","marks":[{"type":"link","attrs":{"href":"https://example.com/"}}]}]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := doc.Content[0].Content[0]
+	if text.Text != "hi" || text.Marks[0].Attrs["href"] != "https://example.com/" {
+		t.Errorf("unexpected document %+v", text)
+	}
+	if _, err := FromJSON([]byte(`{"type":`)); err == nil {
+		t.Errorf("expected an error for invalid JSON")
+	}
+}
+
+func TestSchemas(t *testing.T) {
+	// every format has a mark in the schemas
+	for _, s := range []*Schema{DefaultSchema(), TiptapSchema()} {
+		formats := make(map[string]bool)
+		for _, format := range s.Marks {
+			formats[format] = true
+		}
+		for _, format := range formatOrder {
+			if !formats[format] {
+				t.Errorf("no mark for %s in the %s schema", format, s.CodeBlock)
+			}
+		}
+	}
+}
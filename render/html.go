@@ -0,0 +1,224 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+	"github.com/fmpwizard/go-quilljs-delta/model"
+)
+
+// htmlSizes are the CSS font sizes of quill's size format
+var htmlSizes = map[string]string{"small": "0.75em", "large": "1.5em", "huge": "2.5em"}
+
+// htmlFonts are the CSS font families of quill's font format
+var htmlFonts = map[string]string{"serif": "serif", "monospace": "monospace", "sans-serif": "sans-serif"}
+
+// htmlTags are the elements of the inline formats, outermost first
+var htmlTags = []struct{ format, tag string }{
+	{"bold", "strong"}, {"italic", "em"}, {"underline", "u"}, {"strike", "s"}, {"code", "code"},
+}
+
+// HTML writes doc as an HTML fragment, the semantic elements of each block,
+// with styles for alignment, colors, fonts and sizes
+func HTML(w io.Writer, doc *delta.Delta) error {
+	parsed, err := model.Parse(doc)
+	if err != nil {
+		return err
+	}
+	x := &htmlWriter{}
+	for _, b := range parsed.Blocks {
+		x.block(b)
+	}
+	_, err = w.Write(x.b.Bytes())
+	return err
+}
+
+// htmlWriter holds the state of one HTML call
+type htmlWriter struct {
+	b bytes.Buffer
+}
+
+func (x *htmlWriter) block(b model.Block) {
+	switch b := b.(type) {
+	case *model.Paragraph:
+		x.line("p", b.Line)
+	case *model.Heading:
+		level := b.Level
+		if level > 6 {
+			level = 6
+		}
+		x.line("h"+strconv.Itoa(level), b.Line)
+	case *model.List:
+		x.list(b)
+	case *model.Blockquote:
+		x.b.WriteString("<blockquote>\n")
+		for _, l := range b.Lines {
+			x.line("p", l)
+		}
+		x.b.WriteString("</blockquote>\n")
+	case *model.CodeBlock:
+		x.b.WriteString("<pre><code")
+		if b.Language != "" {
+			fmt.Fprintf(&x.b, ` class="language-%s"`, html.EscapeString(b.Language))
+		}
+		x.b.WriteString(">")
+		for i, l := range b.Lines {
+			if i > 0 {
+				x.b.WriteString("\n")
+			}
+			x.b.WriteString(html.EscapeString(l.Text()))
+		}
+		x.b.WriteString("</code></pre>\n")
+	case *model.EmbedBlock:
+		if src := embedURL(&b.Embed); b.Embed.Key == "video" && src != "" {
+			fmt.Fprintf(&x.b, "<iframe src=\"%s\" frameborder=\"0\" allowfullscreen></iframe>\n", html.EscapeString(src))
+		} else {
+			x.b.WriteString("<p>")
+			x.run(model.Run{Embed: &b.Embed, Attributes: b.Attributes})
+			x.b.WriteString("</p>\n")
+		}
+	}
+}
+
+// line writes l as a tag element, empty lines hold a <br> so they keep
+// their height
+func (x *htmlWriter) line(tag string, l model.Line) {
+	x.open(tag, l.Attributes)
+	x.runs(l.Runs)
+	fmt.Fprintf(&x.b, "</%s>\n", tag)
+}
+
+// open writes the start tag of a line with the style of its line formats
+func (x *htmlWriter) open(tag string, attrs delta.Attributes) {
+	x.b.WriteString("<" + tag)
+	var style []string
+	switch align, _ := attrs.String("align"); align {
+	case "center", "right", "justify":
+		style = append(style, "text-align: "+align)
+	}
+	if indent, _ := attrs.Int("indent"); indent > 0 && tag != "li" {
+		style = append(style, fmt.Sprintf("padding-left: %dem", indent*3))
+	}
+	if len(style) > 0 {
+		fmt.Fprintf(&x.b, ` style="%s"`, strings.Join(style, "; "))
+	}
+	if direction, _ := attrs.String("direction"); direction == "rtl" {
+		x.b.WriteString(` dir="rtl"`)
+	}
+	x.b.WriteString(">")
+}
+
+// list writes l and, inside their items, the lists nested under them
+func (x *htmlWriter) list(l *model.List) {
+	tag := "ul"
+	if l.Type == "ordered" {
+		tag = "ol"
+	}
+	x.b.WriteString("<" + tag + ">\n")
+	for _, item := range l.Items {
+		x.open("li", item.Attributes)
+		if l.Type == "checked" {
+			x.b.WriteString(`<input type="checkbox" disabled`)
+			if item.Checked {
+				x.b.WriteString(" checked")
+			}
+			x.b.WriteString("> ")
+		}
+		x.runs(item.Runs)
+		if len(item.Children) > 0 {
+			x.b.WriteString("\n")
+			for _, child := range item.Children {
+				x.list(child)
+			}
+		}
+		x.b.WriteString("</li>\n")
+	}
+	x.b.WriteString("</" + tag + ">\n")
+}
+
+func (x *htmlWriter) runs(runs []model.Run) {
+	if len(runs) == 0 {
+		x.b.WriteString("<br>")
+	}
+	for _, r := range runs {
+		x.run(r)
+	}
+}
+
+// run writes a run inside the elements of its formats
+func (x *htmlWriter) run(r model.Run) {
+	attrs := r.Attributes
+	var closing []string
+	wrap := func(open, tag string) {
+		x.b.WriteString(open)
+		closing = append(closing, "</"+tag+">")
+	}
+	if link := model.Link(attrs); link != "" {
+		wrap(fmt.Sprintf(`<a href="%s">`, html.EscapeString(link)), "a")
+	}
+	var style []string
+	if color, ok := attrs.String("color"); ok {
+		if c, ok := delta.NormalizeColor(color); ok {
+			style = append(style, "color: "+c)
+		}
+	}
+	if background, ok := attrs.String("background"); ok {
+		if c, ok := delta.NormalizeColor(background); ok {
+			style = append(style, "background-color: "+c)
+		}
+	}
+	if font, _ := attrs.String("font"); htmlFonts[font] != "" {
+		style = append(style, "font-family: "+htmlFonts[font])
+	}
+	if size, _ := attrs.String("size"); htmlSizes[size] != "" {
+		style = append(style, "font-size: "+htmlSizes[size])
+	}
+	if len(style) > 0 {
+		wrap(fmt.Sprintf(`<span style="%s">`, strings.Join(style, "; ")), "span")
+	}
+	for _, t := range htmlTags {
+		if v, _ := attrs.Bool(t.format); v {
+			wrap("<"+t.tag+">", t.tag)
+		}
+	}
+	switch script, _ := attrs.String("script"); script {
+	case "super":
+		wrap("<sup>", "sup")
+	case "sub":
+		wrap("<sub>", "sub")
+	}
+
+	switch {
+	case r.Embed == nil:
+		x.b.WriteString(html.EscapeString(r.Text))
+	case r.Embed.Key == "image":
+		alt, _ := attrs.String("alt")
+		src := embedURL(r.Embed)
+		if src == "" {
+			x.b.WriteString(html.EscapeString(alt))
+			break
+		}
+		fmt.Fprintf(&x.b, `<img src="%s" alt="%s"`, html.EscapeString(src), html.EscapeString(alt))
+		for _, dim := range []string{"width", "height"} {
+			if v, ok := attrs[dim]; ok && v != nil {
+				fmt.Fprintf(&x.b, ` %s="%s"`, dim, html.EscapeString(fmt.Sprint(v)))
+			}
+		}
+		x.b.WriteString(">")
+	case r.Embed.Key == "formula":
+		formula, _ := r.Embed.Value.(string)
+		fmt.Fprintf(&x.b, `<span class="formula">%s</span>`, html.EscapeString(formula))
+	case r.Embed.Key == "video":
+		if src := embedURL(r.Embed); src != "" {
+			fmt.Fprintf(&x.b, `<a href="%s">%s</a>`, html.EscapeString(src), html.EscapeString(src))
+		}
+	}
+	for i := len(closing) - 1; i >= 0; i-- {
+		x.b.WriteString(closing[i])
+	}
+}
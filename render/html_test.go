@@ -0,0 +1,58 @@
+package render
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+)
+
+func TestHTML(t *testing.T) {
+	doc := delta.New(nil).
+		Insert("Title", nil).Insert("\n", delta.Attributes{"header": 2}).
+		Insert("Some ", nil).Insert("bold", delta.Attributes{"bold": true, "italic": true}).
+		Insert(" <tag> ", nil).Insert("link", delta.Attributes{"link": "https://example.com/?a=1&b=2"}).
+		Insert("bad", delta.Attributes{"link": "javascript:alert(1)", "color": "red", "size": "large"}).
+		Insert("2", delta.Attributes{"script": "super"}).
+		Insert("\n", delta.Attributes{"align": "center", "direction": "rtl"}).
+		Insert("\n", nil).
+		Insert("a", nil).Insert("\n", delta.Attributes{"list": "ordered"}).
+		Insert("b", nil).Insert("\n", delta.Attributes{"list": "bullet", "indent": 1}).
+		Insert("t", nil).Insert("\n", delta.Attributes{"list": "unchecked"}).
+		Insert("q", nil).Insert("\n", delta.Attributes{"blockquote": true}).
+		Insert("x < 1", nil).Insert("\n", delta.Attributes{"code-block": "go"}).
+		Insert("y", nil).Insert("\n", delta.Attributes{"code-block": "go"}).
+		InsertEmbed(delta.Embed{Key: "image", Value: "a.png"}, delta.Attributes{"alt": "A \"pic\"", "width": "20"}).
+		InsertEmbed(delta.Embed{Key: "image", Value: "javascript:x"}, delta.Attributes{"alt": "gone"}).
+		Insert("\n", nil).
+		InsertEmbed(delta.Embed{Key: "video", Value: "https://example.com/v"}, nil)
+	var b bytes.Buffer
+	if err := HTML(&b, doc); err != nil {
+		t.Fatal(err)
+	}
+	expected := `<h2>Title</h2>
+<p style="text-align: center" dir="rtl">Some <strong><em>bold</em></strong> &lt;tag&gt; <a href="https://example.com/?a=1&amp;b=2">link</a>` +
+		`<span style="color: #ff0000; font-size: 1.5em">bad</span><sup>2</sup></p>
+<p><br></p>
+<ol>
+<li>a
+<ul>
+<li>b</li>
+</ul>
+</li>
+</ol>
+<ul>
+<li><input type="checkbox" disabled> t</li>
+</ul>
+<blockquote>
+<p>q</p>
+</blockquote>
+<pre><code class="language-go">x &lt; 1
+y</code></pre>
+<p><img src="a.png" alt="A &#34;pic&#34;" width="20">gone</p>
+<iframe src="https://example.com/v" frameborder="0" allowfullscreen></iframe>
+`
+	if b.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, b.String())
+	}
+}
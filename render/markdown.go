@@ -0,0 +1,265 @@
+package render
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+	"github.com/fmpwizard/go-quilljs-delta/model"
+)
+
+// mdEscaped are the characters escaped in Markdown text, everywhere
+const mdEscaped = "\\`*_[]<>~|&"
+
+// mark is an inline format as Markdown, what goes before and after the text
+type mark struct {
+	open, close string
+}
+
+// Markdown writes doc as GitHub flavored Markdown. Each line is a paragraph
+// of its own, underline, superscript and subscript are inline HTML, and
+// formats Markdown has no syntax for, like colors, are left out
+func Markdown(w io.Writer, doc *delta.Delta) error {
+	parsed, err := model.Parse(doc)
+	if err != nil {
+		return err
+	}
+	x := &markdownWriter{}
+	for _, b := range parsed.Blocks {
+		x.block(b)
+	}
+	// blocks end with a blank line, the last one doesn't need it
+	out := x.b.Bytes()
+	if bytes.HasSuffix(out, []byte("\n\n")) {
+		out = out[:len(out)-1]
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// markdownWriter holds the state of one Markdown call
+type markdownWriter struct {
+	b bytes.Buffer
+}
+
+func (x *markdownWriter) block(b model.Block) {
+	switch b := b.(type) {
+	case *model.Paragraph:
+		if len(b.Runs) > 0 {
+			x.b.WriteString(mdInline(b.Runs) + "\n\n")
+		}
+	case *model.Heading:
+		level := b.Level
+		if level > 6 {
+			level = 6
+		}
+		x.b.WriteString(strings.Repeat("#", level) + " " + mdInline(b.Runs) + "\n\n")
+	case *model.List:
+		x.list(b, "")
+		x.b.WriteString("\n")
+	case *model.Blockquote:
+		for i, l := range b.Lines {
+			if i > 0 {
+				x.b.WriteString(">\n")
+			}
+			x.b.WriteString("> " + mdInline(l.Runs) + "\n")
+		}
+		x.b.WriteString("\n")
+	case *model.CodeBlock:
+		fence := "```"
+		for _, l := range b.Lines {
+			for strings.Contains(l.Text(), fence) {
+				fence += "`"
+			}
+		}
+		x.b.WriteString(fence + b.Language + "\n")
+		for _, l := range b.Lines {
+			x.b.WriteString(l.Text() + "\n")
+		}
+		x.b.WriteString(fence + "\n\n")
+	case *model.EmbedBlock:
+		if src := embedURL(&b.Embed); b.Embed.Key == "video" && src != "" {
+			x.b.WriteString("[" + mdEscape(src) + "](" + mdDestination(src) + ")\n\n")
+		} else if text := mdInline([]model.Run{{Embed: &b.Embed, Attributes: b.Attributes}}); text != "" {
+			x.b.WriteString(text + "\n\n")
+		}
+	}
+}
+
+// list writes the items of l after indent, with the lists nested under them
+// indented to the text of their item
+func (x *markdownWriter) list(l *model.List, indent string) {
+	for i, item := range l.Items {
+		marker := "- "
+		if l.Type == "ordered" {
+			marker = strconv.Itoa(i+1) + ". "
+		}
+		x.b.WriteString(indent + marker)
+		if l.Type == "checked" {
+			if item.Checked {
+				x.b.WriteString("[x] ")
+			} else {
+				x.b.WriteString("[ ] ")
+			}
+		}
+		x.b.WriteString(mdInline(item.Runs) + "\n")
+		for _, child := range item.Children {
+			x.list(child, indent+strings.Repeat(" ", len(marker)))
+		}
+	}
+}
+
+// mdInline returns runs as Markdown. Formats shared by neighbouring runs are
+// opened once, and spaces are kept out of the markers around them, or
+// Markdown wouldn't see them as emphasis
+func mdInline(runs []model.Run) string {
+	var b bytes.Buffer
+	var open []mark
+	closeTo := func(n int) {
+		text := b.Bytes()
+		trimmed := bytes.TrimRightFunc(text, unicode.IsSpace)
+		spaces := string(text[len(trimmed):])
+		b.Truncate(len(trimmed))
+		for i := len(open) - 1; i >= n; i-- {
+			b.WriteString(open[i].close)
+		}
+		b.WriteString(spaces)
+		open = open[:n]
+	}
+	for _, r := range runs {
+		marks := mdMarks(r.Attributes)
+		keep := 0
+		for keep < len(open) && keep < len(marks) && open[keep] == marks[keep] {
+			keep++
+		}
+		closeTo(keep)
+		content := mdRun(r)
+		if content == "" {
+			continue
+		}
+		if r.Embed == nil && strings.TrimSpace(r.Text) == "" {
+			// nothing to format
+			b.WriteString(content)
+			continue
+		}
+		trimmed := strings.TrimLeftFunc(content, unicode.IsSpace)
+		b.WriteString(content[:len(content)-len(trimmed)])
+		if b.Len() == 0 && keep == len(marks) {
+			trimmed = mdEscapeStart(trimmed)
+		}
+		for _, m := range marks[keep:] {
+			b.WriteString(m.open)
+			open = append(open, m)
+		}
+		b.WriteString(trimmed)
+	}
+	closeTo(0)
+	return b.String()
+}
+
+// mdMarks returns the marks of the inline formats of attrs, outermost first
+func mdMarks(attrs delta.Attributes) []mark {
+	var marks []mark
+	if link := model.Link(attrs); link != "" {
+		marks = append(marks, mark{"[", "](" + mdDestination(link) + ")"})
+	}
+	for _, f := range []struct {
+		format string
+		mark   mark
+	}{
+		{"bold", mark{"**", "**"}}, {"italic", mark{"*", "*"}}, {"strike", mark{"~~", "~~"}},
+		{"underline", mark{"<u>", "</u>"}},
+	} {
+		if v, _ := attrs.Bool(f.format); v {
+			marks = append(marks, f.mark)
+		}
+	}
+	switch script, _ := attrs.String("script"); script {
+	case "super":
+		marks = append(marks, mark{"<sup>", "</sup>"})
+	case "sub":
+		marks = append(marks, mark{"<sub>", "</sub>"})
+	}
+	return marks
+}
+
+// mdRun returns the content of a run, text, a code span or an image
+func mdRun(r model.Run) string {
+	if r.Embed == nil {
+		if code, _ := r.Attributes.Bool("code"); code {
+			return mdCode(r.Text)
+		}
+		return mdEscape(r.Text)
+	}
+	switch r.Embed.Key {
+	case "image":
+		alt, _ := r.Attributes.String("alt")
+		if src := embedURL(r.Embed); src != "" {
+			return "![" + mdEscape(alt) + "](" + mdDestination(src) + ")"
+		}
+		return mdEscape(alt)
+	case "formula":
+		formula, _ := r.Embed.Value.(string)
+		return mdCode(formula)
+	}
+	return ""
+}
+
+// mdCode returns text as a code span, with a fence of backticks longer than
+// the ones in text
+func mdCode(text string) string {
+	fence := "`"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") ||
+		(strings.HasPrefix(text, " ") && strings.HasSuffix(text, " ") && strings.TrimSpace(text) != "") {
+		// Markdown strips one space on each side
+		text = " " + text + " "
+	}
+	return fence + text + fence
+}
+
+// mdEscape escapes the characters of text that Markdown gives a meaning to
+// inside a line
+func mdEscape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if strings.ContainsRune(mdEscaped, r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// mdEscapeStart escapes what would make text, at the start of a line, a
+// heading, a list item or a thematic break
+func mdEscapeStart(text string) string {
+	if text == "" {
+		return text
+	}
+	if strings.ContainsRune("#-+=", rune(text[0])) {
+		return `\` + text
+	}
+	digits := 0
+	for digits < len(text) && text[digits] >= '0' && text[digits] <= '9' {
+		digits++
+	}
+	if digits > 0 && digits < len(text) && (text[digits] == '.' || text[digits] == ')') {
+		return text[:digits] + `\` + text[digits:]
+	}
+	return text
+}
+
+// mdDestination returns url as the destination of a link or image, in angle
+// brackets when it has characters that would end it early
+func mdDestination(url string) string {
+	if strings.ContainsAny(url, " ()<>") {
+		return "<" + strings.NewReplacer("<", "%3C", ">", "%3E").Replace(url) + ">"
+	}
+	return url
+}
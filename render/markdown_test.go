@@ -0,0 +1,64 @@
+package render
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+)
+
+func TestMarkdown(t *testing.T) {
+	doc := delta.New(nil).
+		Insert("Title", nil).Insert("\n", delta.Attributes{"header": 1}).
+		Insert("1. Some ", nil).Insert("bold ", delta.Attributes{"bold": true}).
+		Insert("both", delta.Attributes{"bold": true, "italic": true}).
+		Insert(" a*b ", nil).Insert("link", delta.Attributes{"link": "https://example.com/a (b)"}).
+		Insert(" ", delta.Attributes{"strike": true}).Insert("x`y", delta.Attributes{"code": true}).
+		Insert("u", delta.Attributes{"underline": true}).
+		Insert("\n\n", nil).
+		Insert("# not a heading\n", nil).
+		Insert("a", nil).Insert("\n", delta.Attributes{"list": "ordered"}).
+		Insert("b", nil).Insert("\n", delta.Attributes{"list": "bullet", "indent": 1}).
+		Insert("c", nil).Insert("\n", delta.Attributes{"list": "ordered"}).
+		Insert("done", nil).Insert("\n", delta.Attributes{"list": "checked"}).
+		Insert("q1", nil).Insert("\n", delta.Attributes{"blockquote": true}).
+		Insert("q2", nil).Insert("\n", delta.Attributes{"blockquote": true}).
+		Insert("s := \"```\"", nil).Insert("\n", delta.Attributes{"code-block": "go"}).
+		InsertEmbed(delta.Embed{Key: "image", Value: "a.png"}, delta.Attributes{"alt": "A"}).
+		Insert("\n", nil)
+	var b bytes.Buffer
+	if err := Markdown(&b, doc); err != nil {
+		t.Fatal(err)
+	}
+	expected := "# Title\n\n" +
+		"1\\. Some **bold *both*** a\\*b [link](<https://example.com/a (b)>) ``x`y``<u>u</u>\n\n" +
+		"\\# not a heading\n\n" +
+		"1. a\n   - b\n2. c\n\n" +
+		"- [x] done\n\n" +
+		"> q1\n>\n> q2\n\n" +
+		"````go\ns := \"```\"\n````\n\n" +
+		"![A](a.png)\n"
+	if b.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, b.String())
+	}
+}
+
+func TestMarkdownSpaces(t *testing.T) {
+	tests := []struct {
+		doc      *delta.Delta
+		expected string
+	}{
+		{delta.New(nil).Insert(" bold ", delta.Attributes{"bold": true}).Insert("x\n", nil), " **bold** x\n"},
+		{delta.New(nil).Insert("a", delta.Attributes{"italic": true}).Insert("b", delta.Attributes{"bold": true}).Insert("\n", nil), "*a***b**\n"},
+		{delta.New(nil).Insert(" ", delta.Attributes{"code": true}).Insert("\n", nil), "` `\n"},
+	}
+	for _, test := range tests {
+		var b bytes.Buffer
+		if err := Markdown(&b, test.doc); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != test.expected {
+			t.Errorf("expected %q, got %q", test.expected, b.String())
+		}
+	}
+}
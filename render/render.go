@@ -0,0 +1,38 @@
+// Package render writes Quill documents, insert only deltas, as HTML,
+// Markdown or plain text, the formats that are read rather than opened in
+// another editor. Links and images with unsafe URLs, see delta.Sanitizer,
+// are written as their text.
+package render
+
+import (
+	"io"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+)
+
+// Func writes a document to w
+type Func func(w io.Writer, doc *delta.Delta) error
+
+// Formats are the renderers by the name of their format
+var Formats = map[string]Func{
+	"html": HTML,
+	"md":   Markdown,
+	"text": Text,
+}
+
+// urls checks the URLs of images and videos, model.Link checks links
+var urls = func() *delta.Sanitizer {
+	s := delta.NewSanitizer()
+	s.DataImages = true
+	return s
+}()
+
+// embedURL returns the URL of an image or video embed, "" when it's not
+// safe
+func embedURL(e *delta.Embed) string {
+	src, _ := e.Value.(string)
+	if src == "" || !urls.ValidURL(src, e.Key == "image") {
+		return ""
+	}
+	return src
+}
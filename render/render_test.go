@@ -0,0 +1,40 @@
+package render
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+	"github.com/fmpwizard/go-quilljs-delta/model"
+)
+
+func TestFormats(t *testing.T) {
+	doc := delta.New(nil).Insert("hi\n", nil)
+	for name, render := range Formats {
+		var b bytes.Buffer
+		if err := render(&b, doc); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		if !bytes.Contains(b.Bytes(), []byte("hi")) {
+			t.Errorf("%s: expected the text of the document, got %q", name, b.String())
+		}
+		if err := render(&b, delta.New(nil).Retain(1, nil)); err != model.ErrNotDocument {
+			t.Errorf("%s: expected ErrNotDocument, got %v", name, err)
+		}
+	}
+}
+
+func TestURLs(t *testing.T) {
+	if got := model.Link(delta.Attributes{"link": "javascript:alert(1)"}); got != "" {
+		t.Errorf("expected no link for a javascript: URL, got %q", got)
+	}
+	if got := model.Link(delta.Attributes{"link": "/docs"}); got != "/docs" {
+		t.Errorf("expected relative links to be kept, got %q", got)
+	}
+	if got := embedURL(&delta.Embed{Key: "image", Value: "data:image/png;base64,AA=="}); got == "" {
+		t.Errorf("expected data: URLs of images to be kept")
+	}
+	if got := embedURL(&delta.Embed{Key: "video", Value: "data:image/png;base64,AA=="}); got != "" {
+		t.Errorf("expected no data: URL for videos, got %q", got)
+	}
+}
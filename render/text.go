@@ -0,0 +1,97 @@
+package render
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+	"github.com/fmpwizard/go-quilljs-delta/model"
+)
+
+// Text writes doc as plain text, one line for each line of the document.
+// List items get their marker and are indented two spaces by level, and
+// quoted lines start with "> ". Images are written as their alt text
+func Text(w io.Writer, doc *delta.Delta) error {
+	parsed, err := model.Parse(doc)
+	if err != nil {
+		return err
+	}
+	x := &textWriter{}
+	for _, b := range parsed.Blocks {
+		x.block(b)
+	}
+	_, err = w.Write(x.b.Bytes())
+	return err
+}
+
+// textWriter holds the state of one Text call
+type textWriter struct {
+	b bytes.Buffer
+}
+
+func (x *textWriter) block(b model.Block) {
+	switch b := b.(type) {
+	case *model.Paragraph:
+		x.line("", b.Line)
+	case *model.Heading:
+		x.line("", b.Line)
+	case *model.List:
+		x.list(b)
+	case *model.Blockquote:
+		for _, l := range b.Lines {
+			x.line("> ", l)
+		}
+	case *model.CodeBlock:
+		for _, l := range b.Lines {
+			x.line("", l)
+		}
+	case *model.EmbedBlock:
+		if src, _ := b.Embed.Value.(string); b.Embed.Key == "video" {
+			x.b.WriteString(src + "\n")
+		} else {
+			x.line("", model.Line{Runs: []model.Run{{Embed: &b.Embed, Attributes: b.Attributes}}})
+		}
+	}
+}
+
+func (x *textWriter) list(l *model.List) {
+	indent := strings.Repeat("  ", l.Indent)
+	for i, item := range l.Items {
+		marker := "- "
+		switch {
+		case l.Type == "ordered":
+			marker = strconv.Itoa(i+1) + ". "
+		case l.Type == "checked" && item.Checked:
+			marker = "[x] "
+		case l.Type == "checked":
+			marker = "[ ] "
+		}
+		x.line(indent+marker, item.Line)
+		for _, child := range item.Children {
+			x.list(child)
+		}
+	}
+}
+
+// line writes the text of l after prefix. The last line of a delta that
+// doesn't end with a newline doesn't get one either
+func (x *textWriter) line(prefix string, l model.Line) {
+	x.b.WriteString(prefix)
+	for _, r := range l.Runs {
+		switch {
+		case r.Embed == nil:
+			x.b.WriteString(r.Text)
+		case r.Embed.Key == "image":
+			alt, _ := r.Attributes.String("alt")
+			x.b.WriteString(alt)
+		case r.Embed.Key == "formula":
+			formula, _ := r.Embed.Value.(string)
+			x.b.WriteString(formula)
+		}
+	}
+	if !l.NoNewline {
+		x.b.WriteString("\n")
+	}
+}
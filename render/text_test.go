@@ -0,0 +1,32 @@
+package render
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+)
+
+func TestText(t *testing.T) {
+	doc := delta.New(nil).
+		Insert("Title", nil).Insert("\n", delta.Attributes{"header": 1}).
+		Insert("Some ", nil).Insert("bold", delta.Attributes{"bold": true}).
+		InsertEmbed(delta.Embed{Key: "image", Value: "a.png"}, delta.Attributes{"alt": "[A]"}).
+		InsertEmbed(delta.Embed{Key: "formula", Value: "e=mc^2"}, nil).
+		Insert("\n", nil).
+		Insert("a", nil).Insert("\n", delta.Attributes{"list": "ordered"}).
+		Insert("b", nil).Insert("\n", delta.Attributes{"list": "bullet", "indent": 1}).
+		Insert("todo", nil).Insert("\n", delta.Attributes{"list": "unchecked"}).
+		Insert("q", nil).Insert("\n", delta.Attributes{"blockquote": true}).
+		Insert("  code", nil).Insert("\n", delta.Attributes{"code-block": true}).
+		InsertEmbed(delta.Embed{Key: "video", Value: "https://example.com/v"}, nil).
+		Insert("end", nil)
+	var b bytes.Buffer
+	if err := Text(&b, doc); err != nil {
+		t.Fatal(err)
+	}
+	expected := "Title\nSome bold[A]e=mc^2\n1. a\n  - b\n[ ] todo\n> q\n  code\nhttps://example.com/v\nend"
+	if b.String() != expected {
+		t.Errorf("expected\n%q\ngot\n%q", expected, b.String())
+	}
+}
@@ -0,0 +1,438 @@
+// Package rtf exports Quill documents, insert only deltas, as Rich Text
+// Format, for the clipboard of desktop applications and older systems that
+// take nothing else. The font and color tables only list what the document
+// uses, and text outside of ASCII is written as \uN? escapes.
+package rtf
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+	"github.com/fmpwizard/go-quilljs-delta/model"
+)
+
+const (
+	defaultFont = "Times New Roman"
+	// twipsPerIndent is how much each level of quill's indent format moves
+	// a paragraph, half an inch
+	twipsPerIndent = 720
+	// twipsPerPixel converts pixels, at 96 dpi, to twips
+	twipsPerPixel = 15
+	// maxImageWidth is the width of the text on a letter page, in pixels
+	maxImageWidth = 624
+	// linkColor is the color links are written in
+	linkColor = "#0000ff"
+)
+
+// headingSizes are the font sizes of the header levels, in half points
+var headingSizes = []int{48, 36, 28, 24, 20, 18}
+
+// families are the font families of the fonts quill knows about, fonts
+// missing from it are written as \fnil
+var families = map[string]string{"Times New Roman": `\froman`, model.CodeFont: `\fmodern`, "Arial": `\fswiss`}
+
+// alignments maps quill's align format to paragraph control words
+var alignments = map[string]string{"center": `\qc`, "right": `\qr`, "justify": `\qj`}
+
+// pictures maps the image types RTF can embed to their control word
+var pictures = map[string]string{"image/png": `\pngblip`, "image/jpeg": `\jpegblip`, "image/jpg": `\jpegblip`}
+
+// Exporter writes documents as RTF
+type Exporter struct {
+	// Images gets the content of image embeds. When it returns no image, or
+	// one that isn't a PNG or JPEG, the alt text of the embed is used instead
+	Images model.ImageResolver
+}
+
+// NewExporter returns an Exporter that only includes images from data: URLs
+func NewExporter() *Exporter {
+	return &Exporter{Images: model.DataURLs}
+}
+
+// Export writes doc as RTF to w, with NewExporter()
+func Export(w io.Writer, doc *delta.Delta) error {
+	return NewExporter().Export(w, doc)
+}
+
+// Export writes doc, an insert only delta, as RTF to w
+func (e *Exporter) Export(w io.Writer, doc *delta.Delta) error {
+	parsed, err := model.Parse(doc)
+	if err != nil {
+		return err
+	}
+	x := &exporter{
+		images:     e.Images,
+		fontIndex:  make(map[string]int),
+		colorIndex: make(map[string]int),
+	}
+	if x.images == nil {
+		x.images = model.DataURLs
+	}
+	x.font(defaultFont)
+	for _, b := range parsed.Blocks {
+		x.collect(b)
+	}
+	for _, b := range parsed.Blocks {
+		x.block(b)
+	}
+	if x.err != nil {
+		return x.err
+	}
+
+	var out bytes.Buffer
+	out.WriteString(`{\rtf1\ansi\ansicpg1252\deff0\uc1` + "\n")
+	out.WriteString(`{\fonttbl`)
+	for i, name := range x.fonts {
+		family := families[name]
+		if family == "" {
+			family = `\fnil`
+		}
+		fmt.Fprintf(&out, `{\f%d%s\fcharset0 %s;}`, i, family, escapeFontName(name))
+	}
+	out.WriteString("}\n")
+	if len(x.colors) > 0 {
+		// the first entry is empty, it stands for the default color
+		out.WriteString(`{\colortbl;`)
+		for _, c := range x.colors {
+			r, _ := strconv.ParseUint(c[1:3], 16, 8)
+			g, _ := strconv.ParseUint(c[3:5], 16, 8)
+			b, _ := strconv.ParseUint(c[5:7], 16, 8)
+			fmt.Fprintf(&out, `\red%d\green%d\blue%d;`, r, g, b)
+		}
+		out.WriteString("}\n")
+	}
+	out.Write(x.body.Bytes())
+	out.WriteString("}\n")
+	_, err = w.Write(out.Bytes())
+	return err
+}
+
+// exporter holds the state of one Export call
+type exporter struct {
+	images     model.ImageResolver
+	fonts      []string
+	fontIndex  map[string]int
+	colors     []string
+	colorIndex map[string]int
+	body       bytes.Buffer
+	err        error
+}
+
+// font returns the index of name in the font table, adding it if needed
+func (x *exporter) font(name string) int {
+	i, ok := x.fontIndex[name]
+	if !ok {
+		i = len(x.fonts)
+		x.fonts = append(x.fonts, name)
+		x.fontIndex[name] = i
+	}
+	return i
+}
+
+// color returns the index of a css color in the color table, adding it if
+// needed. Colors it can't read give 0, the default color
+func (x *exporter) color(css string) int {
+	c, ok := delta.NormalizeColor(css)
+	if !ok {
+		return 0
+	}
+	i, ok := x.colorIndex[c]
+	if !ok {
+		x.colors = append(x.colors, c)
+		i = len(x.colors)
+		x.colorIndex[c] = i
+	}
+	return i
+}
+
+// collect adds the fonts and colors of b to the tables, so they are in the
+// order the document uses them
+func (x *exporter) collect(b model.Block) {
+	var lines []model.Line
+	switch b := b.(type) {
+	case *model.Paragraph:
+		lines = append(lines, b.Line)
+	case *model.Heading:
+		lines = append(lines, b.Line)
+	case *model.List:
+		for _, item := range b.Items {
+			lines = append(lines, item.Line)
+			for _, child := range item.Children {
+				x.collect(child)
+			}
+		}
+	case *model.Blockquote:
+		lines = b.Lines
+	case *model.CodeBlock:
+		x.font(model.CodeFont)
+	case *model.EmbedBlock:
+		if b.Embed.Key == "video" {
+			x.color(linkColor)
+		}
+	}
+	for _, l := range lines {
+		for _, r := range l.Runs {
+			x.collectRun(r.Attributes)
+		}
+	}
+}
+
+func (x *exporter) collectRun(attrs delta.Attributes) {
+	if font, _ := attrs.String("font"); font != "" {
+		x.font(model.FontName(font))
+	}
+	if code, _ := attrs.Bool("code"); code {
+		x.font(model.CodeFont)
+	}
+	if color, ok := attrs.String("color"); ok {
+		x.color(color)
+	}
+	if link := model.Link(attrs); link != "" {
+		x.color(linkColor)
+	}
+	if background, ok := attrs.String("background"); ok {
+		x.color(background)
+	}
+}
+
+func (x *exporter) block(b model.Block) {
+	switch b := b.(type) {
+	case *model.Paragraph:
+		x.paragraph(b.Line, "", "")
+	case *model.Heading:
+		level := b.Level
+		if level > len(headingSizes) {
+			level = len(headingSizes)
+		}
+		x.paragraph(b.Line, `\sb240\sa120\keepn`, fmt.Sprintf(`\b\fs%d`, headingSizes[level-1]))
+	case *model.List:
+		x.list(b)
+	case *model.Blockquote:
+		for _, l := range b.Lines {
+			x.paragraph(l, fmt.Sprintf(`\li%d\brdrl\brdrs\brdrw30\brsp120`, twipsPerIndent), "")
+		}
+	case *model.CodeBlock:
+		f := x.font(model.CodeFont)
+		for _, l := range b.Lines {
+			fmt.Fprintf(&x.body, `\pard\plain{\f%d\fs20 %s}\par`+"\n", f, Escape(l.Text()))
+		}
+	case *model.EmbedBlock:
+		x.body.WriteString(`\pard\plain `)
+		src, _ := b.Embed.Value.(string)
+		if b.Embed.Key == "video" && src != "" {
+			x.run(model.Run{Text: src, Attributes: delta.Attributes{"link": src}})
+		} else {
+			x.run(model.Run{Embed: &b.Embed, Attributes: b.Attributes})
+		}
+		x.body.WriteString(`\par` + "\n")
+	}
+}
+
+// paragraph writes l with the controls of its block attributes, extra are
+// the paragraph controls of the kind of block it is in and chars the
+// character controls it gives its text
+func (x *exporter) paragraph(l model.Line, extra, chars string) {
+	x.body.WriteString(`\pard\plain`)
+	x.body.WriteString(x.paragraphControls(l.Attributes, 0))
+	x.body.WriteString(extra + chars + " ")
+	x.runs(l.Runs)
+	x.body.WriteString(`\par` + "\n")
+}
+
+// paragraphControls returns the alignment, direction and indentation of a
+// paragraph, indented by another offset twips
+func (x *exporter) paragraphControls(attrs delta.Attributes, offset int) string {
+	var b bytes.Buffer
+	if align, _ := attrs.String("align"); alignments[align] != "" {
+		b.WriteString(alignments[align])
+	}
+	if direction, _ := attrs.String("direction"); direction == "rtl" {
+		b.WriteString(`\rtlpar`)
+	}
+	indent, _ := attrs.Int("indent")
+	if left := indent*twipsPerIndent + offset; left > 0 {
+		fmt.Fprintf(&b, `\li%d`, left)
+	}
+	return b.String()
+}
+
+// list writes the items of l as paragraphs with a hanging marker. The
+// marker is both written as \pntext, for readers that take it as is, and
+// described with \pn, for the ones that make a list of it
+func (x *exporter) list(l *model.List) {
+	for n, item := range l.Items {
+		x.body.WriteString(`\pard\plain`)
+		x.body.WriteString(x.paragraphControls(item.Attributes, twipsPerIndent))
+		x.body.WriteString(`\fi-360`)
+		switch {
+		case l.Type == "ordered":
+			fmt.Fprintf(&x.body, `{\pntext %d.\tab}{\*\pn\pnlvlbody\pndec\pnstart%d\pnindent360{\pntxta .}}`, n+1, n+1)
+		case l.Type == "checked" && item.Checked:
+			x.body.WriteString(`{\pntext ` + Escape("☑") + `\tab}`)
+		case l.Type == "checked":
+			x.body.WriteString(`{\pntext ` + Escape("☐") + `\tab}`)
+		default:
+			x.body.WriteString(`{\pntext \bullet\tab}{\*\pn\pnlvlblt\pnindent360{\pntxtb\bullet}}`)
+		}
+		x.body.WriteString(" ")
+		x.runs(item.Runs)
+		x.body.WriteString(`\par` + "\n")
+		for _, child := range item.Children {
+			x.list(child)
+		}
+	}
+}
+
+func (x *exporter) runs(runs []model.Run) {
+	for _, r := range runs {
+		x.run(r)
+	}
+}
+
+// run writes r as a group with the controls of its formats
+func (x *exporter) run(r model.Run) {
+	attrs := r.Attributes
+	link := model.Link(attrs)
+	if link != "" {
+		fmt.Fprintf(&x.body, `{\field{\*\fldinst HYPERLINK "%s"}{\fldrslt `, Escape(strings.Replace(link, `"`, "%22", -1)))
+	}
+	// runs without formats need no group of their own
+	controls := x.characterControls(attrs, link != "")
+	if controls != "" {
+		x.body.WriteString("{" + controls + " ")
+	}
+	switch {
+	case r.Embed == nil:
+		x.body.WriteString(Escape(r.Text))
+	case r.Embed.Key == "image":
+		src, _ := r.Embed.Value.(string)
+		x.image(src, attrs)
+	case r.Embed.Key == "formula":
+		formula, _ := r.Embed.Value.(string)
+		x.body.WriteString(Escape(formula))
+	}
+	if controls != "" {
+		x.body.WriteString("}")
+	}
+	if link != "" {
+		x.body.WriteString("}}")
+	}
+}
+
+// characterControls returns the control words of a run's formats
+func (x *exporter) characterControls(attrs delta.Attributes, link bool) string {
+	var b bytes.Buffer
+	if font, _ := attrs.String("font"); font != "" {
+		fmt.Fprintf(&b, `\f%d`, x.font(model.FontName(font)))
+	}
+	if code, _ := attrs.Bool("code"); code {
+		fmt.Fprintf(&b, `\f%d`, x.font(model.CodeFont))
+	}
+	if size, _ := attrs.String("size"); size != "" {
+		if n, ok := model.HalfPoints(size); ok {
+			fmt.Fprintf(&b, `\fs%d`, n)
+		}
+	}
+	for _, f := range []struct{ format, control string }{
+		{"bold", `\b`}, {"italic", `\i`}, {"underline", `\ul`}, {"strike", `\strike`},
+	} {
+		if v, _ := attrs.Bool(f.format); v {
+			b.WriteString(f.control)
+		}
+	}
+	switch script, _ := attrs.String("script"); script {
+	case "super":
+		b.WriteString(`\super`)
+	case "sub":
+		b.WriteString(`\sub`)
+	}
+	color, hasColor := attrs.String("color")
+	if link && !hasColor {
+		color, hasColor = linkColor, true
+		if v, ok := attrs.Bool("underline"); !ok || v {
+			b.WriteString(`\ul`)
+		}
+	}
+	if hasColor {
+		if i := x.color(color); i > 0 {
+			fmt.Fprintf(&b, `\cf%d`, i)
+		}
+	}
+	if background, ok := attrs.String("background"); ok {
+		if i := x.color(background); i > 0 {
+			fmt.Fprintf(&b, `\highlight%d`, i)
+		}
+	}
+	return b.String()
+}
+
+func (x *exporter) image(src string, attrs delta.Attributes) {
+	alt, _ := attrs.String("alt")
+	img, err := x.images.ResolveImage(src)
+	if err != nil {
+		if x.err == nil {
+			x.err = err
+		}
+		return
+	}
+	blip := ""
+	if img != nil {
+		blip = pictures[strings.ToLower(img.ContentType)]
+	}
+	if blip == "" {
+		x.body.WriteString(Escape(alt))
+		return
+	}
+	width, height := img.DisplaySize(attrs, maxImageWidth)
+	naturalWidth, naturalHeight, ok := img.Size()
+	if !ok {
+		naturalWidth, naturalHeight = width, height
+	}
+	fmt.Fprintf(&x.body, `{\pict%s\picw%d\pich%d\picwgoal%d\pichgoal%d`+"\n",
+		blip, naturalWidth, naturalHeight, width*twipsPerPixel, height*twipsPerPixel)
+	data := hex.EncodeToString(img.Data)
+	for len(data) > 128 {
+		x.body.WriteString(data[:128] + "\n")
+		data = data[128:]
+	}
+	x.body.WriteString(data + "}")
+}
+
+// escapeFontName escapes name for the font table, where a semicolon ends the
+// name, so it is written as a hex escape
+func escapeFontName(name string) string {
+	return strings.Replace(Escape(name), ";", `\'3b`, -1)
+}
+
+// Escape returns text as RTF: backslashes and braces are escaped, tabs and
+// line breaks become control words, and runes outside of ASCII become \uN?
+// escapes of their UTF-16 code units
+func Escape(text string) string {
+	var b bytes.Buffer
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '{' || r == '}':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case r == '\t':
+			b.WriteString(`\tab `)
+		case r == '\n':
+			b.WriteString(`\line `)
+		case r == '\r':
+		case r < 0x80:
+			b.WriteRune(r)
+		default:
+			for _, u := range utf16.Encode([]rune{r}) {
+				// \u takes a signed 16 bit number
+				fmt.Fprintf(&b, `\u%d?`, int16(u))
+			}
+		}
+	}
+	return b.String()
+}
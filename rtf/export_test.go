@@ -0,0 +1,171 @@
+package rtf
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+	"github.com/fmpwizard/go-quilljs-delta/model"
+)
+
+func testPNG(w, h int) []byte {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h)))
+	return buf.Bytes()
+}
+
+// balanced reports whether the groups of an RTF document are balanced
+func balanced(s string) bool {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth < 0 {
+				return false
+			}
+		}
+	}
+	return depth == 0
+}
+
+func TestEscape(t *testing.T) {
+	got := Escape("a\\b{c}\td é😀")
+	expected := `a\\b\{c\}\tab d \u233?\u-10179?\u-8704?`
+	if got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+}
+
+func TestExportFontNames(t *testing.T) {
+	doc := delta.New(nil).
+		Insert("odd", delta.Attributes{"font": `a;}{\b`}).
+		Insert("\n", nil)
+	var buf bytes.Buffer
+	if err := Export(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	if !balanced(got) || !strings.Contains(got, `\fcharset0 a\'3b\}\{\\b;}`) {
+		t.Errorf("expected the font name to be escaped\n%s", got)
+	}
+}
+
+func TestExport(t *testing.T) {
+	doc := delta.New(nil).
+		Insert("Title", nil).Insert("\n", delta.Attributes{"header": 1}).
+		Insert("Some ", nil).Insert("bold", delta.Attributes{"bold": true, "color": "red"}).
+		Insert(" ", nil).Insert("it", delta.Attributes{"italic": true, "underline": true, "strike": true}).
+		Insert(" x", nil).Insert("2", delta.Attributes{"script": "super", "size": "large"}).
+		Insert(" ", nil).Insert("mono", delta.Attributes{"font": "monospace", "background": "#ff0"}).
+		Insert(" ", nil).Insert("link", delta.Attributes{"link": "https://example.com/"}).
+		Insert(" café", delta.Attributes{"color": "#f00", "font": "Comic Sans"}).
+		Insert("\n", delta.Attributes{"align": "center", "indent": 1}).
+		Insert("one", nil).Insert("\n", delta.Attributes{"list": "ordered"}).
+		Insert("nested", nil).Insert("\n", delta.Attributes{"list": "bullet", "indent": 1}).
+		Insert("two", nil).Insert("\n", delta.Attributes{"list": "ordered"}).
+		Insert("done", nil).Insert("\n", delta.Attributes{"list": "checked"}).
+		Insert("quoted", nil).Insert("\n", delta.Attributes{"blockquote": true}).
+		Insert("x := {}", nil).Insert("\n", delta.Attributes{"code-block": true}).
+		Insert("\n", nil)
+
+	var buf bytes.Buffer
+	if err := Export(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	if !balanced(got) {
+		t.Errorf("unbalanced groups\n%s", got)
+	}
+	for _, s := range []string{
+		`{\rtf1\ansi\ansicpg1252\deff0\uc1`,
+		`{\fonttbl{\f0\froman\fcharset0 Times New Roman;}{\f1\fmodern\fcharset0 Courier New;}{\f2\fnil\fcharset0 Comic Sans;}}`,
+		// red is only listed once, and in the order the document uses colors
+		`{\colortbl;\red255\green0\blue0;\red255\green255\blue0;\red0\green0\blue255;}`,
+		`\pard\plain\sb240\sa120\keepn\b\fs48 Title\par`,
+		`\pard\plain\qc\li720 Some {\b\cf1 bold} {\i\ul\strike it} x{\fs32\super 2} {\f1\highlight2 mono} ` +
+			`{\field{\*\fldinst HYPERLINK "https://example.com/"}{\fldrslt {\ul\cf3 link}}}{\f2\cf1  caf\u233?}\par`,
+		`\pard\plain\li720\fi-360{\pntext 1.\tab}{\*\pn\pnlvlbody\pndec\pnstart1\pnindent360{\pntxta .}} one\par`,
+		`\pard\plain\li1440\fi-360{\pntext \bullet\tab}{\*\pn\pnlvlblt\pnindent360{\pntxtb\bullet}} nested\par`,
+		`{\pntext 2.\tab}`,
+		`\pard\plain\li720\fi-360{\pntext \u9745?\tab} done\par`,
+		`\pard\plain\li720\brdrl\brdrs\brdrw30\brsp120 quoted\par`,
+		`\pard\plain{\f1\fs20 x := \{\}}\par`,
+	} {
+		if !strings.Contains(got, s) {
+			t.Errorf("output is missing %s\n%s", s, got)
+		}
+	}
+}
+
+func TestExportImages(t *testing.T) {
+	data := testPNG(40, 20)
+	img := "data:image/png;base64," + base64.StdEncoding.EncodeToString(data)
+	doc := delta.New(nil).
+		InsertEmbed(delta.Embed{Key: "image", Value: img}, delta.Attributes{"width": "20"}).
+		InsertEmbed(delta.Embed{Key: "image", Value: "https://example.com/a.png"}, delta.Attributes{"alt": "remote"}).
+		Insert("\n", nil)
+
+	var buf bytes.Buffer
+	if err := Export(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	flat := strings.Replace(got, "\n", "", -1)
+	if !strings.Contains(got, `{\pict\pngblip\picw40\pich20\picwgoal300\pichgoal150`) ||
+		!strings.Contains(flat, hex.EncodeToString(data)+"}remote") {
+		t.Errorf("unexpected images\n%s", got)
+	}
+
+	fail := errors.New("fail")
+	e := &Exporter{Images: model.ImageResolverFunc(func(src string) (*model.Image, error) {
+		return nil, fail
+	})}
+	if err := e.Export(&buf, doc); err != fail {
+		t.Errorf("expected the resolver error, got %v", err)
+	}
+	if err := Export(ioutil.Discard, delta.New(nil).Delete(1)); err != model.ErrNotDocument {
+		t.Errorf("expected ErrNotDocument, got %v", err)
+	}
+}
+
+func TestExportUnsafeLinks(t *testing.T) {
+	doc := delta.New(nil).
+		Insert("click", delta.Attributes{"link": "javascript:alert(1)"}).
+		Insert(" here", delta.Attributes{"link": " JaVa\tscript:alert(1)"}).
+		Insert("\n", nil).
+		InsertEmbed(delta.Embed{Key: "video", Value: "javascript:alert(1)"}, nil).
+		Insert("\n", nil)
+	var buf bytes.Buffer
+	if err := Export(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); strings.Contains(got, "HYPERLINK") || !strings.Contains(got, "click here") {
+		t.Errorf("expected unsafe links as plain text\n%s", got)
+	}
+}
+
+func TestExportTransparentColors(t *testing.T) {
+	doc := delta.New(nil).
+		Insert("clear", delta.Attributes{"color": "transparent", "background": "transparent"}).
+		Insert("\n", nil)
+	var buf bytes.Buffer
+	if err := Export(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); strings.Contains(got, `\colortbl`) || strings.Contains(got, `\cf`) || strings.Contains(got, `\highlight`) ||
+		!strings.Contains(got, "clear") {
+		t.Errorf("expected transparent colors to be left out\n%s", got)
+	}
+}
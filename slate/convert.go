@@ -0,0 +1,448 @@
+package slate
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+	"github.com/fmpwizard/go-quilljs-delta/model"
+)
+
+// headings are the element types of the header levels
+var headings = []string{"heading-one", "heading-two", "heading-three", "heading-four", "heading-five", "heading-six"}
+
+// marks maps the marks of text nodes to quill formats
+var marks = map[string]struct {
+	format string
+	value  interface{}
+}{
+	"bold":          {"bold", true},
+	"italic":        {"italic", true},
+	"underline":     {"underline", true},
+	"strikethrough": {"strike", true},
+	"code":          {"code", true},
+	"superscript":   {"script", "super"},
+	"subscript":     {"script", "sub"},
+}
+
+// markOrder is the order of marks, to write them in a stable order
+var markOrder = []string{"bold", "italic", "underline", "strikethrough", "code", "superscript", "subscript"}
+
+// valueMarks are the formats kept as marks with their value
+var valueMarks = []string{"color", "background"}
+
+// imageProperties are the formats of image embeds kept as properties of
+// image elements
+var imageProperties = []string{"alt", "width", "height"}
+
+// ToDelta returns the delta of nodes, the top level nodes of a document.
+// Nested lists become indented list lines, and newlines in text end the
+// line, the text after them gets a line of its own with the same formats.
+// The content of elements of other types is kept
+func ToDelta(nodes []Node) *delta.Delta {
+	im := &importer{d: delta.New(nil)}
+	im.blocks(nodes, nil, 0)
+	if len(im.d.Ops) == 0 {
+		im.d.Insert("\n", nil)
+	}
+	return im.d
+}
+
+// importer holds the state of one ToDelta call
+type importer struct {
+	d *delta.Delta
+}
+
+// inline reports whether n goes inside a line of text
+func inline(n Node) bool {
+	return n.IsText() || n.Type() == "link"
+}
+
+// textblock reports whether nodes are the content of a line of text. Slate
+// keeps the children of an element either all blocks or all inline nodes
+// and text, so unknown inline elements, like mentions, are found by the
+// text next to them
+func textblock(nodes []Node) bool {
+	if len(nodes) == 0 {
+		return true
+	}
+	for _, n := range nodes {
+		if inline(n) {
+			return true
+		}
+	}
+	return false
+}
+
+// blocks adds the lines of nodes, with the block formats of attrs. depth is
+// how deep lists are nested
+func (im *importer) blocks(nodes []Node, attrs delta.Attributes, depth int) {
+	for _, n := range nodes {
+		im.block(n, attrs, depth)
+	}
+}
+
+func (im *importer) block(n Node, attrs delta.Attributes, depth int) {
+	if inline(n) {
+		im.line([]Node{n}, attrs)
+		return
+	}
+	children := n.Children()
+	if align, _ := n["align"].(string); align != "" && align != "left" {
+		attrs = attrs.With("align", align)
+	}
+	for i, h := range headings {
+		if n.Type() == h {
+			im.line(children, attrs.With("header", i+1))
+			return
+		}
+	}
+	switch n.Type() {
+	case "block-quote":
+		attrs = attrs.With("blockquote", true)
+		if textblock(children) {
+			im.line(children, attrs)
+		} else {
+			im.blocks(children, attrs, depth)
+		}
+	case "bulleted-list", "numbered-list":
+		depth = indent(n, depth)
+		for _, item := range children {
+			im.listItem(n.Type(), item, depth)
+		}
+	case "check-list-item":
+		attrs = attrs.With("list", "unchecked")
+		if checked, _ := n["checked"].(bool); checked {
+			attrs["list"] = "checked"
+		}
+		if depth = indent(n, depth); depth > 0 {
+			attrs["indent"] = depth
+		}
+		im.line(children, attrs)
+	case "code-block":
+		var format interface{} = true
+		if lang, _ := n["language"].(string); lang != "" {
+			format = lang
+		}
+		var lines []string
+		if textblock(children) {
+			lines = strings.Split(plainText(children), "\n")
+		}
+		for _, c := range children {
+			if !inline(c) {
+				lines = append(lines, plainText(c.Children()))
+			}
+		}
+		for _, l := range lines {
+			im.d.Insert(l, nil)
+			im.d.Insert("\n", delta.Attributes{"code-block": format})
+		}
+	case "image", "video":
+		url, _ := n["url"].(string)
+		if url == "" {
+			url, _ = n["src"].(string)
+		}
+		if url == "" {
+			return
+		}
+		if n.Type() == "video" {
+			im.d.InsertEmbed(delta.Embed{Key: "video", Value: url}, nil)
+			return
+		}
+		var formats delta.Attributes
+		for _, p := range imageProperties {
+			switch v := n[p].(type) {
+			case string:
+				if v != "" {
+					formats = formats.With(p, v)
+				}
+			case float64:
+				formats = formats.With(p, strconv.FormatFloat(v, 'f', -1, 64))
+			}
+		}
+		im.d.InsertEmbed(delta.Embed{Key: "image", Value: url}, formats)
+		im.d.Insert("\n", attrs)
+	default:
+		if textblock(children) {
+			im.line(children, attrs)
+		} else {
+			im.blocks(children, attrs, depth)
+		}
+	}
+}
+
+// listItem adds the lines of an item of a list of type listType
+func (im *importer) listItem(listType string, item Node, depth int) {
+	if item.Type() != "list-item" && !inline(item) {
+		// a list right inside its parent list
+		im.block(item, nil, depth+1)
+		return
+	}
+	attrs := delta.Attributes{"list": "bullet"}
+	if listType == "numbered-list" {
+		attrs["list"] = "ordered"
+	}
+	if depth > 0 {
+		attrs["indent"] = depth
+	}
+	if align, _ := item["align"].(string); align != "" && align != "left" {
+		attrs["align"] = align
+	}
+	children := item.Children()
+	if inline(item) || textblock(children) {
+		if inline(item) {
+			children = []Node{item}
+		}
+		im.line(children, attrs)
+		return
+	}
+	for _, child := range children {
+		switch child.Type() {
+		case "bulleted-list", "numbered-list":
+			im.block(child, nil, depth+1)
+		default:
+			im.block(child, attrs, depth)
+		}
+	}
+}
+
+// indent returns the indent property of a list element, which FromDelta
+// sets when the nesting of the element doesn't give it, or depth
+func indent(n Node, depth int) int {
+	switch v := n["indent"].(type) {
+	case float64:
+		if v >= 0 {
+			return int(v)
+		}
+	case int:
+		if v >= 0 {
+			return v
+		}
+	}
+	return depth
+}
+
+// line adds the inline content of a line and the newline that ends it
+func (im *importer) line(nodes []Node, attrs delta.Attributes) {
+	im.inline(nodes, attrs, nil)
+	im.d.Insert("\n", attrs)
+}
+
+// inline adds text nodes with their marks and the formats of the inline
+// elements they are in
+func (im *importer) inline(nodes []Node, attrs, formats delta.Attributes) {
+	for _, n := range nodes {
+		if !n.IsText() {
+			inner := formats
+			if url, _ := n["url"].(string); n.Type() == "link" && url != "" {
+				inner = inner.With("link", url)
+			}
+			im.inline(n.Children(), attrs, inner)
+			continue
+		}
+		text := formats
+		for _, name := range markOrder {
+			if v, _ := n[name].(bool); v {
+				text = text.With(marks[name].format, marks[name].value)
+			}
+		}
+		for _, name := range valueMarks {
+			if v, _ := n[name].(string); v != "" {
+				text = text.With(name, v)
+			}
+		}
+		for i, part := range strings.Split(n.Text(), "\n") {
+			if i > 0 {
+				im.d.Insert("\n", attrs)
+			}
+			im.d.Insert(part, text)
+		}
+	}
+}
+
+// plainText returns the text of nodes, without marks
+func plainText(nodes []Node) string {
+	var b strings.Builder
+	for _, n := range nodes {
+		if n.IsText() {
+			b.WriteString(n.Text())
+		} else {
+			b.WriteString(plainText(n.Children()))
+		}
+	}
+	return b.String()
+}
+
+// FromDelta returns the top level nodes of the Slate document of d, an
+// insert only delta. Images become image elements of their own, splitting
+// the line they are in, and the items of check lists are top level
+// check-list-item elements. Lists nested deeper than where they are get an
+// indent property, like the items of nested check lists
+func FromDelta(d *delta.Delta) ([]Node, error) {
+	parsed, err := model.Parse(d)
+	if err != nil {
+		return nil, err
+	}
+	nodes := []Node{}
+	for _, b := range parsed.Blocks {
+		nodes = append(nodes, block(b)...)
+	}
+	return nodes, nil
+}
+
+func block(b model.Block) []Node {
+	switch b := b.(type) {
+	case *model.Paragraph:
+		return line(b.Line, Node{"type": "paragraph"})
+	case *model.Heading:
+		level := b.Level
+		if level > len(headings) {
+			level = len(headings)
+		}
+		return line(b.Line, Node{"type": headings[level-1]})
+	case *model.List:
+		return list(b, 0)
+	case *model.Blockquote:
+		var nodes []Node
+		for _, l := range b.Lines {
+			nodes = append(nodes, line(l, Node{"type": "block-quote"})...)
+		}
+		return nodes
+	case *model.CodeBlock:
+		code := Node{"type": "code-block"}
+		if b.Language != "" {
+			code["language"] = b.Language
+		}
+		var lines []Node
+		for _, l := range b.Lines {
+			lines = append(lines, Node{"type": "code-line", "children": []Node{{"text": l.Text()}}})
+		}
+		code["children"] = lines
+		return []Node{code}
+	case *model.EmbedBlock:
+		if src, _ := b.Embed.Value.(string); src != "" && b.Embed.Key == "video" {
+			return []Node{{"type": "video", "url": src, "children": []Node{{"text": ""}}}}
+		}
+	}
+	return nil
+}
+
+// list returns the nodes of l, placed depth lists deep
+func list(l *model.List, depth int) []Node {
+	if l.Type == "checked" {
+		var nodes []Node
+		for _, item := range l.Items {
+			element := Node{"type": "check-list-item", "checked": item.Checked}
+			if l.Indent > 0 {
+				element["indent"] = l.Indent
+			}
+			nodes = append(nodes, line(item.Line, element)...)
+			// the items are not nested, their children are at the same depth
+			for _, child := range item.Children {
+				nodes = append(nodes, list(child, depth)...)
+			}
+		}
+		return nodes
+	}
+	listType := "bulleted-list"
+	if l.Type == "ordered" {
+		listType = "numbered-list"
+	}
+	var items []Node
+	for _, item := range l.Items {
+		var nested []Node
+		for _, child := range item.Children {
+			nested = append(nested, list(child, l.Indent+1)...)
+		}
+		if len(nested) == 0 {
+			items = append(items, line(item.Line, Node{"type": "list-item"})...)
+			continue
+		}
+		content := line(item.Line, Node{"type": "paragraph"})
+		items = append(items, Node{"type": "list-item", "children": append(content, nested...)})
+	}
+	element := Node{"type": listType, "children": items}
+	if l.Indent != depth {
+		element["indent"] = l.Indent
+	}
+	return []Node{element}
+}
+
+// line returns the element of a line, element with its children, and the
+// image elements that split it
+func line(l model.Line, element Node) []Node {
+	if align, _ := l.Attributes.String("align"); align != "" {
+		element["align"] = align
+	}
+	var nodes []Node
+	var runs []model.Run
+	flush := func(always bool) {
+		if len(runs) == 0 && !always {
+			return
+		}
+		e := make(Node, len(element)+1)
+		for k, v := range element {
+			e[k] = v
+		}
+		e["children"] = inlineNodes(runs)
+		nodes = append(nodes, e)
+		runs = nil
+	}
+	for _, r := range l.Runs {
+		if r.Embed == nil {
+			runs = append(runs, r)
+			continue
+		}
+		if src, _ := r.Embed.Value.(string); r.Embed.Key == "image" && src != "" {
+			flush(false)
+			image := Node{"type": "image", "url": src, "children": []Node{{"text": ""}}}
+			for _, p := range imageProperties {
+				if v, ok := r.Attributes.String(p); ok {
+					image[p] = v
+				}
+			}
+			nodes = append(nodes, image)
+		}
+	}
+	flush(len(nodes) == 0)
+	return nodes
+}
+
+// inlineNodes returns the text nodes of runs, with runs that have the same
+// link inside a link element. Inline elements are between text nodes, as
+// Slate wants them
+func inlineNodes(runs []model.Run) []Node {
+	nodes := []Node{}
+	var link Node
+	for _, r := range runs {
+		text := Node{"text": r.Text}
+		for _, name := range markOrder {
+			if v, ok := r.Attributes[marks[name].format]; ok && v == marks[name].value {
+				text[name] = true
+			}
+		}
+		for _, name := range valueMarks {
+			if v, _ := r.Attributes.String(name); v != "" {
+				text[name] = v
+			}
+		}
+		url, _ := r.Attributes.String("link")
+		switch {
+		case url == "":
+			link = nil
+			nodes = append(nodes, text)
+		case link != nil && link["url"] == url:
+			link["children"] = append(link["children"].([]Node), text)
+		default:
+			if len(nodes) == 0 || !nodes[len(nodes)-1].IsText() {
+				nodes = append(nodes, Node{"text": ""})
+			}
+			link = Node{"type": "link", "url": url, "children": []Node{text}}
+			nodes = append(nodes, link)
+		}
+	}
+	if len(nodes) == 0 || !nodes[len(nodes)-1].IsText() {
+		nodes = append(nodes, Node{"text": ""})
+	}
+	return nodes
+}
@@ -0,0 +1,107 @@
+package slate
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/fmpwizard/go-quilljs-delta/delta"
+)
+
+func TestRoundTrip(t *testing.T) {
+	doc := delta.New(nil).
+		Insert("Title", nil).Insert("\n", delta.Attributes{"header": 1, "align": "center"}).
+		Insert("Some ", nil).Insert("bold", delta.Attributes{"bold": true}).
+		Insert(" both", delta.Attributes{"bold": true, "italic": true}).
+		Insert(" ", nil).Insert("linked", delta.Attributes{"link": "https://example.com/", "underline": true}).
+		Insert(" too", delta.Attributes{"link": "https://example.com/"}).
+		Insert(" x", nil).Insert("2", delta.Attributes{"script": "super"}).
+		Insert(" ", nil).Insert("red", delta.Attributes{"color": "#ff0000", "strike": true}).
+		Insert("\n\n", nil).
+		Insert("one", nil).Insert("\n", delta.Attributes{"list": "ordered"}).
+		Insert("nested", nil).Insert("\n", delta.Attributes{"list": "bullet", "indent": 1}).
+		Insert("two", nil).Insert("\n", delta.Attributes{"list": "ordered"}).
+		Insert("todo", nil).Insert("\n", delta.Attributes{"list": "unchecked"}).
+		Insert("subtask", nil).Insert("\n", delta.Attributes{"list": "checked", "indent": 1}).
+		Insert("note", nil).Insert("\n", delta.Attributes{"list": "bullet", "indent": 2}).
+		Insert("done", nil).Insert("\n", delta.Attributes{"list": "checked"}).
+		Insert("quoted", delta.Attributes{"code": true}).Insert("\n", delta.Attributes{"blockquote": true}).
+		Insert("x := 1", nil).Insert("\n", delta.Attributes{"code-block": "go"}).
+		Insert("y", nil).Insert("\n", delta.Attributes{"code-block": "go"}).
+		InsertEmbed(delta.Embed{Key: "image", Value: "https://example.com/a.png"}, delta.Attributes{"alt": "a", "width": "20"}).
+		Insert("\n", nil).
+		InsertEmbed(delta.Embed{Key: "video", Value: "https://example.com/v"}, nil).
+		Insert("end\n", nil)
+
+	nodes, err := FromDelta(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(nodes)
+	parsed, err := FromJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ToDelta(parsed); !reflect.DeepEqual(got, doc) {
+		t.Errorf("round trip doesn't match\ngot:      %v\nexpected: %v", got.Ops, doc.Ops)
+	}
+}
+
+func TestFromDelta(t *testing.T) {
+	doc := delta.New(nil).
+		Insert("a", delta.Attributes{"bold": true, "link": "https://example.com/"}).
+		InsertEmbed(delta.Embed{Key: "image", Value: "a.png"}, nil).
+		Insert("c", delta.Attributes{"script": "sub"}).
+		Insert("\n", delta.Attributes{"align": "right"}).
+		Insert("\n", nil)
+	nodes, err := FromDelta(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(nodes)
+	expected := `[` +
+		`{"align":"right","children":[{"text":""},{"children":[{"bold":true,"text":"a"}],"type":"link","url":"https://example.com/"},{"text":""}],"type":"paragraph"},` +
+		`{"children":[{"text":""}],"type":"image","url":"a.png"},` +
+		`{"align":"right","children":[{"subscript":true,"text":"c"}],"type":"paragraph"},` +
+		`{"children":[{"text":""}],"type":"paragraph"}]`
+	if string(data) != expected {
+		t.Errorf("expected %s\ngot      %s", expected, data)
+	}
+}
+
+func TestToDelta(t *testing.T) {
+	nodes, err := FromJSON([]byte(`[
+		{"type":"bulleted-list","children":[
+			{"type":"list-item","children":[{"text":"first\nsecond","strikethrough":true}]},
+			{"type":"bulleted-list","children":[{"type":"list-item","children":[{"text":"deep"}]}]}
+		]},
+		{"type":"check-list-item","checked":true,"children":[{"text":"done"}]},
+		{"type":"code-block","language":"js","children":[{"text":"f(x)\n  y"}]},
+		{"type":"image","src":"b.png","width":30,"children":[{"text":""}]},
+		{"type":"block-quote","children":[{"type":"paragraph","children":[{"text":"a"}]},{"type":"paragraph","children":[{"text":"b"}]}]},
+		{"type":"mystery","children":[{"type":"mention","children":[{"text":"@x"}]},{"text":" plain"}]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bullet := delta.Attributes{"list": "bullet"}
+	code := delta.Attributes{"code-block": "js"}
+	quote := delta.Attributes{"blockquote": true}
+	expected := delta.New(nil).
+		Insert("first", delta.Attributes{"strike": true}).Insert("\n", bullet).
+		Insert("second", delta.Attributes{"strike": true}).Insert("\n", bullet).
+		Insert("deep", nil).Insert("\n", delta.Attributes{"list": "bullet", "indent": 1}).
+		Insert("done", nil).Insert("\n", delta.Attributes{"list": "checked"}).
+		Insert("f(x)", nil).Insert("\n", code).Insert("  y", nil).Insert("\n", code).
+		InsertEmbed(delta.Embed{Key: "image", Value: "b.png"}, delta.Attributes{"width": "30"}).
+		Insert("\n", nil).
+		Insert("a", nil).Insert("\n", quote).Insert("b", nil).Insert("\n", quote).
+		Insert("@x plain\n", nil)
+	if got := ToDelta(nodes); !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected delta\ngot:      %v\nexpected: %v", got.Ops, expected.Ops)
+	}
+
+	if got := ToDelta(nil); !reflect.DeepEqual(got, delta.New(nil).Insert("\n", nil)) {
+		t.Errorf("expected an empty document, got %v", got.Ops)
+	}
+}
@@ -0,0 +1,67 @@
+// Package slate converts between Quill documents, insert only deltas, and
+// the JSON of Slate editors: a list of elements, with a type, properties
+// and children, and text nodes, with their text and their marks as
+// properties. Slate leaves the names of element types and marks to each
+// editor, the ones of Slate's examples are used:
+//
+//	paragraph, heading-one to heading-six, block-quote,
+//	bulleted-list and numbered-list of list-item, check-list-item,
+//	code-block of code-line, image and video with a url, link with a url
+//
+// and bold, italic, underline, strikethrough, code, superscript and
+// subscript marks. Elements can have an align property, and lists and
+// check-list-items an indent property when their nesting doesn't give it.
+package slate
+
+import "encoding/json"
+
+// Node is an element or a text node
+type Node map[string]interface{}
+
+// FromJSON parses the JSON of a document, its list of top level nodes
+func FromJSON(in []byte) ([]Node, error) {
+	var nodes []Node
+	if err := json.Unmarshal(in, &nodes); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// IsText reports whether n is a text node
+func (n Node) IsText() bool {
+	_, ok := n["text"].(string)
+	return ok
+}
+
+// Text returns the text of a text node
+func (n Node) Text() string {
+	s, _ := n["text"].(string)
+	return s
+}
+
+// Type returns the type of an element
+func (n Node) Type() string {
+	s, _ := n["type"].(string)
+	return s
+}
+
+// Children returns the children of an element, whether n was built or
+// parsed from JSON
+func (n Node) Children() []Node {
+	switch children := n["children"].(type) {
+	case []Node:
+		return children
+	case []interface{}:
+		ret := make([]Node, 0, len(children))
+		for _, c := range children {
+			switch c := c.(type) {
+			case map[string]interface{}:
+				ret = append(ret, Node(c))
+			case Node:
+				ret = append(ret, c)
+			}
+		}
+		return ret
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package slate
+
+import "testing"
+
+func TestFromJSON(t *testing.T) {
+	nodes, err := FromJSON([]byte(`[{"type":"paragraph","children":[{"text":"hi","bold":true}]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 1 || nodes[0].Type() != "paragraph" || nodes[0].IsText() {
+		t.Fatalf("unexpected nodes %v", nodes)
+	}
+	children := nodes[0].Children()
+	if len(children) != 1 || !children[0].IsText() || children[0].Text() != "hi" {
+		t.Errorf("unexpected children %v", children)
+	}
+	if _, err := FromJSON([]byte(`[{"type":`)); err == nil {
+		t.Errorf("expected an error for invalid JSON")
+	}
+}
+
+func TestChildren(t *testing.T) {
+	built := Node{"type": "paragraph", "children": []Node{{"text": "a"}}}
+	if c := built.Children(); len(c) != 1 || c[0].Text() != "a" {
+		t.Errorf("unexpected children %v", c)
+	}
+	if c := (Node{"text": "a"}).Children(); c != nil {
+		t.Errorf("expected no children for a text node, got %v", c)
+	}
+}